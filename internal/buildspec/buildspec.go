@@ -0,0 +1,340 @@
+// Package buildspec generates Amplify build specs and merges the settings an
+// mvpbridge deployment needs into an existing amplify.yml.
+package buildspec
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"mvpbridge/internal/detect"
+)
+
+// AmplifyFile is the build spec Amplify reads from the repository root
+const AmplifyFile = "amplify.yml"
+
+// Settings are the settings an Amplify build spec must contain for an
+// mvpbridge deployment to work
+type Settings struct {
+	InstallCommand string // e.g. npm ci
+	BuildCommand   string
+	OutputDir      string // artifacts.baseDirectory
+	NodeVersion    string // selected with nvm when set
+}
+
+// Conflict is a required setting that an existing build spec sets
+// differently. The existing value is kept.
+type Conflict struct {
+	Setting  string
+	Existing string
+	Required string
+}
+
+// String describes the conflict for warnings
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s is %q, mvpbridge expects %q", c.Setting, c.Existing, c.Required)
+}
+
+// nodeVersionPattern matches versions nvm can install, e.g. 20, v20.11.1 or lts/iron
+var nodeVersionPattern = regexp.MustCompile(`^(v?\d+(\.\d+){0,2}|lts/[\w*-]+)$`)
+
+// SettingsFor returns the build settings for a detected project.
+// SSR apps always publish .next, which Amplify's compute runtime serves.
+func SettingsFor(d *detect.Detection, isStatic bool) Settings {
+	s := Settings{
+		InstallCommand: InstallCommand(d.PackageManager),
+		BuildCommand:   orDefault(d.BuildCommand, "npm run build"),
+		OutputDir:      orDefault(d.OutputDir, "dist"),
+	}
+	if !isStatic {
+		s.OutputDir = ".next"
+	}
+	if nodeVersionPattern.MatchString(d.NodeVersion) {
+		s.NodeVersion = d.NodeVersion
+	}
+	return s
+}
+
+// InstallCommand returns the lockfile-respecting install command for a package manager
+func InstallCommand(pm detect.PackageManager) string {
+	switch pm {
+	case detect.Yarn:
+		return "yarn install --frozen-lockfile"
+	case detect.PNPM:
+		return "pnpm install --frozen-lockfile"
+	default:
+		return "npm ci"
+	}
+}
+
+// New returns a complete build spec for the settings
+func New(s Settings) ([]byte, error) {
+	spec, _, err := Merge(nil, s)
+	return spec, err
+}
+
+// Complete reports whether an existing amplify.yml already has every required
+// setting, so Merge would change no more than its formatting. Conflicts do not
+// make a file incomplete; they are returned for warnings.
+func Complete(data []byte, s Settings) (bool, []Conflict, error) {
+	merged, conflicts, err := Merge(data, s)
+	if err != nil || len(bytes.TrimSpace(data)) == 0 {
+		return false, conflicts, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return false, nil, err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return false, nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return false, nil, err
+	}
+	return bytes.Equal(buf.Bytes(), merged), conflicts, nil
+}
+
+// Merge adds the required settings to an existing amplify.yml, keeping its
+// other phases, commands, cache paths and comments. Settings the file already
+// sets differently are left alone and returned as conflicts.
+// An empty file yields a complete build spec.
+func Merge(data []byte, s Settings) ([]byte, []Conflict, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("parsing %s: %w", AmplifyFile, err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("%s must be a mapping", AmplifyFile)
+	}
+
+	if mapValue(root, "version") == nil {
+		root.Content = append([]*yaml.Node{scalar("version"), {Kind: yaml.ScalarNode, Tag: "!!int", Value: "1"}}, root.Content...)
+	}
+
+	frontend, err := frontendNode(root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var conflicts []Conflict
+	phases := ensureMapping(frontend, "phases")
+	preBuild := ensureSequence(ensureMapping(phases, "preBuild"), "commands")
+	conflicts = append(conflicts, mergePreBuild(preBuild, s)...)
+
+	build := ensureSequence(ensureMapping(phases, "build"), "commands")
+	if c := mergeBuild(build, s.BuildCommand); c != nil {
+		conflicts = append(conflicts, *c)
+	}
+
+	artifacts := ensureMapping(frontend, "artifacts")
+	if base := mapValue(artifacts, "baseDirectory"); base == nil {
+		setValue(artifacts, "baseDirectory", scalar(s.OutputDir))
+	} else if cleanDir(base.Value) != cleanDir(s.OutputDir) {
+		conflicts = append(conflicts, Conflict{Setting: "artifacts.baseDirectory", Existing: base.Value, Required: s.OutputDir})
+	}
+	if mapValue(artifacts, "files") == nil {
+		setValue(artifacts, "files", sequence("**/*"))
+	}
+
+	if mapValue(frontend, "cache") == nil {
+		paths := []string{"node_modules/**/*"}
+		if cleanDir(s.OutputDir) == ".next" {
+			paths = append([]string{".next/cache/**/*"}, paths...)
+		}
+		cache := &yaml.Node{Kind: yaml.MappingNode}
+		setValue(cache, "paths", sequence(paths...))
+		setValue(frontend, "cache", cache)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), conflicts, nil
+}
+
+// frontendNode returns the frontend section, which monorepo build specs nest
+// under their single application
+func frontendNode(root *yaml.Node) (*yaml.Node, error) {
+	apps := mapValue(root, "applications")
+	if apps == nil {
+		return ensureMapping(root, "frontend"), nil
+	}
+	if apps.Kind != yaml.SequenceNode || len(apps.Content) != 1 || apps.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s defines %d applications; only single-app build specs can be merged", AmplifyFile, len(apps.Content))
+	}
+	return ensureMapping(apps.Content[0], "frontend"), nil
+}
+
+// mergePreBuild makes sure the preBuild commands select the Node version and
+// install dependencies, in that order
+func mergePreBuild(commands *yaml.Node, s Settings) []Conflict {
+	var conflicts []Conflict
+	insertAt := 0
+
+	if s.NodeVersion != "" {
+		found := false
+		for i, c := range commands.Content {
+			fields := strings.Fields(c.Value)
+			if len(fields) >= 3 && fields[0] == "nvm" && (fields[1] == "use" || fields[1] == "install") {
+				found = true
+				insertAt = i + 1
+				if strings.TrimPrefix(fields[2], "v") != strings.TrimPrefix(s.NodeVersion, "v") {
+					conflicts = append(conflicts, Conflict{Setting: "node version", Existing: fields[2], Required: s.NodeVersion})
+				}
+				break
+			}
+		}
+		if !found {
+			insertNode(commands, 0, scalar("nvm install "+s.NodeVersion))
+			insertAt = 1
+		}
+	}
+
+	for _, c := range commands.Content {
+		if tool, ok := installTool(c.Value); ok {
+			if want, _ := installTool(s.InstallCommand); tool != want {
+				conflicts = append(conflicts, Conflict{Setting: "install command", Existing: c.Value, Required: s.InstallCommand})
+			}
+			return conflicts
+		}
+	}
+	insertNode(commands, insertAt, scalar(s.InstallCommand))
+	return conflicts
+}
+
+// installTool returns the package manager a dependency install command runs
+func installTool(command string) (string, bool) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return "", false
+	}
+	switch fields[0] {
+	case "npm", "pnpm", "yarn", "bun":
+	default:
+		return "", false
+	}
+	if len(fields) == 1 {
+		return fields[0], fields[0] == "yarn"
+	}
+	switch fields[1] {
+	case "ci", "install", "i":
+		return fields[0], true
+	}
+	return "", false
+}
+
+// mergeBuild adds the build command to an empty build phase. A phase that
+// runs neither the command nor the build script is reported, since its steps
+// may build the app some other way.
+func mergeBuild(commands *yaml.Node, buildCommand string) *Conflict {
+	var existing []string
+	for _, c := range commands.Content {
+		if c.Value == buildCommand || runsBuildScript(c.Value) {
+			return nil
+		}
+		existing = append(existing, c.Value)
+	}
+	if len(existing) == 0 {
+		commands.Content = append(commands.Content, scalar(buildCommand))
+		return nil
+	}
+	return &Conflict{Setting: "build commands", Existing: strings.Join(existing, "; "), Required: buildCommand}
+}
+
+// runsBuildScript reports whether a command runs the package.json build script
+func runsBuildScript(command string) bool {
+	switch strings.Join(strings.Fields(command), " ") {
+	case "npm run build", "yarn build", "yarn run build", "pnpm build", "pnpm run build", "bun run build":
+		return true
+	}
+	return false
+}
+
+// orDefault returns s, or def when s is empty
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// cleanDir normalizes a relative directory so dist, ./dist and dist/ compare equal
+func cleanDir(dir string) string {
+	return path.Clean(strings.TrimSuffix(dir, "/"))
+}
+
+// mapValue returns the value of key in a mapping node, or nil
+func mapValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setValue sets key in a mapping node, appending it when missing
+func setValue(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, scalar(key), value)
+}
+
+// ensureMapping returns the mapping under key, replacing a missing or malformed value
+func ensureMapping(m *yaml.Node, key string) *yaml.Node {
+	if v := mapValue(m, key); v != nil && v.Kind == yaml.MappingNode {
+		return v
+	}
+	v := &yaml.Node{Kind: yaml.MappingNode}
+	setValue(m, key, v)
+	return v
+}
+
+// ensureSequence returns the sequence under key, replacing a missing or malformed value
+func ensureSequence(m *yaml.Node, key string) *yaml.Node {
+	if v := mapValue(m, key); v != nil && v.Kind == yaml.SequenceNode {
+		return v
+	}
+	v := &yaml.Node{Kind: yaml.SequenceNode}
+	setValue(m, key, v)
+	return v
+}
+
+// insertNode inserts n into a sequence node at index i
+func insertNode(seq *yaml.Node, i int, n *yaml.Node) {
+	seq.Content = append(seq.Content[:i], append([]*yaml.Node{n}, seq.Content[i:]...)...)
+}
+
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func sequence(values ...string) *yaml.Node {
+	seq := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, v := range values {
+		seq.Content = append(seq.Content, scalar(v))
+	}
+	return seq
+}
@@ -0,0 +1,207 @@
+package buildspec
+
+import (
+	"strings"
+	"testing"
+
+	"mvpbridge/internal/detect"
+)
+
+func TestMerge(t *testing.T) {
+	settings := Settings{InstallCommand: "npm ci", BuildCommand: "vite build", OutputDir: "dist", NodeVersion: "20"}
+
+	tests := []struct {
+		name          string
+		existing      string
+		wantContains  []string
+		wantConflicts []string
+	}{
+		{
+			name: "Empty file gets a complete spec",
+			wantContains: []string{
+				"version: 1",
+				"- nvm install 20\n        - npm ci",
+				"- vite build",
+				"baseDirectory: dist",
+				"- '**/*'",
+				"- node_modules/**/*",
+			},
+		},
+		{
+			name: "Custom phases, comments and cache are kept",
+			existing: `version: 1
+frontend:
+  phases:
+    preBuild:
+      commands:
+        - echo hello # greet
+    build:
+      commands:
+        - npm test
+        - npm run build
+  cache:
+    paths:
+      - .cache/**
+`,
+			wantContains: []string{
+				"- nvm install 20\n        - npm ci\n        - echo hello # greet",
+				"- npm test\n        - npm run build",
+				"baseDirectory: dist",
+				"- .cache/**",
+			},
+		},
+		{
+			name: "Conflicting settings are reported and kept",
+			existing: `version: 1
+frontend:
+  phases:
+    preBuild:
+      commands:
+        - nvm use 18
+        - yarn install
+    build:
+      commands:
+        - make site
+  artifacts:
+    baseDirectory: build
+    files:
+      - '**/*'
+`,
+			wantContains:  []string{"nvm use 18", "yarn install", "make site", "baseDirectory: build"},
+			wantConflicts: []string{"node version", "install command", "build commands", "artifacts.baseDirectory"},
+		},
+		{
+			name: "Equivalent settings do not conflict",
+			existing: `frontend:
+  phases:
+    preBuild:
+      commands:
+        - nvm use v20
+        - npm install
+    build:
+      commands:
+        - npm run build
+  artifacts:
+    baseDirectory: ./dist/
+`,
+			wantContains: []string{"version: 1", "baseDirectory: ./dist/"},
+		},
+		{
+			name: "Single application monorepo spec",
+			existing: `version: 1
+applications:
+  - appRoot: web
+    frontend:
+      phases:
+        build:
+          commands:
+            - npm run build
+`,
+			wantContains: []string{"appRoot: web", "baseDirectory: dist"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, conflicts, err := Merge([]byte(tt.existing), settings)
+			if err != nil {
+				t.Fatalf("Merge() error: %v", err)
+			}
+
+			for _, want := range tt.wantContains {
+				if !strings.Contains(string(merged), want) {
+					t.Errorf("Expected merged spec to contain %q, got:\n%s", want, merged)
+				}
+			}
+
+			var got []string
+			for _, c := range conflicts {
+				got = append(got, c.Setting)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantConflicts, ",") {
+				t.Errorf("Expected conflicts %v, got %v", tt.wantConflicts, conflicts)
+			}
+		})
+	}
+}
+
+func TestMergeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+	}{
+		{name: "Invalid YAML", existing: "frontend: ["},
+		{name: "Not a mapping", existing: "- a\n- b\n"},
+		{name: "Several applications", existing: "applications:\n  - appRoot: a\n  - appRoot: b\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := Merge([]byte(tt.existing), Settings{}); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestComplete(t *testing.T) {
+	settings := Settings{InstallCommand: "npm ci", BuildCommand: "vite build", OutputDir: "dist"}
+
+	if complete, _, err := Complete(nil, settings); err != nil || complete {
+		t.Errorf("Expected a missing file to be incomplete, got %v (%v)", complete, err)
+	}
+
+	spec, err := New(settings)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if complete, _, err := Complete(spec, settings); err != nil || !complete {
+		t.Errorf("Expected a generated spec to be complete, got %v (%v)", complete, err)
+	}
+
+	partial := []byte("version: 1\nfrontend:\n  phases:\n    build:\n      commands:\n        - vite build\n")
+	if complete, _, err := Complete(partial, settings); err != nil || complete {
+		t.Errorf("Expected a spec without artifacts to be incomplete, got %v (%v)", complete, err)
+	}
+
+	other := []byte(strings.Replace(string(spec), "baseDirectory: dist", "baseDirectory: build", 1))
+	complete, conflicts, err := Complete(other, settings)
+	if err != nil || !complete || len(conflicts) != 1 {
+		t.Errorf("Expected a complete spec with one conflict, got %v %v (%v)", complete, conflicts, err)
+	}
+}
+
+func TestSettingsFor(t *testing.T) {
+	tests := []struct {
+		name      string
+		detection detect.Detection
+		isStatic  bool
+		want      Settings
+	}{
+		{
+			name:      "Vite with yarn",
+			detection: detect.Detection{PackageManager: detect.Yarn, NodeVersion: "20", BuildCommand: "vite build", OutputDir: "dist"},
+			isStatic:  true,
+			want:      Settings{InstallCommand: "yarn install --frozen-lockfile", BuildCommand: "vite build", OutputDir: "dist", NodeVersion: "20"},
+		},
+		{
+			name:      "Next.js SSR with a version range",
+			detection: detect.Detection{PackageManager: detect.NPM, NodeVersion: ">=18", BuildCommand: "next build", OutputDir: ".next"},
+			want:      Settings{InstallCommand: "npm ci", BuildCommand: "next build", OutputDir: ".next"},
+		},
+		{
+			name:      "Defaults",
+			detection: detect.Detection{PackageManager: detect.PNPM},
+			isStatic:  true,
+			want:      Settings{InstallCommand: "pnpm install --frozen-lockfile", BuildCommand: "npm run build", OutputDir: "dist"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SettingsFor(&tt.detection, tt.isStatic); got != tt.want {
+				t.Errorf("SettingsFor() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
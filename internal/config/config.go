@@ -6,10 +6,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
 	"mvpbridge/internal/detect"
+	"mvpbridge/internal/notify"
+	"mvpbridge/internal/smoke"
 )
 
 const (
@@ -36,9 +41,87 @@ type Config struct {
 
 	// Deployment settings
 	Deploy struct {
-		AppName string `yaml:"app_name,omitempty"`
-		Region  string `yaml:"region,omitempty"`
+		AppName    string `yaml:"app_name,omitempty"`
+		Region     string `yaml:"region,omitempty"`    // AWS region
+		DORegion   string `yaml:"do_region,omitempty"` // DigitalOcean region slug, e.g. nyc or fra
+		AWSProfile string `yaml:"aws_profile,omitempty"`
+		DOContext  string `yaml:"do_context,omitempty"`
+
+		// Settings usually written by 'mvpbridge import' so deploys keep
+		// what was configured by hand in the platform dashboard
+		KeepEnv       []string `yaml:"keep_env,omitempty"`       // remote env vars kept when missing from .env
+		InstanceSize  string   `yaml:"instance_size,omitempty"`  // DO services
+		InstanceCount int      `yaml:"instance_count,omitempty"` // DO services
+		HTTPPort      int      `yaml:"http_port,omitempty"`      // DO services
+		Component     string   `yaml:"component,omitempty"`      // DO main component name, default app_name
+		BuildSpec     string   `yaml:"build_spec,omitempty"`     // Amplify build spec used verbatim
+		CustomRules   []Rule   `yaml:"custom_rules,omitempty"`   // Amplify rewrites and redirects
 	} `yaml:"deploy,omitempty"`
+
+	// Post-deploy smoke tests run against the live URL
+	Smoke struct {
+		Checks   []smoke.Check `yaml:"checks,omitempty"`
+		Retries  *int          `yaml:"retries,omitempty"` // per check; 0 disables retries (default 5)
+		Interval time.Duration `yaml:"interval,omitempty"`
+		Rollback bool          `yaml:"rollback,omitempty"` // roll back when a check fails
+	} `yaml:"smoke,omitempty"`
+
+	// Custom domains attached to the deployed app
+	Domains []Domain `yaml:"domains,omitempty"`
+
+	// Extra components deployed from the same repository on DigitalOcean,
+	// such as an API service beside a static frontend
+	Components []Component `yaml:"components,omitempty"`
+
+	// Database attached to the app on DigitalOcean
+	Database *Database `yaml:"database,omitempty"`
+
+	// Webhooks told about deploys and rollbacks
+	Notifications struct {
+		Webhooks []notify.Webhook `yaml:"webhooks,omitempty"`
+		Retries  int              `yaml:"retries,omitempty"` // per delivery (default 3)
+	} `yaml:"notifications,omitempty"`
+}
+
+// Database is a DigitalOcean database attached to the app. Without a
+// cluster, a dev database is created with the app.
+type Database struct {
+	Name    string `yaml:"name"`              // component name, used in ${name.DATABASE_URL}
+	Engine  string `yaml:"engine"`            // PG or MYSQL
+	Version string `yaml:"version,omitempty"` // engine version
+	Cluster string `yaml:"cluster,omitempty"` // existing managed cluster to use
+	DBName  string `yaml:"db_name,omitempty"` // managed clusters: database to connect to
+	DBUser  string `yaml:"db_user,omitempty"` // managed clusters: user to connect as
+	EnvVar  string `yaml:"env_var,omitempty"` // env var set to the connection string, default DATABASE_URL
+}
+
+// Component is an extra DigitalOcean App Platform component
+type Component struct {
+	Name         string `yaml:"name"`
+	Kind         string `yaml:"kind"` // service, worker or job
+	SourceDir    string `yaml:"source_dir,omitempty"`
+	BuildCommand string `yaml:"build_command,omitempty"`
+	RunCommand   string `yaml:"run_command,omitempty"`
+	HTTPPort     int    `yaml:"http_port,omitempty"`     // services
+	Route        string `yaml:"route,omitempty"`         // services: path prefix routed to the service
+	JobKind      string `yaml:"job_kind,omitempty"`      // jobs: PRE_DEPLOY (default), POST_DEPLOY or FAILED_DEPLOY
+	InstanceSize string `yaml:"instance_size,omitempty"` // defaults to basic-xxs
+}
+
+// Domain is a custom domain served by the deployed app
+type Domain struct {
+	Host   string `yaml:"host"`
+	Type   string `yaml:"type,omitempty"`   // DO: PRIMARY or ALIAS
+	Zone   string `yaml:"zone,omitempty"`   // apex domain; on DO, a zone hosted in DigitalOcean DNS
+	Branch string `yaml:"branch,omitempty"` // Amplify: branch served on the host
+}
+
+// Rule is an Amplify rewrite or redirect rule
+type Rule struct {
+	Source    string `yaml:"source"`
+	Target    string `yaml:"target"`
+	Status    string `yaml:"status"`
+	Condition string `yaml:"condition,omitempty"`
 }
 
 // Load reads config from .mvpbridge/config.yaml
@@ -48,7 +131,7 @@ func Load(root string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("config not found - run 'mvpbridge init' first")
+			return nil, fmt.Errorf("config not found - run 'mvpbridge init' first: %w", err)
 		}
 		return nil, err
 	}
@@ -90,10 +173,80 @@ func NewFromDetection(d *detect.Detection, target string) *Config {
 	cfg.Detected.OutputDir = d.OutputDir
 	cfg.Detected.NodeVersion = d.NodeVersion
 	cfg.Detected.OutputType = string(d.OutputType)
+	cfg.Components = BackendComponents(d.Backend)
+	if db := d.Database; db != nil {
+		cfg.Database = &Database{Name: "db", Engine: db.Engine, EnvVar: db.EnvVar}
+		if job := MigrationJob(db); job != nil && !cfg.hasComponent(job.Name) {
+			cfg.Components = append(cfg.Components, *job)
+		}
+	}
 
 	return cfg
 }
 
+// MigrationJob returns a pre-deploy job applying the ORM's migrations, or
+// nil when the detected database has no migration command
+func MigrationJob(db *detect.Database) *Component {
+	if db == nil || db.MigrateCommand == "" {
+		return nil
+	}
+	return &Component{
+		Name:       "migrate",
+		Kind:       "job",
+		SourceDir:  db.Dir,
+		RunCommand: db.MigrateCommand,
+		JobKind:    "PRE_DEPLOY",
+	}
+}
+
+func (c *Config) hasComponent(name string) bool {
+	for _, comp := range c.Components {
+		if comp.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// BackendComponents returns the components that deploy a detected API
+// server: a service routed at /api, plus a worker and a pre-deploy job when
+// the backend has worker and migrate scripts
+func BackendComponents(b *detect.Backend) []Component {
+	if b == nil {
+		return nil
+	}
+
+	components := []Component{{
+		Name:         "api",
+		Kind:         "service",
+		SourceDir:    b.Dir,
+		BuildCommand: b.BuildCommand,
+		RunCommand:   b.RunCommand,
+		HTTPPort:     8080,
+		Route:        "/api",
+	}}
+	if b.WorkerCommand != "" {
+		components = append(components, Component{
+			Name:         "worker",
+			Kind:         "worker",
+			SourceDir:    b.Dir,
+			BuildCommand: b.BuildCommand,
+			RunCommand:   b.WorkerCommand,
+		})
+	}
+	if b.MigrateCommand != "" {
+		components = append(components, Component{
+			Name:         "migrate",
+			Kind:         "job",
+			SourceDir:    b.Dir,
+			BuildCommand: b.BuildCommand,
+			RunCommand:   b.MigrateCommand,
+			JobKind:      "PRE_DEPLOY",
+		})
+	}
+	return components
+}
+
 // Validate checks if config has required fields
 func (c *Config) Validate() error {
 	if c.Version != 1 {
@@ -116,9 +269,182 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if err := validateDomains(c.Domains); err != nil {
+		return err
+	}
+
+	if err := validateComponents(c.Components); err != nil {
+		return err
+	}
+
+	if db := c.Database; db != nil {
+		if !componentName.MatchString(db.Name) {
+			return fmt.Errorf("database name %q must be 2-32 lowercase letters, digits or hyphens", db.Name)
+		}
+		for _, comp := range c.Components {
+			if comp.Name == db.Name {
+				return fmt.Errorf("database name %q is already used by a component", db.Name)
+			}
+		}
+		switch db.Engine {
+		case "PG":
+		case "MYSQL":
+			if db.Cluster == "" {
+				return fmt.Errorf("database %s: dev databases are PostgreSQL only; set cluster for MYSQL", db.Name)
+			}
+		default:
+			return fmt.Errorf("database %s: engine must be PG or MYSQL", db.Name)
+		}
+	}
+
+	for _, check := range c.Smoke.Checks {
+		if !strings.HasPrefix(check.Path, "/") {
+			return fmt.Errorf("smoke check path must start with /: %q", check.Path)
+		}
+	}
+
+	return validateWebhooks(c.Notifications.Webhooks)
+}
+
+// validateWebhooks checks webhook URLs, formats and event names
+func validateWebhooks(webhooks []notify.Webhook) error {
+	for i, w := range webhooks {
+		if !strings.HasPrefix(w.URL, "https://") && !strings.HasPrefix(w.URL, "http://") {
+			return fmt.Errorf("notifications webhook %d: url must be http(s)", i+1)
+		}
+		switch w.Format {
+		case "", notify.Generic, notify.Slack, notify.Discord:
+		default:
+			return fmt.Errorf("notifications webhook %d: unknown format %q (supported: generic, slack, discord)", i+1, w.Format)
+		}
+		for _, e := range w.Events {
+			known := false
+			for _, t := range notify.EventTypes {
+				known = known || e == t
+			}
+			if !known {
+				return fmt.Errorf("notifications webhook %d: unknown event %q (supported: start, success, failure, rollback)", i+1, e)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateDomains checks only the domains, for commands that change nothing else
+func (c *Config) ValidateDomains() error {
+	return validateDomains(c.Domains)
+}
+
+// validateDomains checks hosts are bare, unique names and at most one is primary
+func validateDomains(domains []Domain) error {
+	seen := make(map[string]bool)
+	primary := ""
+	for _, d := range domains {
+		if d.Host == "" || strings.ContainsAny(d.Host, ":/ ") || strings.HasPrefix(d.Host, ".") || strings.HasSuffix(d.Host, ".") {
+			return fmt.Errorf("invalid domain host: %q", d.Host)
+		}
+		if seen[d.Host] {
+			return fmt.Errorf("domain listed twice: %s", d.Host)
+		}
+		seen[d.Host] = true
+
+		if d.Zone != "" && d.Host != d.Zone && !strings.HasSuffix(d.Host, "."+d.Zone) {
+			return fmt.Errorf("domain %s is not in zone %s", d.Host, d.Zone)
+		}
+
+		switch d.Type {
+		case "", "ALIAS":
+		case "PRIMARY":
+			if primary != "" {
+				return fmt.Errorf("only one primary domain allowed: %s and %s", primary, d.Host)
+			}
+			primary = d.Host
+		default:
+			return fmt.Errorf("domain %s: type must be PRIMARY or ALIAS", d.Host)
+		}
+	}
+	return nil
+}
+
+// componentName matches the names App Platform accepts for components
+var componentName = regexp.MustCompile(`^[a-z][a-z0-9-]{0,30}[a-z0-9]$`)
+
+// validateComponents checks components have unique names, a known kind and
+// the commands and routes their kind needs
+func validateComponents(components []Component) error {
+	seen := make(map[string]bool)
+	for _, comp := range components {
+		if !componentName.MatchString(comp.Name) {
+			return fmt.Errorf("component name %q must be 2-32 lowercase letters, digits or hyphens", comp.Name)
+		}
+		if seen[comp.Name] {
+			return fmt.Errorf("component listed twice: %s", comp.Name)
+		}
+		seen[comp.Name] = true
+
+		switch comp.Kind {
+		case "service":
+			if comp.Route != "" && (!strings.HasPrefix(comp.Route, "/") || comp.Route == "/") {
+				return fmt.Errorf("component %s: route must be a path prefix like /api", comp.Name)
+			}
+		case "worker", "job":
+			if comp.RunCommand == "" {
+				return fmt.Errorf("component %s: a %s needs a run_command", comp.Name, comp.Kind)
+			}
+			if comp.Route != "" || comp.HTTPPort != 0 {
+				return fmt.Errorf("component %s: only services take a route or http_port", comp.Name)
+			}
+		default:
+			return fmt.Errorf("component %s: kind must be service, worker or job", comp.Name)
+		}
+
+		switch comp.JobKind {
+		case "", "PRE_DEPLOY", "POST_DEPLOY", "FAILED_DEPLOY":
+		default:
+			return fmt.Errorf("component %s: job_kind must be PRE_DEPLOY, POST_DEPLOY or FAILED_DEPLOY", comp.Name)
+		}
+		if comp.JobKind != "" && comp.Kind != "job" {
+			return fmt.Errorf("component %s: only jobs take a job_kind", comp.Name)
+		}
+	}
 	return nil
 }
 
+// SetDomain adds a domain, replacing any entry with the same host. A new
+// primary domain demotes the previous one to an alias.
+func (c *Config) SetDomain(d Domain) {
+	d.Host = strings.ToLower(d.Host)
+	d.Zone = strings.ToLower(d.Zone)
+
+	domains := make([]Domain, 0, len(c.Domains)+1)
+	replaced := false
+	for _, existing := range c.Domains {
+		if existing.Host == d.Host {
+			existing = d
+			replaced = true
+		} else if d.Type == "PRIMARY" && existing.Type == "PRIMARY" {
+			existing.Type = "ALIAS"
+		}
+		domains = append(domains, existing)
+	}
+	if !replaced {
+		domains = append(domains, d)
+	}
+	c.Domains = domains
+}
+
+// RemoveDomain removes a domain by host, reporting whether it was listed
+func (c *Config) RemoveDomain(host string) bool {
+	host = strings.ToLower(host)
+	for i, d := range c.Domains {
+		if d.Host == host {
+			c.Domains = append(c.Domains[:i], c.Domains[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 // IsStatic returns true if the project outputs static files
 func (c *Config) IsStatic() bool {
 	return c.Detected.OutputType == "static"
@@ -1,12 +1,16 @@
 package config
 
 import (
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"mvpbridge/internal/detect"
+	"mvpbridge/internal/notify"
 )
 
 func TestLoad(t *testing.T) {
@@ -92,6 +96,8 @@ invalid yaml
 			if tt.wantErr {
 				if err == nil {
 					t.Error("Expected error but got none")
+				} else if errors.Is(err, fs.ErrNotExist) != (tt.configYAML == "") {
+					t.Errorf("Expected only a missing config to match fs.ErrNotExist, got %v", err)
 				}
 				return
 			}
@@ -318,6 +324,87 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Domain with a scheme",
+			config: &Config{
+				Version:   1,
+				Framework: "vite",
+				Domains:   []Domain{{Host: "https://example.com"}},
+			},
+			wantErr: true,
+			errMsg:  "invalid domain host",
+		},
+		{
+			name: "Two primary domains",
+			config: &Config{
+				Version:   1,
+				Framework: "vite",
+				Domains:   []Domain{{Host: "example.com", Type: "PRIMARY"}, {Host: "www.example.com", Type: "PRIMARY"}},
+			},
+			wantErr: true,
+			errMsg:  "only one primary domain",
+		},
+		{
+			name: "Domain outside its zone",
+			config: &Config{
+				Version:   1,
+				Framework: "vite",
+				Domains:   []Domain{{Host: "www.example.org", Zone: "example.com"}},
+			},
+			wantErr: true,
+			errMsg:  "not in zone",
+		},
+		{
+			name: "Worker without a run command",
+			config: &Config{
+				Version:    1,
+				Framework:  "vite",
+				Components: []Component{{Name: "queue", Kind: "worker"}},
+			},
+			wantErr: true,
+			errMsg:  "needs a run_command",
+		},
+		{
+			name: "Service routed at the root",
+			config: &Config{
+				Version:    1,
+				Framework:  "vite",
+				Components: []Component{{Name: "api", Kind: "service", Route: "/"}},
+			},
+			wantErr: true,
+			errMsg:  "route must be a path prefix",
+		},
+		{
+			name: "MySQL dev database",
+			config: &Config{
+				Version:   1,
+				Framework: "vite",
+				Database:  &Database{Name: "db", Engine: "MYSQL"},
+			},
+			wantErr: true,
+			errMsg:  "set cluster",
+		},
+		{
+			name: "Database named like a component",
+			config: &Config{
+				Version:    1,
+				Framework:  "vite",
+				Components: []Component{{Name: "db", Kind: "worker", RunCommand: "node db.js"}},
+				Database:   &Database{Name: "db", Engine: "PG"},
+			},
+			wantErr: true,
+			errMsg:  "already used",
+		},
+		{
+			name: "Unknown component kind",
+			config: &Config{
+				Version:    1,
+				Framework:  "vite",
+				Components: []Component{{Name: "db", Kind: "database"}},
+			},
+			wantErr: true,
+			errMsg:  "kind must be",
+		},
 	}
 
 	for _, tt := range tests {
@@ -339,6 +426,92 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestBackendComponents(t *testing.T) {
+	if components := BackendComponents(nil); components != nil {
+		t.Errorf("Expected no components without a backend, got %v", components)
+	}
+
+	components := BackendComponents(&detect.Backend{Dir: "server", RunCommand: "npm start", MigrateCommand: "npm run migrate"})
+	if len(components) != 2 {
+		t.Fatalf("Expected a service and a job, got %+v", components)
+	}
+	if api := components[0]; api.Kind != "service" || api.Route != "/api" || api.SourceDir != "server" {
+		t.Errorf("Unexpected service: %+v", api)
+	}
+	if job := components[1]; job.Kind != "job" || job.JobKind != "PRE_DEPLOY" || job.RunCommand != "npm run migrate" {
+		t.Errorf("Unexpected job: %+v", job)
+	}
+
+	cfg := &Config{Version: 1, Framework: "vite", Components: components}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected generated components to be valid, got %v", err)
+	}
+}
+
+func TestNewFromDetectionDatabase(t *testing.T) {
+	d := &detect.Detection{
+		Framework:  detect.NextJS,
+		OutputType: detect.SSR,
+		Database:   &detect.Database{Engine: "PG", ORM: detect.Prisma, EnvVar: "DATABASE_URL", MigrateCommand: "npx prisma migrate deploy"},
+	}
+
+	cfg := NewFromDetection(d, "do")
+	if cfg.Database == nil || cfg.Database.Name != "db" || cfg.Database.Engine != "PG" {
+		t.Fatalf("Expected a PG database named db, got %+v", cfg.Database)
+	}
+	if len(cfg.Components) != 1 || cfg.Components[0].RunCommand != "npx prisma migrate deploy" || cfg.Components[0].JobKind != "PRE_DEPLOY" {
+		t.Errorf("Expected a pre-deploy migration job, got %+v", cfg.Components)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected generated config to be valid, got %v", err)
+	}
+
+	// A migrate script in the API server takes precedence
+	d.Backend = &detect.Backend{Dir: "server", RunCommand: "npm start", MigrateCommand: "npm run migrate"}
+	cfg = NewFromDetection(d, "do")
+	if len(cfg.Components) != 2 || cfg.Components[1].RunCommand != "npm run migrate" {
+		t.Errorf("Expected the backend's migrate job only, got %+v", cfg.Components)
+	}
+}
+
+func TestSetDomain(t *testing.T) {
+	cfg := &Config{Domains: []Domain{{Host: "example.com", Type: "PRIMARY"}, {Host: "old.example.com"}}}
+
+	cfg.SetDomain(Domain{Host: "WWW.Example.com", Type: "PRIMARY"})
+	cfg.SetDomain(Domain{Host: "old.example.com", Branch: "dev"})
+
+	want := []Domain{
+		{Host: "example.com", Type: "ALIAS"},
+		{Host: "old.example.com", Branch: "dev"},
+		{Host: "www.example.com", Type: "PRIMARY"},
+	}
+	if len(cfg.Domains) != len(want) {
+		t.Fatalf("Expected %d domains, got %v", len(want), cfg.Domains)
+	}
+	for i := range want {
+		if cfg.Domains[i] != want[i] {
+			t.Errorf("Domains[%d] = %+v, want %+v", i, cfg.Domains[i], want[i])
+		}
+	}
+
+	if !cfg.RemoveDomain("OLD.example.com") || cfg.RemoveDomain("missing.example.com") {
+		t.Error("RemoveDomain() reported the wrong result")
+	}
+	if len(cfg.Domains) != 2 {
+		t.Errorf("Expected 2 domains after removal, got %v", cfg.Domains)
+	}
+
+	// Unrelated settings do not fail domain validation
+	cfg.Components = []Component{{Name: "api", Kind: "cron"}}
+	if err := cfg.ValidateDomains(); err != nil {
+		t.Errorf("ValidateDomains() error: %v", err)
+	}
+	cfg.SetDomain(Domain{Host: "https://example.com"})
+	if err := cfg.ValidateDomains(); err == nil {
+		t.Error("Expected error for a host with a scheme")
+	}
+}
+
 func TestIsStatic(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -431,6 +604,7 @@ func TestConfigRoundTrip(t *testing.T) {
 	original.Detected.OutputType = "static"
 	original.Deploy.AppName = "my-app"
 	original.Deploy.Region = "nyc"
+	original.Deploy.DORegion = "fra"
 
 	tmpDir := t.TempDir()
 
@@ -463,4 +637,118 @@ func TestConfigRoundTrip(t *testing.T) {
 		t.Errorf("AppName mismatch: expected %s, got %s",
 			original.Deploy.AppName, loaded.Deploy.AppName)
 	}
+	if loaded.Deploy.DORegion != original.Deploy.DORegion {
+		t.Errorf("DORegion mismatch: expected %s, got %s",
+			original.Deploy.DORegion, loaded.Deploy.DORegion)
+	}
+}
+
+func TestLoadSmokeConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	configDir := filepath.Join(tmpDir, ConfigDir)
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+
+	configYAML := `version: 1
+framework: vite
+target: do
+smoke:
+  retries: 3
+  interval: 5s
+  rollback: true
+  checks:
+    - path: /
+      contains: '<div id="root">'
+    - path: /api/health
+      status: 204
+      headers:
+        cache-control: no-store
+`
+	if err := os.WriteFile(filepath.Join(configDir, ConfigFile), []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := Load(tmpDir)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	if cfg.Smoke.Retries == nil || *cfg.Smoke.Retries != 3 || cfg.Smoke.Interval != 5*time.Second || !cfg.Smoke.Rollback {
+		t.Errorf("Unexpected smoke settings: %+v", cfg.Smoke)
+	}
+	if len(cfg.Smoke.Checks) != 2 {
+		t.Fatalf("Expected 2 checks, got %d", len(cfg.Smoke.Checks))
+	}
+	if cfg.Smoke.Checks[1].Status != 204 || cfg.Smoke.Checks[1].Headers["cache-control"] != "no-store" {
+		t.Errorf("Unexpected second check: %+v", cfg.Smoke.Checks[1])
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error: %v", err)
+	}
+
+	cfg.Smoke.Checks[0].Path = "health"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for smoke path without leading slash")
+	}
+
+	configYAML = "version: 1\nframework: vite\ntarget: do\nsmoke:\n  retries: 0\n"
+	if err := os.WriteFile(filepath.Join(configDir, ConfigFile), []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if cfg, err = Load(tmpDir); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cfg.Smoke.Retries == nil || *cfg.Smoke.Retries != 0 {
+		t.Errorf("Expected retries: 0 to be kept, got %v", cfg.Smoke.Retries)
+	}
+}
+
+func TestLoadNotificationsConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	configDir := filepath.Join(tmpDir, ConfigDir)
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+
+	configYAML := `version: 1
+framework: vite
+target: do
+notifications:
+  retries: 5
+  webhooks:
+    - url: https://hooks.slack.com/services/T0/B0/x
+      format: slack
+      events: [failure, rollback]
+    - url: https://example.com/deploys
+`
+	if err := os.WriteFile(filepath.Join(configDir, ConfigFile), []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := Load(tmpDir)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	hooks := cfg.Notifications.Webhooks
+	if cfg.Notifications.Retries != 5 || len(hooks) != 2 {
+		t.Fatalf("Unexpected notifications: %+v", cfg.Notifications)
+	}
+	if hooks[0].Format != notify.Slack || hooks[0].Wants(notify.Success) || !hooks[1].Wants(notify.Start) {
+		t.Errorf("Unexpected webhooks: %+v", hooks)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error: %v", err)
+	}
+
+	hooks[1].Events = []notify.EventType{"finished"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for an unknown event")
+	}
+	hooks[1].Events = nil
+	hooks[1].Format = "teams"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for an unknown format")
+	}
 }
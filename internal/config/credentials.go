@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CredentialsFile is the name of the per-user file holding stored credentials
+const CredentialsFile = "credentials.yaml"
+
+// Credentials holds tokens saved with `mvpbridge auth login`, keyed by target
+type Credentials struct {
+	Targets map[string]*TargetCredentials `yaml:"targets,omitempty"`
+}
+
+// TargetCredentials holds the stored credentials for one target
+type TargetCredentials struct {
+	Token           string `yaml:"token,omitempty"`             // DigitalOcean
+	AccessKeyID     string `yaml:"access_key_id,omitempty"`     // AWS
+	SecretAccessKey string `yaml:"secret_access_key,omitempty"` // AWS
+}
+
+// UserCredentialsPath returns the credentials file under the user config
+// directory, e.g. ~/.config/mvpbridge/credentials.yaml
+func UserCredentialsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locating user config directory: %w", err)
+	}
+	return filepath.Join(dir, "mvpbridge", CredentialsFile), nil
+}
+
+// LoadCredentials reads stored credentials, returning an empty set if the
+// file does not exist
+func LoadCredentials(path string) (*Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Credentials{}, nil
+		}
+		return nil, err
+	}
+
+	var c Credentials
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parsing credentials: %w", err)
+	}
+
+	return &c, nil
+}
+
+// Save writes credentials readable only by the current user
+func (c *Credentials) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of an existing file, so tighten it explicitly
+	return os.Chmod(path, 0600)
+}
+
+// Get returns the stored credentials for a target, or nil
+func (c *Credentials) Get(target string) *TargetCredentials {
+	return c.Targets[target]
+}
+
+// Set stores credentials for a target
+func (c *Credentials) Set(target string, creds *TargetCredentials) {
+	if c.Targets == nil {
+		c.Targets = make(map[string]*TargetCredentials)
+	}
+	c.Targets[target] = creds
+}
+
+// Remove forgets the credentials for a target, reporting whether any existed
+func (c *Credentials) Remove(target string) bool {
+	if _, ok := c.Targets[target]; !ok {
+		return false
+	}
+	delete(c.Targets, target)
+	return true
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCredentialsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mvpbridge", CredentialsFile)
+
+	c, err := LoadCredentials(path)
+	if err != nil {
+		t.Fatalf("LoadCredentials() on missing file error: %v", err)
+	}
+	if c.Get("do") != nil {
+		t.Error("Expected no stored credentials")
+	}
+
+	c.Set("do", &TargetCredentials{Token: "dop_v1_abc"})
+	c.Set("aws", &TargetCredentials{AccessKeyID: "AKIA", SecretAccessKey: "secret"})
+	if err := c.Save(path); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Stat() error: %v", err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("Expected mode 0600, got %o", info.Mode().Perm())
+		}
+	}
+
+	loaded, err := LoadCredentials(path)
+	if err != nil {
+		t.Fatalf("LoadCredentials() error: %v", err)
+	}
+	if got := loaded.Get("do"); got == nil || got.Token != "dop_v1_abc" {
+		t.Errorf("Unexpected do credentials: %+v", got)
+	}
+	if got := loaded.Get("aws"); got == nil || got.AccessKeyID != "AKIA" || got.SecretAccessKey != "secret" {
+		t.Errorf("Unexpected aws credentials: %+v", got)
+	}
+
+	if !loaded.Remove("do") {
+		t.Error("Expected Remove to report stored credentials")
+	}
+	if loaded.Remove("do") {
+		t.Error("Expected second Remove to report nothing stored")
+	}
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// HistoryFile is the name of the append-only log of deploys and other
+// changes made to deployed apps
+const HistoryFile = "history.jsonl"
+
+// History results
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// HistoryEntry records one action against a deployment target
+type HistoryEntry struct {
+	Time         time.Time `json:"time"`
+	Action       string    `json:"action"` // deploy, rollback or destroy
+	Target       string    `json:"target"`
+	Preview      string    `json:"preview,omitempty"`
+	AppID        string    `json:"app_id,omitempty"`
+	AppName      string    `json:"app_name,omitempty"`
+	URL          string    `json:"url,omitempty"`
+	DeploymentID string    `json:"deployment_id,omitempty"` // DO deployment or Amplify job
+	Commit       string    `json:"commit,omitempty"`
+	User         string    `json:"user,omitempty"`
+	DurationMS   int64     `json:"duration_ms"`
+	Result       string    `json:"result"`
+	Error        string    `json:"error,omitempty"` // redacted by the caller
+}
+
+// Duration returns how long the action took
+func (e *HistoryEntry) Duration() time.Duration {
+	return time.Duration(e.DurationMS) * time.Millisecond
+}
+
+// AppendHistory adds an entry to .mvpbridge/history.jsonl, one JSON object
+// per line
+func AppendHistory(root string, entry HistoryEntry) error {
+	dir := filepath.Join(root, ConfigDir)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, HistoryFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadHistory reads .mvpbridge/history.jsonl, oldest first, returning no
+// entries if it is missing
+func LoadHistory(root string) ([]HistoryEntry, error) {
+	f, err := os.Open(filepath.Join(root, ConfigDir, HistoryFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("parsing history line %d: %w", n, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistoryAppendAndLoad(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	entries, err := LoadHistory(tmpDir)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("LoadHistory() on missing file = %v, %v", entries, err)
+	}
+
+	started := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, e := range []HistoryEntry{
+		{Time: started, Action: "deploy", Target: "do", AppID: "a1", Commit: "abc123", DurationMS: 1500, Result: ResultSuccess},
+		{Time: started.Add(time.Hour), Action: "rollback", Target: "do", Result: ResultFailure, Error: "API error 422"},
+	} {
+		if err := AppendHistory(tmpDir, e); err != nil {
+			t.Fatalf("AppendHistory() error: %v", err)
+		}
+	}
+
+	entries, err = LoadHistory(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadHistory() error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if first := entries[0]; !first.Time.Equal(started) || first.Commit != "abc123" || first.Duration() != 1500*time.Millisecond {
+		t.Errorf("Unexpected first entry: %+v", first)
+	}
+	if entries[1].Action != "rollback" || entries[1].Error != "API error 422" {
+		t.Errorf("Unexpected second entry: %+v", entries[1])
+	}
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StateFile is the name of the file that records deployed resources
+const StateFile = "state.yaml"
+
+// State records what MVPBridge has created on each deployment target
+type State struct {
+	Targets map[string]*TargetState `yaml:"targets,omitempty"`
+}
+
+// TargetState holds the deployed app for a single target and the last
+// deployment MVPBridge started on it
+type TargetState struct {
+	AppID        string `yaml:"app_id,omitempty"`
+	AppName      string `yaml:"app_name,omitempty"`
+	Region       string `yaml:"region,omitempty"`
+	DeploymentID string `yaml:"deployment_id,omitempty"` // DO deployment or Amplify job
+	Commit       string `yaml:"commit,omitempty"`        // SHA the deployment builds
+}
+
+// LoadState reads .mvpbridge/state.yaml, returning empty state if it is missing
+func LoadState(root string) (*State, error) {
+	path := filepath.Join(root, ConfigDir, StateFile)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &State{}, nil
+		}
+		return nil, err
+	}
+
+	var s State
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parsing state: %w", err)
+	}
+
+	return &s, nil
+}
+
+// Save writes state to .mvpbridge/state.yaml, removing the file when empty
+func (s *State) Save(root string) error {
+	dir := filepath.Join(root, ConfigDir)
+	path := filepath.Join(dir, StateFile)
+
+	if len(s.Targets) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0600)
+}
+
+// Target returns the state for a target, creating it if needed
+func (s *State) Target(name string) *TargetState {
+	if s.Targets == nil {
+		s.Targets = make(map[string]*TargetState)
+	}
+	if s.Targets[name] == nil {
+		s.Targets[name] = &TargetState{}
+	}
+	return s.Targets[name]
+}
+
+// Clear forgets everything recorded for a target
+func (s *State) Clear(name string) {
+	delete(s.Targets, name)
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	s, err := LoadState(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadState() on missing file error: %v", err)
+	}
+	if len(s.Targets) != 0 {
+		t.Errorf("Expected empty state, got %+v", s.Targets)
+	}
+
+	ts := s.Target("do")
+	ts.AppID = "abc-123"
+	ts.AppName = "my-app"
+	ts.DeploymentID = "dep-1"
+	ts.Commit = "0123456789abcdef0123456789abcdef01234567"
+	if err := s.Save(tmpDir); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	loaded, err := LoadState(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadState() error: %v", err)
+	}
+	if got := loaded.Target("do"); got.AppID != "abc-123" || got.AppName != "my-app" || got.DeploymentID != "dep-1" || got.Commit != ts.Commit {
+		t.Errorf("Unexpected target state: %+v", got)
+	}
+}
+
+func TestStateClearRemovesFile(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	s := &State{}
+	s.Target("aws").AppID = "d123"
+	if err := s.Save(tmpDir); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	s.Clear("aws")
+	if err := s.Save(tmpDir); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, ConfigDir, StateFile)); !os.IsNotExist(err) {
+		t.Errorf("Expected state file to be removed, got err=%v", err)
+	}
+}
@@ -0,0 +1,320 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DOAppSpecFile is the app spec DigitalOcean's tooling reads from a repository
+const DOAppSpecFile = ".do/app.yaml"
+
+// doComponentKinds are the app spec sections that hold components
+var doComponentKinds = []string{"services", "static_sites", "workers", "jobs", "functions"}
+
+// doManagedFields are the component fields mvpbridge sets on every deploy.
+// Any other field in the spec file is left as written.
+var doManagedFields = map[string]bool{
+	"github":        true,
+	"gitlab":        true,
+	"git":           true,
+	"build_command": true,
+	"run_command":   true,
+	"envs":          true,
+}
+
+// doSourceFields are the ways a component can get its code. A component that
+// already has a source other than the deployed repository's keeps it.
+var doSourceFields = []string{"github", "gitlab", "bitbucket", "git", "image"}
+
+// doComponentName matches the names App Platform accepts for apps and components
+var doComponentName = regexp.MustCompile(`^[a-z][a-z0-9-]{0,30}[a-z0-9]$`)
+
+// LoadDOAppSpec reads and validates an app spec file, returning nil when the
+// file does not exist
+func LoadDOAppSpec(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var spec map[string]interface{}
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if spec == nil {
+		spec = map[string]interface{}{}
+	}
+	if err := ValidateDOAppSpec(spec); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// ValidateDOAppSpec checks the parts of an app spec mvpbridge relies on:
+// component lists, unique component names and well-formed env vars
+func ValidateDOAppSpec(spec map[string]interface{}) error {
+	if name, ok := spec["name"]; ok {
+		if s, isString := name.(string); !isString || !doComponentName.MatchString(s) {
+			return fmt.Errorf("name %v must be 2-32 lowercase letters, digits or hyphens", name)
+		}
+	}
+	if region, ok := spec["region"]; ok {
+		if _, isString := region.(string); !isString {
+			return fmt.Errorf("region must be a string")
+		}
+	}
+	if err := validateEnvs("envs", spec["envs"]); err != nil {
+		return err
+	}
+
+	seen := make(map[string]string)
+	for _, kind := range doComponentKinds {
+		raw, ok := spec[kind]
+		if !ok {
+			continue
+		}
+		list, ok := raw.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be a list", kind)
+		}
+		for i, item := range list {
+			where := fmt.Sprintf("%s[%d]", kind, i)
+			comp, ok := item.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s must be a mapping", where)
+			}
+			name, _ := comp["name"].(string)
+			if !doComponentName.MatchString(name) {
+				return fmt.Errorf("%s.name %q must be 2-32 lowercase letters, digits or hyphens", where, name)
+			}
+			if other, dup := seen[name]; dup {
+				return fmt.Errorf("%s.name %q is already used by %s", where, name, other)
+			}
+			seen[name] = where
+			if err := validateEnvs(where+".envs", comp["envs"]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateEnvs checks an envs list: every entry needs a key, and type and
+// scope must be values App Platform knows
+func validateEnvs(where string, raw interface{}) error {
+	if raw == nil {
+		return nil
+	}
+	list, ok := raw.([]interface{})
+	if !ok {
+		return fmt.Errorf("%s must be a list", where)
+	}
+	for i, item := range list {
+		env, ok := item.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s[%d] must be a mapping", where, i)
+		}
+		if key, _ := env["key"].(string); key == "" {
+			return fmt.Errorf("%s[%d] has no key", where, i)
+		}
+		switch env["type"] {
+		case nil, "GENERAL", "SECRET":
+		default:
+			return fmt.Errorf("%s[%d].type must be GENERAL or SECRET", where, i)
+		}
+		switch env["scope"] {
+		case nil, "RUN_TIME", "BUILD_TIME", "RUN_AND_BUILD_TIME":
+		default:
+			return fmt.Errorf("%s[%d].scope must be RUN_TIME, BUILD_TIME or RUN_AND_BUILD_TIME", where, i)
+		}
+	}
+	return nil
+}
+
+// overlaySpec applies a generated spec to a copy of base. The app name and
+// the managed fields of the generated components are always set; everything
+// else is taken from base, falling back to the generated value when base
+// does not set it. Each generated component updates the component of the
+// same name, or is added. The main component also updates the only
+// component of its kind, unless that one is another generated component.
+func overlaySpec(base map[string]interface{}, spec *DOAppSpec) (map[string]interface{}, error) {
+	out, err := toGeneric(base)
+	if err != nil {
+		return nil, err
+	}
+	generated, err := toGeneric(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	mainKind := "services"
+	if len(spec.StaticSites) > 0 {
+		mainKind = "static_sites"
+	}
+	generatedNames := make(map[string]bool)
+	for _, kind := range doComponentKinds {
+		for _, comp := range componentList(generated[kind]) {
+			name, _ := comp["name"].(string)
+			generatedNames[name] = true
+		}
+	}
+
+	for key, value := range generated {
+		switch {
+		case key == "name":
+			out[key] = value
+		case isComponentKind(key):
+			for i, comp := range componentList(value) {
+				// The main component is the first of its kind
+				soleMatch := false
+				if existing := componentList(out[key]); key == mainKind && i == 0 && len(existing) == 1 {
+					name, _ := existing[0]["name"].(string)
+					soleMatch = !generatedNames[name]
+				}
+				out[key] = overlayComponent(out[key], comp, soleMatch)
+			}
+		default:
+			if _, ok := out[key]; !ok {
+				out[key] = value
+			}
+		}
+	}
+
+	if err := ValidateDOAppSpec(out); err != nil {
+		return nil, fmt.Errorf("%s with mvpbridge settings applied: %w", DOAppSpecFile, err)
+	}
+	return out, nil
+}
+
+// isComponentKind reports whether an app spec key holds components
+func isComponentKind(key string) bool {
+	for _, kind := range doComponentKinds {
+		if key == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// componentList returns the components of a component list
+func componentList(raw interface{}) []map[string]interface{} {
+	list, _ := raw.([]interface{})
+	components := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		if comp, ok := item.(map[string]interface{}); ok {
+			components = append(components, comp)
+		}
+	}
+	return components
+}
+
+// overlayComponent applies a generated component to the entry of the same
+// name in a component list, or to its only entry when soleMatch is set,
+// appending it when none matches
+func overlayComponent(raw interface{}, generated map[string]interface{}, soleMatch bool) []interface{} {
+	list, _ := raw.([]interface{})
+
+	index := -1
+	for i, item := range list {
+		if comp, _ := item.(map[string]interface{}); comp["name"] == generated["name"] {
+			index = i
+		}
+	}
+	if index < 0 && soleMatch && len(list) == 1 {
+		index = 0
+	}
+	if index < 0 {
+		return append(list, generated)
+	}
+
+	comp, _ := list[index].(map[string]interface{})
+	for key, value := range generated {
+		switch {
+		case key == "envs":
+			comp[key] = overlayEnvs(comp[key], value)
+		case key == "github" || key == "gitlab" || key == "git":
+			source, _ := value.(map[string]interface{})
+			overlaySource(comp, key, source)
+		case doManagedFields[key]:
+			comp[key] = value
+		default:
+			if _, ok := comp[key]; !ok {
+				comp[key] = value
+			}
+		}
+	}
+	return list
+}
+
+// overlaySource points a component at the deployed repo and branch, keeping
+// the rest of its source settings. Components built from another source,
+// including another kind of repository, keep it.
+func overlaySource(comp map[string]interface{}, kind string, generated map[string]interface{}) {
+	existing, ok := comp[kind].(map[string]interface{})
+	if !ok {
+		for _, source := range doSourceFields {
+			if _, has := comp[source]; has {
+				return
+			}
+		}
+		comp[kind] = generated
+		return
+	}
+	for key, value := range generated {
+		if _, has := existing[key]; !has || key != "deploy_on_push" {
+			existing[key] = value
+		}
+	}
+}
+
+// overlayEnvs sets the generated env vars on an envs list. Entries for other
+// keys, and the scope of existing entries, are kept. A generated entry without
+// a value keeps the existing value, so exported secrets are not blanked.
+func overlayEnvs(raw, generated interface{}) []interface{} {
+	list, _ := raw.([]interface{})
+	genList, _ := generated.([]interface{})
+
+	for _, g := range genList {
+		gen, _ := g.(map[string]interface{})
+		found := false
+		for _, item := range list {
+			env, _ := item.(map[string]interface{})
+			if env["key"] != gen["key"] {
+				continue
+			}
+			found = true
+			if v, _ := gen["value"].(string); v != "" {
+				env["value"] = v
+			}
+			env["type"] = gen["type"]
+		}
+		if !found {
+			list = append(list, gen)
+		}
+	}
+	return list
+}
+
+// toGeneric converts a value to plain maps and lists via JSON, which also
+// deep-copies it
+func toGeneric(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	if out == nil {
+		out = map[string]interface{}{}
+	}
+	return out, nil
+}
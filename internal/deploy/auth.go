@@ -0,0 +1,146 @@
+package deploy
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Identity describes the account behind a set of credentials
+type Identity struct {
+	Account string   `json:"account"`
+	Name    string   `json:"name"`              // email or ARN
+	Missing []string `json:"missing,omitempty"` // permissions that were denied
+}
+
+// ValidateCredentials checks the token against GET /v2/account and probes
+// the App Platform API to report missing scopes
+func (d *DODeployer) ValidateCredentials() (*Identity, error) {
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "GET", d.apiBase()+"/account", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := d.send(req)
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("DigitalOcean token is invalid or expired")
+		}
+		return nil, err
+	}
+
+	var result struct {
+		Account struct {
+			UUID   string `json:"uuid"`
+			Email  string `json:"email"`
+			Status string `json:"status"`
+			Team   struct {
+				Name string `json:"name"`
+			} `json:"team"`
+		} `json:"account"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	if result.Account.Status != "" && result.Account.Status != "active" {
+		return nil, fmt.Errorf("DigitalOcean account %s is %s", result.Account.Email, result.Account.Status)
+	}
+
+	id := &Identity{Account: result.Account.UUID, Name: result.Account.Email}
+	if result.Account.Team.Name != "" {
+		id.Name += " (team " + result.Account.Team.Name + ")"
+	}
+
+	// Scoped tokens can authenticate but still lack access to apps
+	req, err = http.NewRequestWithContext(orBackground(d.ctx), "GET", d.apiBase()+"/apps?per_page=1", nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := d.send(req); err != nil {
+		if !isForbidden(err) {
+			return nil, err
+		}
+		id.Missing = append(id.Missing, "app:read")
+	}
+
+	return id, nil
+}
+
+// ValidateCredentials calls STS GetCallerIdentity and probes the Amplify API
+// to report missing permissions
+func (d *AWSDeployer) ValidateCredentials() (*Identity, error) {
+	form := url.Values{}
+	form.Set("Action", "GetCallerIdentity")
+	form.Set("Version", "2011-06-15")
+
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "POST", stsEndpoint(d.Region), bytes.NewBufferString(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := sendWithRetry(d.client, req, func(r *http.Request) {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		d.signRequestFor(r, "sts", d.Region, time.Now().UTC())
+	})
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusForbidden || apiErr.StatusCode == http.StatusUnauthorized) {
+			return nil, fmt.Errorf("AWS credentials are invalid or expired: %s", awsErrorMessage([]byte(apiErr.Body)))
+		}
+		return nil, err
+	}
+
+	var result struct {
+		Arn     string `xml:"GetCallerIdentityResult>Arn"`
+		Account string `xml:"GetCallerIdentityResult>Account"`
+	}
+	if err := xml.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	id := &Identity{Account: result.Account, Name: result.Arn}
+
+	req, err = http.NewRequestWithContext(orBackground(d.ctx), "GET", d.apiBase()+"/apps?maxResults=1", nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := d.send(req); err != nil {
+		if !isForbidden(err) {
+			return nil, err
+		}
+		id.Missing = append(id.Missing, "amplify:ListApps")
+	}
+
+	return id, nil
+}
+
+// isForbidden reports whether err is an API error for a denied request
+func isForbidden(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden
+}
+
+// isNotFound reports whether err is an API error for a missing resource
+func isNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// awsErrorMessage extracts the message from an AWS XML error response
+func awsErrorMessage(body []byte) string {
+	var result struct {
+		Code    string `xml:"Error>Code"`
+		Message string `xml:"Error>Message"`
+	}
+	if err := xml.Unmarshal(body, &result); err != nil || result.Code == "" {
+		return string(body)
+	}
+	return result.Code + ": " + result.Message
+}
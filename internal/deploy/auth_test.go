@@ -0,0 +1,123 @@
+package deploy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDOValidateCredentials(t *testing.T) {
+	tests := []struct {
+		name        string
+		accountCode int
+		appsCode    int
+		wantErr     bool
+		wantMissing int
+	}{
+		{name: "Valid token", accountCode: http.StatusOK, appsCode: http.StatusOK},
+		{name: "Invalid token", accountCode: http.StatusUnauthorized, wantErr: true},
+		{name: "Missing app scope", accountCode: http.StatusOK, appsCode: http.StatusForbidden, wantMissing: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/account":
+					w.WriteHeader(tt.accountCode)
+					_, _ = w.Write([]byte(`{"account": {"uuid": "u-1", "email": "dev@example.com", "status": "active", "team": {"name": "Acme"}}}`))
+				case "/apps":
+					w.WriteHeader(tt.appsCode)
+					_, _ = w.Write([]byte(`{"apps": []}`))
+				}
+			}))
+			defer server.Close()
+
+			deployer := &DODeployer{Token: "test", client: server.Client(), baseURL: server.URL}
+
+			id, err := deployer.ValidateCredentials()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateCredentials() error: %v", err)
+			}
+			if id.Account != "u-1" || id.Name != "dev@example.com (team Acme)" {
+				t.Errorf("Unexpected identity: %+v", id)
+			}
+			if len(id.Missing) != tt.wantMissing {
+				t.Errorf("Expected %d missing permissions, got %v", tt.wantMissing, id.Missing)
+			}
+		})
+	}
+}
+
+func TestAWSValidateCredentials(t *testing.T) {
+	tests := []struct {
+		name        string
+		stsCode     int
+		appsCode    int
+		wantErr     bool
+		wantMissing []string
+	}{
+		{name: "Valid credentials", stsCode: http.StatusOK, appsCode: http.StatusOK},
+		{name: "Expired token", stsCode: http.StatusForbidden, wantErr: true},
+		{name: "No Amplify access", stsCode: http.StatusOK, appsCode: http.StatusForbidden, wantMissing: []string{"amplify:ListApps"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stsBody string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/apps" {
+					w.WriteHeader(tt.appsCode)
+					_, _ = w.Write([]byte(`{"apps": []}`))
+					return
+				}
+				body, _ := io.ReadAll(r.Body)
+				stsBody = string(body)
+				w.WriteHeader(tt.stsCode)
+				if tt.stsCode != http.StatusOK {
+					_, _ = w.Write([]byte(`<ErrorResponse><Error><Code>ExpiredToken</Code><Message>The security token included in the request is expired</Message></Error></ErrorResponse>`))
+					return
+				}
+				_, _ = w.Write([]byte(`<GetCallerIdentityResponse><GetCallerIdentityResult>
+  <Arn>arn:aws:sts::123456789012:assumed-role/deploy/ci</Arn>
+  <UserId>AROA:ci</UserId>
+  <Account>123456789012</Account>
+</GetCallerIdentityResult></GetCallerIdentityResponse>`))
+			}))
+			defer server.Close()
+
+			defer func(f func(string) string) { stsEndpoint = f }(stsEndpoint)
+			stsEndpoint = func(string) string { return server.URL + "/" }
+
+			deployer := &AWSDeployer{AccessKey: "key", SecretKey: "secret", Region: "us-east-1", client: server.Client(), endpoint: server.URL}
+
+			id, err := deployer.ValidateCredentials()
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "ExpiredToken") {
+					t.Errorf("Expected ExpiredToken error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateCredentials() error: %v", err)
+			}
+			if !strings.Contains(stsBody, "Action=GetCallerIdentity") {
+				t.Errorf("Unexpected STS request body: %s", stsBody)
+			}
+			if id.Account != "123456789012" || !strings.HasSuffix(id.Name, "assumed-role/deploy/ci") {
+				t.Errorf("Unexpected identity: %+v", id)
+			}
+			if strings.Join(id.Missing, ",") != strings.Join(tt.wantMissing, ",") {
+				t.Errorf("Expected missing %v, got %v", tt.wantMissing, id.Missing)
+			}
+		})
+	}
+}
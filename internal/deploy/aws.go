@@ -7,6 +7,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,19 +16,43 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"mvpbridge/internal/buildspec"
+	"mvpbridge/internal/detect"
+	"mvpbridge/internal/git"
 )
 
 const awsAmplifyAPIBase = "https://amplify.%s.amazonaws.com"
 
+// Amplify hosting platforms: WEB serves static files, WEB_COMPUTE runs SSR
+const (
+	amplifyPlatformStatic = "WEB"
+	amplifyPlatformSSR    = "WEB_COMPUTE"
+	amplifySSRFramework   = "Next.js - SSR"
+)
+
+// amplifyAppsPerPage is the page size used when listing apps (the API maximum)
+const amplifyAppsPerPage = 100
+
 // AWSDeployer handles deployments to AWS Amplify
 type AWSDeployer struct {
-	AccessKey string
-	SecretKey string
-	Region    string
-	AppName   string
-	RepoURL   string
-	Branch    string
-	client    *http.Client
+	AccessKey      string
+	SecretKey      string
+	SessionToken   string
+	Region         string
+	AppName        string
+	AppID          string // when set, the app is addressed by ID instead of by name
+	RepoURL        string
+	Branch         string
+	CommitID       string                // commit to release; empty builds the branch head
+	BuildSpec      string                // used instead of the generated build spec when set
+	RepoBuildSpec  bool                  // the repo has an amplify.yml, which Amplify builds with instead
+	PackageManager detect.PackageManager // picks the generated spec's install command
+	CustomRules    []AmplifyRule         // used instead of the default SPA rules when set
+	KeepEnv        []string              // remote env vars kept when not set locally
+	client         *http.Client
+	endpoint       string
+	ctx            context.Context
 }
 
 // AmplifyApp represents an AWS Amplify application configuration
@@ -52,11 +77,61 @@ type AmplifyRule struct {
 // AmplifyBranch represents a branch configuration in AWS Amplify
 type AmplifyBranch struct {
 	BranchName           string            `json:"branchName"`
+	DisplayName          string            `json:"displayName,omitempty"`
+	Description          string            `json:"description,omitempty"`
 	EnableAutoBuild      bool              `json:"enableAutoBuild"`
 	Stage                string            `json:"stage"` // PRODUCTION, DEVELOPMENT
+	Framework            string            `json:"framework,omitempty"`
+	BuildSpec            string            `json:"buildSpec,omitempty"`
 	EnvironmentVariables map[string]string `json:"environmentVariables,omitempty"`
 }
 
+// AmplifyBranchSummary describes a branch returned by the list endpoint
+type AmplifyBranchSummary struct {
+	BranchName  string  `json:"branchName"`
+	DisplayName string  `json:"displayName"`
+	Description string  `json:"description"`
+	Stage       string  `json:"stage"`
+	ActiveJobID string  `json:"activeJobId"`
+	CreateTime  float64 `json:"createTime"` // seconds since the Unix epoch
+}
+
+// Created returns the branch creation time
+func (b AmplifyBranchSummary) Created() time.Time {
+	return epochTime(b.CreateTime)
+}
+
+// AmplifyJobSummary describes a build job of a branch
+type AmplifyJobSummary struct {
+	JobID         string  `json:"jobId"`
+	JobType       string  `json:"jobType"` // RELEASE, RETRY, MANUAL, WEB_HOOK
+	Status        string  `json:"status"`  // PENDING, PROVISIONING, RUNNING, SUCCEED, FAILED, ...
+	CommitID      string  `json:"commitId"`
+	CommitMessage string  `json:"commitMessage"`
+	StartTime     float64 `json:"startTime"` // seconds since the Unix epoch
+	EndTime       float64 `json:"endTime"`
+}
+
+// Started returns the time the job started
+func (j AmplifyJobSummary) Started() time.Time {
+	return epochTime(j.StartTime)
+}
+
+// AmplifyJobStep is one step of a build job, such as BUILD or DEPLOY
+type AmplifyJobStep struct {
+	StepName string `json:"stepName"`
+	Status   string `json:"status"`
+	LogURL   string `json:"logUrl"` // pre-signed link to the step's log
+}
+
+// epochTime converts Amplify's fractional epoch seconds to a time
+func epochTime(seconds float64) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(seconds), 0).UTC()
+}
+
 // AmplifyAppResponse represents the API response when creating or getting an Amplify app
 type AmplifyAppResponse struct {
 	App struct {
@@ -65,6 +140,19 @@ type AmplifyAppResponse struct {
 		DefaultDomain string `json:"defaultDomain"`
 		Repository    string `json:"repository"`
 	} `json:"app"`
+
+	// JobID is the build started by Deploy; it is not part of the API response
+	JobID string `json:"-"`
+}
+
+// AmplifyAppSettings holds the build and routing settings of an existing app
+type AmplifyAppSettings struct {
+	AppID                string            `json:"appId"`
+	Name                 string            `json:"name"`
+	Platform             string            `json:"platform"` // WEB, WEB_COMPUTE
+	BuildSpec            string            `json:"buildSpec"`
+	EnvironmentVariables map[string]string `json:"environmentVariables"`
+	CustomRules          []AmplifyRule     `json:"customRules"`
 }
 
 // AmplifyBranchResponse represents the API response when creating or getting a branch
@@ -74,73 +162,148 @@ type AmplifyBranchResponse struct {
 	} `json:"branch"`
 }
 
-// NewAWSDeployer creates a new AWS Amplify deployer instance
-func NewAWSDeployer(appName, repoURL, branch, region string) (*AWSDeployer, error) {
-	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
-	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+// AmplifyDomainAssociation represents a custom domain connected to an app
+type AmplifyDomainAssociation struct {
+	DomainName                       string             `json:"domainName"`
+	DomainStatus                     string             `json:"domainStatus,omitempty"`
+	StatusReason                     string             `json:"statusReason,omitempty"`
+	CertificateVerificationDNSRecord string             `json:"certificateVerificationDNSRecord,omitempty"`
+	SubDomains                       []AmplifySubDomain `json:"subDomains,omitempty"`
+}
+
+// AmplifySubDomain is a subdomain of a domain association and its state
+type AmplifySubDomain struct {
+	SubDomainSetting AmplifySubDomainSetting `json:"subDomainSetting"`
+	Verified         bool                    `json:"verified,omitempty"`
+	DNSRecord        string                  `json:"dnsRecord,omitempty"`
+}
+
+// AmplifySubDomainSetting maps a subdomain prefix to a branch
+type AmplifySubDomainSetting struct {
+	Prefix     string `json:"prefix"`
+	BranchName string `json:"branchName"`
+}
 
-	if accessKey == "" || secretKey == "" {
-		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables must be set")
+// NewAWSDeployer creates a new AWS Amplify deployer instance using
+// credentials from the environment or the AWS_PROFILE/default profile
+func NewAWSDeployer(appName, repoURL, branch, region string) (*AWSDeployer, error) {
+	creds, err := ResolveAWSCredentials("")
+	if err != nil {
+		return nil, err
 	}
 
+	return NewAWSDeployerWithCredentials(creds, appName, repoURL, branch, region), nil
+}
+
+// NewAWSDeployerWithCredentials creates a deployer with already resolved credentials
+func NewAWSDeployerWithCredentials(creds *AWSCredentials, appName, repoURL, branch, region string) *AWSDeployer {
 	if region == "" {
 		region = "us-east-1" // Default region
 	}
 
 	return &AWSDeployer{
-		AccessKey: accessKey,
-		SecretKey: secretKey,
-		Region:    region,
-		AppName:   appName,
-		RepoURL:   repoURL,
-		Branch:    branch,
-		client:    &http.Client{Timeout: 30 * time.Second},
-	}, nil
+		AccessKey:    creds.AccessKeyID,
+		SecretKey:    creds.SecretAccessKey,
+		SessionToken: creds.SessionToken,
+		Region:       region,
+		AppName:      appName,
+		RepoURL:      repoURL,
+		Branch:       branch,
+		client:       &http.Client{Timeout: 30 * time.Second},
+		endpoint:     fmt.Sprintf(awsAmplifyAPIBase, region),
+	}
+}
+
+// SetContext makes every subsequent API call and wait honor ctx, so an
+// interrupt or --timeout cancels in-flight requests
+func (d *AWSDeployer) SetContext(ctx context.Context) {
+	d.ctx = ctx
 }
 
 // Deploy creates or updates an AWS Amplify app
 func (d *AWSDeployer) Deploy(isStatic bool, envVars map[string]string, buildCommand, outputDir string) (*AmplifyAppResponse, error) {
 	// Check if app exists
 	existing, err := d.getApp()
-	if err != nil && !strings.Contains(err.Error(), "not found") {
+	if err != nil && !errors.Is(err, ErrAppNotFound) {
 		return nil, fmt.Errorf("checking existing app: %w", err)
 	}
 
 	if existing != nil {
 		// Update existing app
-		return d.updateApp(existing.App.AppID, envVars, buildCommand, outputDir)
+		settings, err := d.GetAppSettings(existing.App.AppID)
+		if err != nil {
+			return nil, fmt.Errorf("reading app settings: %w", err)
+		}
+		envVars = keepEnv(envVars, settings.EnvironmentVariables, d.KeepEnv)
+		return d.updateApp(existing.App.AppID, settings, envVars, buildCommand, outputDir, isStatic)
 	}
 
 	// Create new app
 	return d.createApp(envVars, buildCommand, outputDir, isStatic)
 }
 
+// AmplifyRepository returns the repository URL Amplify connects to. Amplify
+// builds from github.com, gitlab.com, bitbucket.org and CodeCommit only.
+func AmplifyRepository(remote *git.Remote) (string, error) {
+	if remote.IsHosted() || remote.Provider == git.CodeCommit {
+		return remote.HTTPSURL(), nil
+	}
+	return "", fmt.Errorf("AWS Amplify cannot build from %s; it supports github.com, gitlab.com, bitbucket.org and CodeCommit", remote.Host)
+}
+
+// amplifyRepoToken returns the create-app field and env var holding the token
+// for a repository's provider, or empty strings when none is needed
+func amplifyRepoToken(repoURL string) (field, envVar string) {
+	remote, err := git.ParseRemote(repoURL)
+	if err != nil {
+		return "accessToken", "GITHUB_TOKEN"
+	}
+	switch remote.Provider {
+	case git.GitLab:
+		return "oauthToken", "GITLAB_TOKEN"
+	case git.Bitbucket:
+		return "oauthToken", "BITBUCKET_TOKEN"
+	case git.CodeCommit:
+		return "", ""
+	}
+	return "accessToken", "GITHUB_TOKEN"
+}
+
+// appBuildSpec returns the build spec for a static site or a Next.js SSR app
+func (d *AWSDeployer) appBuildSpec(buildCommand, outputDir string, isStatic bool) string {
+	if isStatic {
+		return d.buildSpec(buildCommand, outputDir)
+	}
+	return d.ssrBuildSpec(buildCommand)
+}
+
 func (d *AWSDeployer) createApp(envVars map[string]string, buildCommand, outputDir string, isStatic bool) (*AmplifyAppResponse, error) {
-	// Parse GitHub token from environment or URL
-	githubToken := os.Getenv("GITHUB_TOKEN")
-	if githubToken == "" {
-		return nil, fmt.Errorf("GITHUB_TOKEN environment variable required for AWS Amplify")
+	// Amplify connects to the repository with a provider token; CodeCommit
+	// uses the service role instead
+	tokenField, tokenEnv := amplifyRepoToken(d.RepoURL)
+	repoToken := ""
+	if tokenEnv != "" {
+		if repoToken = os.Getenv(tokenEnv); repoToken == "" {
+			return nil, fmt.Errorf("%s environment variable required for AWS Amplify", tokenEnv)
+		}
 	}
 
 	// Build the app spec
 	app := AmplifyApp{
 		Name:                 d.AppName,
 		Repository:           d.RepoURL,
-		Platform:             "WEB",
+		Platform:             amplifyPlatformStatic,
 		EnvironmentVariables: envVars,
-		BuildSpec:            d.buildSpec(buildCommand, outputDir),
+		BuildSpec:            d.appBuildSpec(buildCommand, outputDir, isStatic),
+		CustomRules:          d.CustomRules,
+	}
+	if !isStatic {
+		app.Platform = amplifyPlatformSSR
 	}
 
 	// Add SPA redirect rules for static apps
-	if isStatic {
-		app.CustomRules = []AmplifyRule{
-			{
-				Source:    "/<*>",
-				Target:    "/index.html",
-				Status:    "404-200",
-				Condition: "",
-			},
-		}
+	if isStatic && len(app.CustomRules) == 0 {
+		app.CustomRules = []AmplifyRule{spaFallbackRule}
 	}
 
 	body := map[string]interface{}{
@@ -148,9 +311,13 @@ func (d *AWSDeployer) createApp(envVars map[string]string, buildCommand, outputD
 		"repository":           app.Repository,
 		"platform":             app.Platform,
 		"environmentVariables": app.EnvironmentVariables,
-		"buildSpec":            app.BuildSpec,
 		"customRules":          app.CustomRules,
-		"accessToken":          githubToken,
+	}
+	if !d.RepoBuildSpec {
+		body["buildSpec"] = app.BuildSpec
+	}
+	if tokenField != "" {
+		body[tokenField] = repoToken
 	}
 
 	jsonBody, err := json.Marshal(body)
@@ -158,8 +325,8 @@ func (d *AWSDeployer) createApp(envVars map[string]string, buildCommand, outputD
 		return nil, err
 	}
 
-	endpoint := fmt.Sprintf(awsAmplifyAPIBase, d.Region) + "/apps"
-	req, err := http.NewRequestWithContext(context.Background(), "POST", endpoint, bytes.NewBuffer(jsonBody))
+	endpoint := d.apiBase() + "/apps"
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "POST", endpoint, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, err
 	}
@@ -170,17 +337,37 @@ func (d *AWSDeployer) createApp(envVars map[string]string, buildCommand, outputD
 	}
 
 	// Create branch
-	if err := d.createBranch(result.App.AppID, envVars); err != nil {
+	if err := d.createBranch(result.App.AppID, envVars, isStatic); err != nil {
 		return nil, fmt.Errorf("creating branch: %w", err)
 	}
 
+	// Branches created through the API do not build until a job is started
+	if result.JobID, err = d.StartJob(result.App.AppID, d.Branch, d.CommitID); err != nil {
+		return nil, fmt.Errorf("starting build: %w", err)
+	}
+
 	return result, nil
 }
 
-func (d *AWSDeployer) updateApp(appID string, envVars map[string]string, buildCommand, outputDir string) (*AmplifyAppResponse, error) {
+// updateApp reconciles an existing app, its custom rules and the deployer's
+// branch with the desired settings, then starts a release build
+func (d *AWSDeployer) updateApp(appID string, settings *AmplifyAppSettings, envVars map[string]string, buildCommand, outputDir string, isStatic bool) (*AmplifyAppResponse, error) {
 	body := map[string]interface{}{
 		"environmentVariables": envVars,
-		"buildSpec":            d.buildSpec(buildCommand, outputDir),
+		"customRules":          d.reconcileRules(settings.CustomRules, isStatic),
+	}
+	if !d.RepoBuildSpec {
+		body["buildSpec"] = d.appBuildSpec(buildCommand, outputDir, isStatic)
+	}
+
+	// SSR apps created before WEB_COMPUTE support were hosted as static sites
+	if !isStatic && settings.Platform == amplifyPlatformStatic {
+		fmt.Printf("  Warning: migrating app %s from the WEB platform to WEB_COMPUTE for SSR.\n", d.AppName)
+		fmt.Println("  The next build is served by Amplify compute; this cannot be undone from mvpbridge.")
+		if _, removed := withoutSPAFallback(settings.CustomRules); removed && len(d.CustomRules) == 0 {
+			fmt.Println("  Removing the SPA rewrite to /index.html, which would shadow SSR routes.")
+		}
+		body["platform"] = amplifyPlatformSSR
 	}
 
 	jsonBody, err := json.Marshal(body)
@@ -188,122 +375,469 @@ func (d *AWSDeployer) updateApp(appID string, envVars map[string]string, buildCo
 		return nil, err
 	}
 
-	endpoint := fmt.Sprintf(awsAmplifyAPIBase, d.Region) + "/apps/" + appID
-	req, err := http.NewRequestWithContext(context.Background(), "POST", endpoint, bytes.NewBuffer(jsonBody))
+	endpoint := d.apiBase() + "/apps/" + appID
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "POST", endpoint, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, err
 	}
 
-	return d.doRequest(req)
+	result, err := d.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.reconcileBranch(appID, envVars, isStatic); err != nil {
+		return nil, err
+	}
+
+	if result.JobID, err = d.StartJob(appID, d.Branch, d.CommitID); err != nil {
+		return nil, fmt.Errorf("starting build: %w", err)
+	}
+
+	return result, nil
 }
 
-func (d *AWSDeployer) createBranch(appID string, envVars map[string]string) error {
+// reconcileRules returns the custom rules an app should have: the configured
+// rules when set, otherwise the current rules with the SPA fallback added for
+// static sites or removed for SSR
+func (d *AWSDeployer) reconcileRules(current []AmplifyRule, isStatic bool) []AmplifyRule {
+	if len(d.CustomRules) > 0 {
+		return d.CustomRules
+	}
+
+	rules, hasFallback := withoutSPAFallback(current)
+	if isStatic {
+		if hasFallback {
+			return current
+		}
+		return append(rules, spaFallbackRule)
+	}
+	return rules
+}
+
+// spaFallbackRule serves index.html for unknown paths so client-side routing works
+var spaFallbackRule = AmplifyRule{Source: "/<*>", Target: "/index.html", Status: "404-200"}
+
+// withoutSPAFallback drops the /<*> to /index.html rewrite that static
+// deploys add, reporting whether it was present
+func withoutSPAFallback(rules []AmplifyRule) ([]AmplifyRule, bool) {
+	kept := make([]AmplifyRule, 0, len(rules))
+	for _, r := range rules {
+		if r.Source == spaFallbackRule.Source && r.Target == spaFallbackRule.Target {
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept, len(kept) != len(rules)
+}
+
+// reconcileBranch creates the deployer's branch, or updates its env vars,
+// stage and framework when it already exists
+func (d *AWSDeployer) reconcileBranch(appID string, envVars map[string]string, isStatic bool) error {
+	existing, err := d.GetBranch(appID, d.Branch)
+	if isNotFound(err) {
+		if err := d.createBranch(appID, envVars, isStatic); err != nil {
+			return fmt.Errorf("creating branch: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading branch: %w", err)
+	}
+
+	update := map[string]interface{}{
+		"stage":                "PRODUCTION",
+		"enableAutoBuild":      true,
+		"environmentVariables": keepEnv(envVars, existing.EnvironmentVariables, d.KeepEnv),
+	}
+	if !isStatic {
+		update["framework"] = amplifySSRFramework
+	}
+
+	jsonBody, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+
+	endpoint := d.apiBase() + "/apps/" + appID + "/branches/" + url.PathEscape(d.Branch)
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "POST", endpoint, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return err
+	}
+
+	if _, err := d.send(req); err != nil {
+		return fmt.Errorf("updating branch: %w", err)
+	}
+	return nil
+}
+
+func (d *AWSDeployer) createBranch(appID string, envVars map[string]string, isStatic bool) error {
 	branch := AmplifyBranch{
 		BranchName:           d.Branch,
 		EnableAutoBuild:      true,
 		Stage:                "PRODUCTION",
 		EnvironmentVariables: envVars,
 	}
+	if !isStatic {
+		branch.Framework = amplifySSRFramework
+	}
 
+	return d.postBranch(appID, branch)
+}
+
+func (d *AWSDeployer) postBranch(appID string, branch AmplifyBranch) error {
 	jsonBody, err := json.Marshal(branch)
 	if err != nil {
 		return err
 	}
 
-	endpoint := fmt.Sprintf(awsAmplifyAPIBase, d.Region) + "/apps/" + appID + "/branches"
-	req, err := http.NewRequestWithContext(context.Background(), "POST", endpoint, bytes.NewBuffer(jsonBody))
+	endpoint := d.apiBase() + "/apps/" + appID + "/branches"
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "POST", endpoint, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	d.signRequest(req)
+	_, err = d.send(req)
+	return err
+}
 
-	resp, err := d.client.Do(req)
-	if err != nil {
-		return err
+func (d *AWSDeployer) getApp() (*AmplifyAppResponse, error) {
+	if d.AppID != "" {
+		return d.getRecordedApp()
 	}
-	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, err := io.ReadAll(resp.Body)
+	nextToken := ""
+	for {
+		endpoint := fmt.Sprintf("%s/apps?maxResults=%d", d.apiBase(), amplifyAppsPerPage)
+		if nextToken != "" {
+			endpoint += "&nextToken=" + url.QueryEscape(nextToken)
+		}
+		req, err := http.NewRequestWithContext(orBackground(d.ctx), "GET", endpoint, nil)
 		if err != nil {
-			return fmt.Errorf("API error %d (failed to read body: %w)", resp.StatusCode, err)
+			return nil, err
+		}
+
+		body, err := d.send(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Apps []struct {
+				AppID string `json:"appId"`
+				Name  string `json:"name"`
+			} `json:"apps"`
+			NextToken string `json:"nextToken"`
+		}
+
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("parsing response: %w", err)
 		}
-		return fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+
+		// Find app by name
+		for _, app := range result.Apps {
+			if app.Name == d.AppName {
+				return d.getAppByID(app.AppID)
+			}
+		}
+
+		if result.NextToken == "" {
+			return nil, fmt.Errorf("%w: %s", ErrAppNotFound, d.AppName)
+		}
+		nextToken = result.NextToken
 	}
+}
 
-	return nil
+// getRecordedApp fetches the app by its recorded ID. An app renamed outside
+// mvpbridge keeps its new name, so AppName is updated to match.
+func (d *AWSDeployer) getRecordedApp() (*AmplifyAppResponse, error) {
+	app, err := d.getAppByID(d.AppID)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("%w: %s (%s)", ErrAppDeleted, d.AppName, d.AppID)
+		}
+		return nil, err
+	}
+
+	if app.App.Name != "" {
+		d.AppName = app.App.Name
+	}
+	return app, nil
 }
 
-func (d *AWSDeployer) getApp() (*AmplifyAppResponse, error) {
-	endpoint := fmt.Sprintf(awsAmplifyAPIBase, d.Region) + "/apps"
-	req, err := http.NewRequestWithContext(context.Background(), "GET", endpoint, nil)
+// FindApp looks up the deployer's app by recorded ID or by name
+func (d *AWSDeployer) FindApp() (*AmplifyAppResponse, error) {
+	return d.getApp()
+}
+
+// GetAppSettings returns the build spec, env vars and custom rules of an app
+func (d *AWSDeployer) GetAppSettings(appID string) (*AmplifyAppSettings, error) {
+	endpoint := d.apiBase() + "/apps/" + appID
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := d.send(req)
 	if err != nil {
 		return nil, err
 	}
 
-	d.signRequest(req)
-	req.Header.Set("Content-Type", "application/json")
+	var result struct {
+		App AmplifyAppSettings `json:"app"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
 
-	resp, err := d.client.Do(req)
+	return &result.App, nil
+}
+
+// GetBranch returns the settings of a branch
+func (d *AWSDeployer) GetBranch(appID, branch string) (*AmplifyBranch, error) {
+	endpoint := d.apiBase() + "/apps/" + appID + "/branches/" + url.PathEscape(branch)
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "GET", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error: %s", resp.Status)
+	body, err := d.send(req)
+	if err != nil {
+		return nil, err
 	}
 
 	var result struct {
-		Apps []struct {
-			AppID string `json:"appId"`
-			Name  string `json:"name"`
-		} `json:"apps"`
+		Branch AmplifyBranch `json:"branch"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	return &result.Branch, nil
+}
+
+// DeleteApp permanently deletes an app with all of its branches and domains
+func (d *AWSDeployer) DeleteApp(appID string) error {
+	endpoint := d.apiBase() + "/apps/" + appID
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "DELETE", endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.send(req)
+	return err
+}
+
+// ListDomainAssociations returns the custom domains connected to an app
+func (d *AWSDeployer) ListDomainAssociations(appID string) ([]AmplifyDomainAssociation, error) {
+	endpoint := d.apiBase() + "/apps/" + appID + "/domains"
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "GET", endpoint, nil)
+	if err != nil {
 		return nil, err
 	}
 
-	// Find app by name
-	for _, app := range result.Apps {
-		if app.Name == d.AppName {
-			return d.getAppByID(app.AppID)
-		}
+	body, err := d.send(req)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("app not found: %s", d.AppName)
+	var result struct {
+		DomainAssociations []AmplifyDomainAssociation `json:"domainAssociations"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	return result.DomainAssociations, nil
 }
 
-func (d *AWSDeployer) getAppByID(appID string) (*AmplifyAppResponse, error) {
-	endpoint := fmt.Sprintf(awsAmplifyAPIBase, d.Region) + "/apps/" + appID
-	req, err := http.NewRequestWithContext(context.Background(), "GET", endpoint, nil)
+// ListBranches returns all branches connected to an app
+func (d *AWSDeployer) ListBranches(appID string) ([]AmplifyBranchSummary, error) {
+	endpoint := d.apiBase() + "/apps/" + appID + "/branches"
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "GET", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	return d.doRequest(req)
+	body, err := d.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Branches []AmplifyBranchSummary `json:"branches"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	return result.Branches, nil
 }
 
-func (d *AWSDeployer) doRequest(req *http.Request) (*AmplifyAppResponse, error) {
-	d.signRequest(req)
-	req.Header.Set("Content-Type", "application/json")
+// ListJobs returns the most recent build jobs of a branch, newest first
+func (d *AWSDeployer) ListJobs(appID, branch string, maxResults int) ([]AmplifyJobSummary, error) {
+	endpoint := d.apiBase() + "/apps/" + appID + "/branches/" + url.PathEscape(branch) + "/jobs"
+	if maxResults > 0 {
+		endpoint += fmt.Sprintf("?maxResults=%d", maxResults)
+	}
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
 
-	resp, err := d.client.Do(req)
+	body, err := d.send(req)
 	if err != nil {
 		return nil, err
 	}
-	defer func() { _ = resp.Body.Close() }()
 
-	body, err := io.ReadAll(resp.Body)
+	var result struct {
+		JobSummaries []AmplifyJobSummary `json:"jobSummaries"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	return result.JobSummaries, nil
+}
+
+// GetLogs fetches the log of a job's failed step, or of its last step when
+// none failed. The API answers with links to the logs, which are then
+// downloaded.
+func (d *AWSDeployer) GetLogs(appID, branch, jobID string) (string, error) {
+	endpoint := d.apiBase() + "/apps/" + appID + "/branches/" + url.PathEscape(branch) + "/jobs/" + jobID
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "GET", endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := d.send(req)
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		Job struct {
+			Steps []AmplifyJobStep `json:"steps"`
+		} `json:"job"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("parsing response: %w", err)
+	}
+
+	logURL := ""
+	for _, step := range result.Job.Steps {
+		if step.LogURL == "" {
+			continue
+		}
+		logURL = step.LogURL
+		if step.Status == "FAILED" {
+			break
+		}
+	}
+	if logURL == "" {
+		return "", nil
+	}
+	return fetchLog(orBackground(d.ctx), d.client, logURL)
+}
+
+// WaitForLatestJob polls the newest job of a branch until it finishes
+func (d *AWSDeployer) WaitForLatestJob(appID, branch string, timeout time.Duration) (*AmplifyJobSummary, error) {
+	deadline := time.Now().Add(timeout)
+
+	for time.Now().Before(deadline) {
+		jobs, err := d.ListJobs(appID, branch, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(jobs) > 0 {
+			job := jobs[0]
+			switch job.Status {
+			case "SUCCEED":
+				return &job, nil
+			case "FAILED", "CANCELLED":
+				return nil, fmt.Errorf("job %s failed: %s", job.JobID, job.Status)
+			}
+			fmt.Printf("  Job %s status: %s\n", job.JobID, job.Status)
+		}
+
+		if err := sleepContext(orBackground(d.ctx), pollInterval); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("job timed out after %v", timeout)
+}
+
+// DeleteBranch removes a branch and its deployments from an app
+func (d *AWSDeployer) DeleteBranch(appID, branch string) error {
+	endpoint := d.apiBase() + "/apps/" + appID + "/branches/" + url.PathEscape(branch)
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "DELETE", endpoint, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return err
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+	_, err = d.send(req)
+	return err
+}
+
+// StartJob starts a RELEASE build of a branch and returns the job ID
+func (d *AWSDeployer) StartJob(appID, branch, commitID string) (string, error) {
+	job := map[string]string{"jobType": "RELEASE"}
+	if commitID != "" {
+		job["commitId"] = commitID
+	}
+	return d.startJob(appID, branch, job)
+}
+
+// RetryJob re-runs an earlier job of a branch, rebuilding the same commit
+func (d *AWSDeployer) RetryJob(appID, branch, jobID string) (string, error) {
+	return d.startJob(appID, branch, map[string]string{"jobType": "RETRY", "jobId": jobID})
+}
+
+func (d *AWSDeployer) startJob(appID, branch string, job map[string]string) (string, error) {
+	jsonBody, err := json.Marshal(job)
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := d.apiBase() + "/apps/" + appID + "/branches/" + url.PathEscape(branch) + "/jobs"
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "POST", endpoint, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return "", err
+	}
+
+	body, err := d.send(req)
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		JobSummary struct {
+			JobID string `json:"jobId"`
+		} `json:"jobSummary"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("parsing response: %w", err)
+	}
+
+	return result.JobSummary.JobID, nil
+}
+
+func (d *AWSDeployer) getAppByID(appID string) (*AmplifyAppResponse, error) {
+	endpoint := d.apiBase() + "/apps/" + appID
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.doRequest(req)
+}
+
+func (d *AWSDeployer) doRequest(req *http.Request) (*AmplifyAppResponse, error) {
+	body, err := d.send(req)
+	if err != nil {
+		return nil, err
 	}
 
 	var result AmplifyAppResponse
@@ -314,6 +848,52 @@ func (d *AWSDeployer) doRequest(req *http.Request) (*AmplifyAppResponse, error)
 	return &result, nil
 }
 
+// send signs and performs a request and returns the raw response body
+func (d *AWSDeployer) send(req *http.Request) ([]byte, error) {
+	return sendWithRetry(d.client, req, func(r *http.Request) {
+		r.Header.Set("Content-Type", "application/json")
+		d.signRequest(r)
+	})
+}
+
+// apiBase returns the Amplify endpoint for the deployer's region
+func (d *AWSDeployer) apiBase() string {
+	if d.endpoint != "" {
+		return d.endpoint
+	}
+	return fmt.Sprintf(awsAmplifyAPIBase, d.Region)
+}
+
+// ssrBuildSpec generates the build spec for a Next.js app on WEB_COMPUTE,
+// which must publish the whole .next directory rather than static output
+func (d *AWSDeployer) ssrBuildSpec(buildCommand string) string {
+	if d.BuildSpec != "" {
+		return d.BuildSpec
+	}
+	if buildCommand == "" {
+		buildCommand = "npm run build"
+	}
+
+	return fmt.Sprintf(`version: 1
+frontend:
+  phases:
+    preBuild:
+      commands:
+        - %s
+    build:
+      commands:
+        - %s
+  artifacts:
+    baseDirectory: .next
+    files:
+      - '**/*'
+  cache:
+    paths:
+      - .next/cache/**/*
+      - node_modules/**/*
+`, buildspec.InstallCommand(d.PackageManager), buildCommand)
+}
+
 // signRequest adds AWS Signature Version 4 authentication
 func (d *AWSDeployer) signRequest(req *http.Request) {
 	d.signRequestWithTime(req, time.Now().UTC())
@@ -321,7 +901,11 @@ func (d *AWSDeployer) signRequest(req *http.Request) {
 
 // signRequestWithTime signs the request with a specific timestamp (for testing)
 func (d *AWSDeployer) signRequestWithTime(req *http.Request, t time.Time) {
-	const service = "amplify"
+	d.signRequestFor(req, "amplify", d.Region, t)
+}
+
+// signRequestFor signs the request for any AWS service and region
+func (d *AWSDeployer) signRequestFor(req *http.Request, service, region string, t time.Time) {
 	const algorithm = "AWS4-HMAC-SHA256"
 
 	// Format timestamps
@@ -334,6 +918,11 @@ func (d *AWSDeployer) signRequestWithTime(req *http.Request, t time.Time) {
 		req.Header.Set("Host", req.URL.Host)
 	}
 
+	// Temporary credentials must send their session token as a signed header
+	if d.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", d.SessionToken)
+	}
+
 	// Get payload hash
 	payloadHash := d.getPayloadHash(req)
 	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
@@ -342,13 +931,13 @@ func (d *AWSDeployer) signRequestWithTime(req *http.Request, t time.Time) {
 	canonicalRequest, signedHeaders := d.createCanonicalRequest(req, payloadHash)
 
 	// Create credential scope
-	credentialScope := fmt.Sprintf("%s/%s/%s/aws4_request", dateStamp, d.Region, service)
+	credentialScope := fmt.Sprintf("%s/%s/%s/aws4_request", dateStamp, region, service)
 
 	// Create string to sign
 	stringToSign := d.createStringToSign(algorithm, amzDate, credentialScope, canonicalRequest)
 
 	// Calculate signing key
-	signingKey := d.deriveSigningKey(d.SecretKey, dateStamp, d.Region, service)
+	signingKey := d.deriveSigningKey(d.SecretKey, dateStamp, region, service)
 
 	// Calculate signature
 	signature := hex.EncodeToString(hmacSHA256(signingKey, []byte(stringToSign)))
@@ -527,6 +1116,9 @@ func sha256Hash(data []byte) string {
 }
 
 func (d *AWSDeployer) buildSpec(buildCommand, outputDir string) string {
+	if d.BuildSpec != "" {
+		return d.BuildSpec
+	}
 	if buildCommand == "" {
 		buildCommand = "npm run build"
 	}
@@ -539,7 +1131,7 @@ frontend:
   phases:
     preBuild:
       commands:
-        - npm ci
+        - %s
     build:
       commands:
         - %s
@@ -550,5 +1142,5 @@ frontend:
   cache:
     paths:
       - node_modules/**/*
-`, buildCommand, outputDir)
+`, buildspec.InstallCommand(d.PackageManager), buildCommand, outputDir)
 }
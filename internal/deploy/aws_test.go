@@ -3,12 +3,16 @@ package deploy
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
 	"time"
+
+	"mvpbridge/internal/detect"
+	"mvpbridge/internal/git"
 )
 
 func TestNewAWSDeployer(t *testing.T) {
@@ -1061,3 +1065,430 @@ func TestSignRequestDifferentRegions(t *testing.T) {
 		t.Error("Auth header should contain region us-west-2")
 	}
 }
+
+func TestAWSDeleteAppAndDomains(t *testing.T) {
+	var deleted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "DELETE" && r.URL.Path == "/apps/app1":
+			deleted = "app1"
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == httpMethodGet && r.URL.Path == "/apps/app1/domains":
+			_, _ = w.Write([]byte(`{"domainAssociations": [{"domainName": "example.com", "subDomains": [{"subDomainSetting": {"prefix": "www", "branchName": "main"}}]}]}`))
+		default:
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	deployer := &AWSDeployer{Region: "us-east-1", client: server.Client(), endpoint: server.URL}
+
+	domains, err := deployer.ListDomainAssociations("app1")
+	if err != nil {
+		t.Fatalf("ListDomainAssociations() error: %v", err)
+	}
+	if len(domains) != 1 || domains[0].DomainName != "example.com" {
+		t.Fatalf("Unexpected domains: %+v", domains)
+	}
+	if domains[0].SubDomains[0].SubDomainSetting.BranchName != "main" {
+		t.Errorf("Expected subdomain mapped to main, got %+v", domains[0].SubDomains[0])
+	}
+
+	if err := deployer.DeleteApp("app1"); err != nil {
+		t.Fatalf("DeleteApp() error: %v", err)
+	}
+	if deleted != "app1" {
+		t.Error("Expected DELETE request for app1")
+	}
+}
+
+func TestAWSListJobs(t *testing.T) {
+	var gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/apps/app1/branches/main/jobs" {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		gotQuery = r.URL.RawQuery
+		_, _ = w.Write([]byte(`{"jobSummaries": [{"jobId": "7", "jobType": "RELEASE", "status": "SUCCEED", "commitId": "abc123", "startTime": 1700000000.5}]}`))
+	}))
+	defer server.Close()
+
+	deployer := &AWSDeployer{Region: "us-east-1", client: server.Client(), endpoint: server.URL}
+
+	jobs, err := deployer.ListJobs("app1", "main", 1)
+	if err != nil {
+		t.Fatalf("ListJobs() error: %v", err)
+	}
+
+	if gotQuery != "maxResults=1" {
+		t.Errorf("Expected maxResults=1 query, got %q", gotQuery)
+	}
+	if len(jobs) != 1 || jobs[0].Status != "SUCCEED" || jobs[0].CommitID != "abc123" {
+		t.Fatalf("Unexpected jobs: %+v", jobs)
+	}
+	if jobs[0].Started().Unix() != 1700000000 {
+		t.Errorf("Unexpected start time: %v", jobs[0].Started())
+	}
+}
+
+func TestAWSGetLogs(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/build.log":
+			if r.Header.Get("Authorization") != "" {
+				t.Error("Expected the log link fetched without signing")
+			}
+			_, _ = w.Write([]byte("npm ERR! missing script: build\n"))
+		case "/apps/app1/branches/main/jobs/7":
+			_, _ = w.Write([]byte(`{"job": {"summary": {"jobId": "7", "status": "FAILED"}, "steps": [
+				{"stepName": "BUILD", "status": "FAILED", "logUrl": "` + server.URL + `/build.log"},
+				{"stepName": "DEPLOY", "status": "CANCELLED", "logUrl": "` + server.URL + `/deploy.log"}
+			]}}`))
+		default:
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	deployer := &AWSDeployer{Region: "us-east-1", client: server.Client(), endpoint: server.URL}
+	logs, err := deployer.GetLogs("app1", "main", "7")
+	if err != nil {
+		t.Fatalf("GetLogs() error: %v", err)
+	}
+	if logs != "npm ERR! missing script: build\n" {
+		t.Errorf("Expected the failed step's log, got %q", logs)
+	}
+}
+
+func TestAWSFindAppPaginates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/apps" && r.URL.Query().Get("nextToken") == "":
+			_, _ = w.Write([]byte(`{"apps": [{"appId": "a1", "name": "other"}], "nextToken": "page/2"}`))
+		case r.URL.Path == "/apps" && r.URL.Query().Get("nextToken") == "page/2":
+			_, _ = w.Write([]byte(`{"apps": [{"appId": "a2", "name": "my-app"}]}`))
+		case r.URL.Path == "/apps/a2":
+			_, _ = w.Write([]byte(`{"app": {"appId": "a2", "name": "my-app"}}`))
+		default:
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	deployer := &AWSDeployer{Region: "us-east-1", AppName: "my-app", client: server.Client(), endpoint: server.URL}
+
+	result, err := deployer.FindApp()
+	if err != nil {
+		t.Fatalf("FindApp() error: %v", err)
+	}
+	if result.App.AppID != "a2" {
+		t.Errorf("Expected app from second page, got %q", result.App.AppID)
+	}
+
+	deployer.AppName = "missing"
+	if _, err := deployer.FindApp(); !errors.Is(err, ErrAppNotFound) {
+		t.Errorf("Expected ErrAppNotFound after last page, got %v", err)
+	}
+}
+
+func TestAWSFindAppByRecordedID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/apps/a1" {
+			_, _ = w.Write([]byte(`{"app": {"appId": "a1", "name": "renamed-app"}}`))
+			return
+		}
+		http.Error(w, `{"message": "App not found"}`, http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	deployer := &AWSDeployer{Region: "us-east-1", AppName: "my-app", AppID: "a1", client: server.Client(), endpoint: server.URL}
+
+	if _, err := deployer.FindApp(); err != nil {
+		t.Fatalf("FindApp() error: %v", err)
+	}
+	if deployer.AppName != "renamed-app" {
+		t.Errorf("Expected remote name to be adopted, got %q", deployer.AppName)
+	}
+
+	deployer.AppID = "gone"
+	if _, err := deployer.FindApp(); !errors.Is(err, ErrAppDeleted) {
+		t.Errorf("Expected ErrAppDeleted, got %v", err)
+	}
+}
+
+func TestAWSGetAppSettingsAndBranch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apps/a1":
+			_, _ = w.Write([]byte(`{"app": {"appId": "a1", "name": "my-app", "platform": "WEB",
+				"buildSpec": "version: 1\n", "environmentVariables": {"API_URL": "https://api"},
+				"customRules": [{"source": "/api/<*>", "target": "https://api/<*>", "status": "200"}]}}`))
+		case "/apps/a1/branches/main":
+			_, _ = w.Write([]byte(`{"branch": {"branchName": "main", "stage": "PRODUCTION", "framework": "React"}}`))
+		default:
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	deployer := &AWSDeployer{Region: "us-east-1", client: server.Client(), endpoint: server.URL}
+
+	settings, err := deployer.GetAppSettings("a1")
+	if err != nil {
+		t.Fatalf("GetAppSettings() error: %v", err)
+	}
+	if settings.BuildSpec != "version: 1\n" || settings.EnvironmentVariables["API_URL"] != "https://api" {
+		t.Errorf("Unexpected settings: %+v", settings)
+	}
+	if len(settings.CustomRules) != 1 || settings.CustomRules[0].Source != "/api/<*>" {
+		t.Errorf("Unexpected custom rules: %+v", settings.CustomRules)
+	}
+
+	branch, err := deployer.GetBranch("a1", "main")
+	if err != nil {
+		t.Fatalf("GetBranch() error: %v", err)
+	}
+	if branch.Stage != "PRODUCTION" || branch.Framework != "React" {
+		t.Errorf("Unexpected branch: %+v", branch)
+	}
+}
+
+func TestAWSBuildSpecOverride(t *testing.T) {
+	deployer := &AWSDeployer{BuildSpec: "version: 1\nfrontend: {}\n"}
+
+	if got := deployer.buildSpec("npm run build", "dist"); got != deployer.BuildSpec {
+		t.Errorf("Expected configured build spec, got %q", got)
+	}
+}
+
+func TestAWSDeploySSR(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "test-token")
+
+	tests := []struct {
+		name         string
+		existing     bool
+		repoSpec     bool
+		wantPlatform string
+		wantRules    int
+	}{
+		{name: "Create SSR app", wantPlatform: "WEB_COMPUTE"},
+		{name: "Migrate WEB app", existing: true, wantPlatform: "WEB_COMPUTE", wantRules: 1},
+		{name: "Create with a repository amplify.yml", repoSpec: true, wantPlatform: "WEB_COMPUTE"},
+		{name: "Migrate with a repository amplify.yml", existing: true, repoSpec: true, wantPlatform: "WEB_COMPUTE", wantRules: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var appBody map[string]interface{}
+			var branchBody map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == httpMethodGet && r.URL.Path == "/apps":
+					if tt.existing {
+						_, _ = w.Write([]byte(`{"apps": [{"appId": "a1", "name": "my-app"}]}`))
+						return
+					}
+					_, _ = w.Write([]byte(`{"apps": []}`))
+				case r.Method == httpMethodGet && r.URL.Path == "/apps/a1":
+					_, _ = w.Write([]byte(`{"app": {"appId": "a1", "name": "my-app", "platform": "WEB", "customRules": [
+						{"source": "/<*>", "target": "/index.html", "status": "404-200"},
+						{"source": "/old", "target": "/new", "status": "301"}]}}`))
+				case r.Method == httpMethodPost && (r.URL.Path == "/apps" || r.URL.Path == "/apps/a1"):
+					_ = json.NewDecoder(r.Body).Decode(&appBody)
+					_, _ = w.Write([]byte(`{"app": {"appId": "a1", "name": "my-app"}}`))
+				case r.Method == httpMethodPost && strings.HasSuffix(r.URL.Path, "/jobs"):
+					_, _ = w.Write([]byte(`{"jobSummary": {"jobId": "1"}}`))
+				case r.Method == httpMethodPost && strings.HasPrefix(r.URL.Path, "/apps/a1/branches"):
+					_ = json.NewDecoder(r.Body).Decode(&branchBody)
+					_, _ = w.Write([]byte(`{"branch": {"branchName": "main"}}`))
+				default:
+					http.Error(w, "Not found", http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			deployer := &AWSDeployer{Region: "us-east-1", AppName: "my-app", Branch: "main", RepoBuildSpec: tt.repoSpec, client: server.Client(), endpoint: server.URL}
+
+			result, err := deployer.Deploy(false, nil, "npm run build", ".next")
+			if err != nil {
+				t.Fatalf("Deploy() error: %v", err)
+			}
+			if result.JobID != "1" {
+				t.Errorf("Expected job ID 1, got %q", result.JobID)
+			}
+
+			if appBody["platform"] != tt.wantPlatform {
+				t.Errorf("Expected platform %s, got %v", tt.wantPlatform, appBody["platform"])
+			}
+			if spec, has := appBody["buildSpec"]; tt.repoSpec && has {
+				t.Errorf("Expected no app build spec beside the repository's amplify.yml, got:\n%v", spec)
+			}
+			if spec, _ := appBody["buildSpec"].(string); !tt.repoSpec && (!strings.Contains(spec, "baseDirectory: .next") || !strings.Contains(spec, ".next/cache/**/*")) {
+				t.Errorf("Expected SSR build spec, got:\n%s", spec)
+			}
+			if branchBody["framework"] != "Next.js - SSR" {
+				t.Errorf("Expected branch framework Next.js - SSR, got %v", branchBody["framework"])
+			}
+			if tt.existing {
+				rules, _ := appBody["customRules"].([]interface{})
+				if len(rules) != tt.wantRules {
+					t.Errorf("Expected SPA fallback removed leaving %d rules, got %v", tt.wantRules, rules)
+				}
+			}
+		})
+	}
+}
+
+func TestAWSDeployUpdateReconcilesBranch(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "test-token")
+
+	tests := []struct {
+		name        string
+		branch      string
+		customRules []AmplifyRule
+		wantRules   []string
+		wantCreate  bool
+	}{
+		{name: "Existing branch keeps rules and adds fallback", branch: `{"branch": {"branchName": "main", "stage": "DEVELOPMENT", "environmentVariables": {"OLD": "1", "KEEP": "x"}}}`, wantRules: []string{"/old", "/<*>"}},
+		{name: "Missing branch is created", wantRules: []string{"/old", "/<*>"}, wantCreate: true},
+		{name: "Configured rules replace remote rules", branch: `{"branch": {"branchName": "main"}}`, customRules: []AmplifyRule{{Source: "/a", Target: "/b", Status: "302"}}, wantRules: []string{"/a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var appBody, branchBody, jobBody map[string]interface{}
+			var branchPath string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == httpMethodGet && r.URL.Path == "/apps/a1":
+					_, _ = w.Write([]byte(`{"app": {"appId": "a1", "name": "my-app", "platform": "WEB", "customRules": [
+						{"source": "/old", "target": "/new", "status": "301"}]}}`))
+				case r.Method == httpMethodGet && r.URL.Path == "/apps/a1/branches/main":
+					if tt.branch == "" {
+						http.Error(w, "Not found", http.StatusNotFound)
+						return
+					}
+					_, _ = w.Write([]byte(tt.branch))
+				case r.Method == httpMethodPost && r.URL.Path == "/apps/a1":
+					_ = json.NewDecoder(r.Body).Decode(&appBody)
+					_, _ = w.Write([]byte(`{"app": {"appId": "a1", "name": "my-app"}}`))
+				case r.Method == httpMethodPost && r.URL.Path == "/apps/a1/branches/main/jobs":
+					_ = json.NewDecoder(r.Body).Decode(&jobBody)
+					_, _ = w.Write([]byte(`{"jobSummary": {"jobId": "7"}}`))
+				case r.Method == httpMethodPost && strings.HasPrefix(r.URL.Path, "/apps/a1/branches"):
+					branchPath = r.URL.Path
+					_ = json.NewDecoder(r.Body).Decode(&branchBody)
+					_, _ = w.Write([]byte(`{"branch": {"branchName": "main"}}`))
+				default:
+					http.Error(w, "Not found", http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			deployer := &AWSDeployer{
+				Region:      "us-east-1",
+				AppID:       "a1",
+				Branch:      "main",
+				CommitID:    "abc123",
+				KeepEnv:     []string{"KEEP"},
+				CustomRules: tt.customRules,
+				client:      server.Client(),
+				endpoint:    server.URL,
+			}
+
+			result, err := deployer.Deploy(true, map[string]string{"NEW": "2"}, "npm run build", "dist")
+			if err != nil {
+				t.Fatalf("Deploy() error: %v", err)
+			}
+			if result.JobID != "7" {
+				t.Errorf("Expected job ID 7, got %q", result.JobID)
+			}
+
+			rules, _ := appBody["customRules"].([]interface{})
+			var sources []string
+			for _, r := range rules {
+				sources = append(sources, r.(map[string]interface{})["source"].(string))
+			}
+			if strings.Join(sources, " ") != strings.Join(tt.wantRules, " ") {
+				t.Errorf("Expected rules %v, got %v", tt.wantRules, sources)
+			}
+
+			wantPath := "/apps/a1/branches/main"
+			if tt.wantCreate {
+				wantPath = "/apps/a1/branches"
+			}
+			if branchPath != wantPath {
+				t.Errorf("Expected branch request to %s, got %s", wantPath, branchPath)
+			}
+			if branchBody["stage"] != "PRODUCTION" {
+				t.Errorf("Expected PRODUCTION stage, got %v", branchBody["stage"])
+			}
+			env, _ := branchBody["environmentVariables"].(map[string]interface{})
+			if env["NEW"] != "2" || env["OLD"] != nil {
+				t.Errorf("Unexpected branch env: %v", env)
+			}
+			if strings.Contains(tt.branch, "KEEP") && env["KEEP"] != "x" {
+				t.Errorf("Expected kept env var KEEP, got %v", env)
+			}
+
+			if jobBody["jobType"] != "RELEASE" || jobBody["commitId"] != "abc123" {
+				t.Errorf("Unexpected job request: %v", jobBody)
+			}
+		})
+	}
+}
+
+func TestAmplifyRepository(t *testing.T) {
+	tests := []struct {
+		raw       string
+		want      string
+		wantErr   bool
+		wantToken string
+	}{
+		{raw: "git@github.com:user/repo.git", want: "https://github.com/user/repo", wantToken: "GITHUB_TOKEN"},
+		{raw: "git@gitlab.com:group/repo.git", want: "https://gitlab.com/group/repo", wantToken: "GITLAB_TOKEN"},
+		{raw: "git@bitbucket.org:team/repo.git", want: "https://bitbucket.org/team/repo", wantToken: "BITBUCKET_TOKEN"},
+		{raw: "codecommit::us-east-1://repo", want: "https://git-codecommit.us-east-1.amazonaws.com/v1/repos/repo"},
+		{raw: "git@github.example.com:org/repo.git", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		remote, err := git.ParseRemote(tt.raw)
+		if err != nil {
+			t.Fatalf("ParseRemote(%q) error: %v", tt.raw, err)
+		}
+		got, err := AmplifyRepository(remote)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("AmplifyRepository(%q) = %q, %v, want %q", tt.raw, got, err, tt.want)
+		}
+		if _, env := amplifyRepoToken(got); !tt.wantErr && env != tt.wantToken {
+			t.Errorf("Token for %q = %q, want %q", tt.raw, env, tt.wantToken)
+		}
+	}
+}
+
+func TestBuildSpecInstallCommand(t *testing.T) {
+	tests := []struct {
+		pm   detect.PackageManager
+		want string
+	}{
+		{detect.NPM, "- npm ci"},
+		{detect.Yarn, "- yarn install --frozen-lockfile"},
+		{detect.PNPM, "- pnpm install --frozen-lockfile"},
+	}
+
+	for _, tt := range tests {
+		deployer := &AWSDeployer{PackageManager: tt.pm}
+		for _, isStatic := range []bool{true, false} {
+			spec := deployer.appBuildSpec("", "", isStatic)
+			if !strings.Contains(spec, tt.want) {
+				t.Errorf("Build spec for %s (static %v) missing %q:\n%s", tt.pm, isStatic, tt.want, spec)
+			}
+			if tt.pm != detect.NPM && strings.Contains(spec, "npm ci") {
+				t.Errorf("Build spec for %s still runs npm ci:\n%s", tt.pm, spec)
+			}
+		}
+	}
+}
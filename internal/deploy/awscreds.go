@@ -0,0 +1,338 @@
+package deploy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// maxProfileDepth bounds source_profile chains so cycles fail cleanly
+const maxProfileDepth = 5
+
+// stsEndpoint returns the STS endpoint for a region
+var stsEndpoint = func(region string) string {
+	return fmt.Sprintf("https://sts.%s.amazonaws.com/", region)
+}
+
+// ErrNoAWSCredentials is returned when neither the environment nor the
+// default profile provides credentials
+var ErrNoAWSCredentials = errors.New("no AWS credentials found: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, configure a profile in ~/.aws/credentials, or run 'mvpbridge auth login aws'")
+
+// AWSCredentials holds an access key pair and, for temporary credentials,
+// the session token that must accompany it
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Source          string // where the credentials came from, for display
+}
+
+// awsProfile is the merged view of a profile across the shared config files
+type awsProfile map[string]string
+
+// ResolveAWSCredentials finds credentials the way the AWS CLI does. An
+// explicit profile wins, then AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY (with
+// AWS_SESSION_TOKEN), then AWS_PROFILE or the default profile from
+// ~/.aws/credentials and ~/.aws/config.
+func ResolveAWSCredentials(profile string) (*AWSCredentials, error) {
+	return ResolveAWSCredentialsContext(context.Background(), profile, "")
+}
+
+// ResolveAWSCredentialsContext is ResolveAWSCredentials with a context that
+// bounds credential_process commands and STS calls. A configured profile,
+// such as one from project config, ranks with AWS_PROFILE: environment
+// credentials still win, so CI runners need not have the profile.
+func ResolveAWSCredentialsContext(ctx context.Context, profile, configured string) (*AWSCredentials, error) {
+	if profile == "" {
+		creds, err := envCredentials()
+		if err != nil || creds != nil {
+			return creds, err
+		}
+		profile = os.Getenv("AWS_PROFILE")
+		if profile == "" {
+			profile = configured
+		}
+	}
+
+	explicit := profile != ""
+	if profile == "" {
+		profile = "default"
+	}
+
+	profiles, err := loadAWSProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := profiles[profile]; !ok {
+		if explicit {
+			return nil, fmt.Errorf("AWS profile %q not found in ~/.aws/credentials or ~/.aws/config", profile)
+		}
+		return nil, ErrNoAWSCredentials
+	}
+
+	return resolveProfile(ctx, profiles, profile, 0)
+}
+
+// envCredentials returns credentials from the environment, or nil if none are set
+func envCredentials() (*AWSCredentials, error) {
+	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+
+	if accessKey == "" && secretKey == "" {
+		return nil, nil
+	}
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables must both be set")
+	}
+
+	return &AWSCredentials{
+		AccessKeyID:     accessKey,
+		SecretAccessKey: secretKey,
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		Source:          "environment",
+	}, nil
+}
+
+func resolveProfile(ctx context.Context, profiles map[string]awsProfile, name string, depth int) (*AWSCredentials, error) {
+	if depth > maxProfileDepth {
+		return nil, fmt.Errorf("AWS profile %q: source_profile chain too deep", name)
+	}
+
+	p, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("AWS profile %q not found", name)
+	}
+
+	if roleARN := p["role_arn"]; roleARN != "" {
+		var base *AWSCredentials
+		var err error
+		switch {
+		case p["source_profile"] == name:
+			base, err = staticProfileCredentials(p, name)
+		case p["source_profile"] != "":
+			base, err = resolveProfile(ctx, profiles, p["source_profile"], depth+1)
+		case p["credential_source"] == "Environment":
+			base, err = envCredentials()
+			if err == nil && base == nil {
+				err = fmt.Errorf("AWS profile %q: credential_source is Environment but AWS_ACCESS_KEY_ID is not set", name)
+			}
+		default:
+			err = fmt.Errorf("AWS profile %q: role_arn requires source_profile or credential_source", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return assumeRole(ctx, base, p, name)
+	}
+
+	if process := p["credential_process"]; process != "" {
+		return processCredentials(ctx, process, name)
+	}
+
+	if p["sso_start_url"] != "" || p["sso_session"] != "" {
+		return nil, fmt.Errorf("AWS profile %q uses SSO, which is not supported directly: run 'aws sso login' and set credential_process = aws configure export-credentials --profile %s --format process", name, name)
+	}
+
+	return staticProfileCredentials(p, name)
+}
+
+func staticProfileCredentials(p awsProfile, name string) (*AWSCredentials, error) {
+	if p["aws_access_key_id"] == "" || p["aws_secret_access_key"] == "" {
+		return nil, fmt.Errorf("AWS profile %q has no credentials", name)
+	}
+
+	return &AWSCredentials{
+		AccessKeyID:     p["aws_access_key_id"],
+		SecretAccessKey: p["aws_secret_access_key"],
+		SessionToken:    p["aws_session_token"],
+		Source:          "profile " + name,
+	}, nil
+}
+
+// processCredentials runs a credential_process command and parses its output
+func processCredentials(ctx context.Context, command, name string) (*AWSCredentials, error) {
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+
+	// #nosec G204 - credential_process is configured by the user in ~/.aws/config
+	cmd := exec.CommandContext(ctx, shell, flag, command)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("AWS profile %q: credential_process failed: %w", name, err)
+	}
+
+	var result struct {
+		Version         int    `json:"Version"`
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string `json:"SecretAccessKey"`
+		SessionToken    string `json:"SessionToken"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("AWS profile %q: parsing credential_process output: %w", name, err)
+	}
+	if result.Version != 1 {
+		return nil, fmt.Errorf("AWS profile %q: unsupported credential_process version %d", name, result.Version)
+	}
+	if result.AccessKeyID == "" || result.SecretAccessKey == "" {
+		return nil, fmt.Errorf("AWS profile %q: credential_process returned no credentials", name)
+	}
+
+	return &AWSCredentials{
+		AccessKeyID:     result.AccessKeyID,
+		SecretAccessKey: result.SecretAccessKey,
+		SessionToken:    result.SessionToken,
+		Source:          "credential_process for profile " + name,
+	}, nil
+}
+
+// assumeRole exchanges base credentials for the profile's role with a
+// SigV4-signed STS AssumeRole call
+func assumeRole(ctx context.Context, base *AWSCredentials, p awsProfile, name string) (*AWSCredentials, error) {
+	region := p["region"]
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	sessionName := p["role_session_name"]
+	if sessionName == "" {
+		sessionName = fmt.Sprintf("mvpbridge-%d", time.Now().Unix())
+	}
+
+	form := url.Values{}
+	form.Set("Action", "AssumeRole")
+	form.Set("Version", "2011-06-15")
+	form.Set("RoleArn", p["role_arn"])
+	form.Set("RoleSessionName", sessionName)
+	if p["external_id"] != "" {
+		form.Set("ExternalId", p["external_id"])
+	}
+	if p["duration_seconds"] != "" {
+		form.Set("DurationSeconds", p["duration_seconds"])
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", stsEndpoint(region), bytes.NewBufferString(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	signer := &AWSDeployer{AccessKey: base.AccessKeyID, SecretKey: base.SecretAccessKey, SessionToken: base.SessionToken}
+	client := &http.Client{Timeout: 30 * time.Second}
+	body, err := sendWithRetry(client, req, func(r *http.Request) {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		signer.signRequestFor(r, "sts", region, time.Now().UTC())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("AWS profile %q: assuming role %s: %w", name, p["role_arn"], err)
+	}
+
+	var result struct {
+		Credentials struct {
+			AccessKeyID     string `xml:"AccessKeyId"`
+			SecretAccessKey string `xml:"SecretAccessKey"`
+			SessionToken    string `xml:"SessionToken"`
+		} `xml:"AssumeRoleResult>Credentials"`
+	}
+	if err := xml.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	return &AWSCredentials{
+		AccessKeyID:     result.Credentials.AccessKeyID,
+		SecretAccessKey: result.Credentials.SecretAccessKey,
+		SessionToken:    result.Credentials.SessionToken,
+		Source:          fmt.Sprintf("role %s via profile %s", p["role_arn"], name),
+	}, nil
+}
+
+// loadAWSProfiles reads ~/.aws/config and ~/.aws/credentials (or the files
+// named by AWS_CONFIG_FILE and AWS_SHARED_CREDENTIALS_FILE). Keys in the
+// credentials file override those in the config file.
+func loadAWSProfiles() (map[string]awsProfile, error) {
+	home, _ := os.UserHomeDir()
+
+	configPath := os.Getenv("AWS_CONFIG_FILE")
+	if configPath == "" {
+		configPath = filepath.Join(home, ".aws", "config")
+	}
+	credentialsPath := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if credentialsPath == "" {
+		credentialsPath = filepath.Join(home, ".aws", "credentials")
+	}
+
+	profiles := make(map[string]awsProfile)
+	if err := readAWSProfileFile(configPath, true, profiles); err != nil {
+		return nil, err
+	}
+	if err := readAWSProfileFile(credentialsPath, false, profiles); err != nil {
+		return nil, err
+	}
+
+	return profiles, nil
+}
+
+// readAWSProfileFile parses an INI-style AWS file into profiles. In the
+// config file sections other than [default] are written as [profile name].
+func readAWSProfileFile(path string, isConfig bool, profiles map[string]awsProfile) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var current awsProfile
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if isConfig && name != "default" {
+				var ok bool
+				if name, ok = strings.CutPrefix(name, "profile "); !ok {
+					// [sso-session x] and [services x] sections are not profiles
+					current = nil
+					continue
+				}
+				name = strings.TrimSpace(name)
+			}
+			if profiles[name] == nil {
+				profiles[name] = make(awsProfile)
+			}
+			current = profiles[name]
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			current[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	return scanner.Err()
+}
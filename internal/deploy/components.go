@@ -0,0 +1,169 @@
+package deploy
+
+import "sort"
+
+// DOComponent is an extra component built from the deployed repository,
+// such as an API service beside a static frontend
+type DOComponent struct {
+	Name         string
+	Kind         string // service, worker or job
+	SourceDir    string
+	BuildCommand string
+	RunCommand   string
+	HTTPPort     int    // services
+	Route        string // services: path prefix routed to the service
+	JobKind      string // jobs: PRE_DEPLOY, POST_DEPLOY or FAILED_DEPLOY
+	InstanceSize string
+}
+
+// DOWorker represents a DigitalOcean worker component
+type DOWorker struct {
+	Name string `json:"name"`
+	DOSource
+	SourceDir        string     `json:"source_dir,omitempty"`
+	EnvironmentSlug  string     `json:"environment_slug,omitempty"`
+	BuildCommand     string     `json:"build_command,omitempty"`
+	RunCommand       string     `json:"run_command,omitempty"`
+	InstanceCount    int        `json:"instance_count,omitempty"`
+	InstanceSizeSlug string     `json:"instance_size_slug,omitempty"`
+	Envs             []DOEnvVar `json:"envs,omitempty"`
+}
+
+// DOJob represents a DigitalOcean job component, run around deployments
+type DOJob struct {
+	Name string `json:"name"`
+	Kind string `json:"kind,omitempty"` // PRE_DEPLOY, POST_DEPLOY, FAILED_DEPLOY
+	DOSource
+	SourceDir        string     `json:"source_dir,omitempty"`
+	EnvironmentSlug  string     `json:"environment_slug,omitempty"`
+	BuildCommand     string     `json:"build_command,omitempty"`
+	RunCommand       string     `json:"run_command,omitempty"`
+	InstanceCount    int        `json:"instance_count,omitempty"`
+	InstanceSizeSlug string     `json:"instance_size_slug,omitempty"`
+	Envs             []DOEnvVar `json:"envs,omitempty"`
+}
+
+// DOIngress routes requests to components by path
+type DOIngress struct {
+	Rules []DOIngressRule `json:"rules,omitempty"`
+}
+
+// DOIngressRule sends requests matching a path prefix to a component
+type DOIngressRule struct {
+	Match     DOIngressMatch     `json:"match"`
+	Component DOIngressComponent `json:"component"`
+}
+
+// DOIngressMatch is the request match of an ingress rule
+type DOIngressMatch struct {
+	Path struct {
+		Prefix string `json:"prefix"`
+	} `json:"path"`
+}
+
+// DOIngressComponent is the target of an ingress rule
+type DOIngressComponent struct {
+	Name               string `json:"name"`
+	PreservePathPrefix bool   `json:"preserve_path_prefix,omitempty"`
+}
+
+// DODatabase represents a database component of an app. Without a cluster
+// name it is a dev database created with the app.
+type DODatabase struct {
+	Name        string `json:"name"`
+	Engine      string `json:"engine,omitempty"` // PG, MYSQL
+	Version     string `json:"version,omitempty"`
+	Production  bool   `json:"production,omitempty"`
+	ClusterName string `json:"cluster_name,omitempty"`
+	DBName      string `json:"db_name,omitempty"`
+	DBUser      string `json:"db_user,omitempty"`
+}
+
+// nodeEnvironment is the buildpack used for components without a Dockerfile
+const nodeEnvironment = "node-js"
+
+// addComponents adds the deployer's extra components to a spec. They build
+// from the same repository and branch and get the same env vars. When a
+// service has a route, ingress rules send its prefix to it, keeping the
+// prefix, and everything else to the main component.
+func (d *DODeployer) addComponents(spec *DOAppSpec, source DOSource, envs []DOEnvVar) {
+	var rules []DOIngressRule
+	for _, c := range d.Components {
+		size := orDefault(c.InstanceSize, "basic-xxs")
+		switch c.Kind {
+		case "service":
+			spec.Services = append(spec.Services, DOService{
+				Name:             c.Name,
+				DOSource:         source,
+				SourceDir:        c.SourceDir,
+				EnvironmentSlug:  nodeEnvironment,
+				BuildCommand:     c.BuildCommand,
+				RunCommand:       c.RunCommand,
+				HTTPPort:         orDefaultInt(c.HTTPPort, 8080),
+				InstanceCount:    1,
+				InstanceSizeSlug: size,
+				Envs:             envs,
+			})
+			if c.Route != "" {
+				rules = append(rules, ingressRule(c.Route, c.Name, true))
+			}
+		case "worker":
+			spec.Workers = append(spec.Workers, DOWorker{
+				Name:             c.Name,
+				DOSource:         source,
+				SourceDir:        c.SourceDir,
+				EnvironmentSlug:  nodeEnvironment,
+				BuildCommand:     c.BuildCommand,
+				RunCommand:       c.RunCommand,
+				InstanceCount:    1,
+				InstanceSizeSlug: size,
+				Envs:             envs,
+			})
+		case "job":
+			spec.Jobs = append(spec.Jobs, DOJob{
+				Name:             c.Name,
+				Kind:             orDefault(c.JobKind, "PRE_DEPLOY"),
+				DOSource:         source,
+				SourceDir:        c.SourceDir,
+				EnvironmentSlug:  nodeEnvironment,
+				BuildCommand:     c.BuildCommand,
+				RunCommand:       c.RunCommand,
+				InstanceCount:    1,
+				InstanceSizeSlug: size,
+				Envs:             envs,
+			})
+		}
+	}
+
+	if len(rules) > 0 {
+		spec.Ingress = &DOIngress{Rules: append(rules, ingressRule("/", d.mainComponent(), false))}
+	}
+}
+
+// bindDatabase replaces the database env var with a binding to the attached
+// database's connection string. It returns the env vars without the
+// variable, for static sites, and with the binding, for components that run.
+func (d *DODeployer) bindDatabase(envs []DOEnvVar) (static, runtime []DOEnvVar) {
+	key := orDefault(d.DatabaseEnv, "DATABASE_URL")
+	for _, e := range envs {
+		if e.Key != key {
+			static = append(static, e)
+		}
+	}
+
+	runtime = append(runtime, static...)
+	runtime = append(runtime, DOEnvVar{
+		Key:   key,
+		Value: "${" + d.Database.Name + ".DATABASE_URL}",
+		Type:  "GENERAL",
+		Scope: "RUN_AND_BUILD_TIME",
+	})
+	sort.Slice(runtime, func(i, j int) bool { return runtime[i].Key < runtime[j].Key })
+	return static, runtime
+}
+
+func ingressRule(prefix, component string, preservePrefix bool) DOIngressRule {
+	rule := DOIngressRule{Component: DOIngressComponent{Name: component, PreservePathPrefix: preservePrefix}}
+	rule.Match.Path.Prefix = prefix
+	return rule
+}
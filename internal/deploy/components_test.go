@@ -0,0 +1,116 @@
+package deploy
+
+import "testing"
+
+func TestDOBuildSpecComponents(t *testing.T) {
+	deployer := &DODeployer{
+		AppName: "my-app", RepoURL: "github.com/user/repo", Branch: "main",
+		Components: []DOComponent{
+			{Name: "api", Kind: "service", SourceDir: "server", RunCommand: "npm start", Route: "/api"},
+			{Name: "queue", Kind: "worker", SourceDir: "server", RunCommand: "npm run worker"},
+			{Name: "migrate", Kind: "job", SourceDir: "server", RunCommand: "npm run migrate"},
+		},
+	}
+
+	spec := deployer.buildSpec(true, map[string]string{"DATABASE_URL": "postgres://db"})
+
+	if len(spec.StaticSites) != 1 || len(spec.Services) != 1 || len(spec.Workers) != 1 || len(spec.Jobs) != 1 {
+		t.Fatalf("Expected a static site, service, worker and job, got %v", spec.Components())
+	}
+
+	api := spec.Services[0]
+	if api.SourceDir != "server" || api.EnvironmentSlug != nodeEnvironment || api.HTTPPort != 8080 || api.GitHub.Repo != "user/repo" {
+		t.Errorf("Unexpected service: %+v", api)
+	}
+	if len(api.Envs) != 1 || api.Envs[0].Key != "DATABASE_URL" {
+		t.Errorf("Expected the service to get the env vars, got %+v", api.Envs)
+	}
+	if spec.Jobs[0].Kind != "PRE_DEPLOY" {
+		t.Errorf("Expected jobs to run before deploys by default, got %q", spec.Jobs[0].Kind)
+	}
+
+	if spec.Ingress == nil || len(spec.Ingress.Rules) != 2 {
+		t.Fatalf("Expected two ingress rules, got %+v", spec.Ingress)
+	}
+	apiRule, mainRule := spec.Ingress.Rules[0], spec.Ingress.Rules[1]
+	if apiRule.Match.Path.Prefix != "/api" || apiRule.Component.Name != "api" || !apiRule.Component.PreservePathPrefix {
+		t.Errorf("Unexpected API rule: %+v", apiRule)
+	}
+	if mainRule.Match.Path.Prefix != "/" || mainRule.Component.Name != "my-app" {
+		t.Errorf("Unexpected catch-all rule: %+v", mainRule)
+	}
+
+	if spec := (&DODeployer{AppName: "my-app"}).buildSpec(true, nil); spec.Ingress != nil {
+		t.Errorf("Expected no ingress without routed services, got %+v", spec.Ingress)
+	}
+}
+
+func TestOverlaySpecComponents(t *testing.T) {
+	base := loadTestSpec(t, `static_sites:
+  - name: web
+services:
+  - name: api
+    instance_size_slug: basic-s
+`)
+	deployer := &DODeployer{
+		AppName: "my-app", RepoURL: "github.com/user/repo", Branch: "main",
+		Components: []DOComponent{{Name: "api", Kind: "service", SourceDir: "server", RunCommand: "npm start", Route: "/api"}},
+	}
+
+	out, err := overlaySpec(base, deployer.buildSpec(true, nil))
+	if err != nil {
+		t.Fatalf("overlaySpec() error: %v", err)
+	}
+
+	sites := componentList(out["static_sites"])
+	services := componentList(out["services"])
+	if len(sites) != 1 || sites[0]["name"] != "web" {
+		t.Errorf("Expected the static site to be updated in place, got %v", sites)
+	}
+	if len(services) != 1 || services[0]["run_command"] != "npm start" || services[0]["instance_size_slug"] != "basic-s" {
+		t.Errorf("Expected the api service to be updated by name, got %v", services)
+	}
+
+	// The file's only service is the generated api, so an SSR main
+	// component is added beside it rather than replacing it
+	out, err = overlaySpec(loadTestSpec(t, "services:\n  - name: api\n"), deployer.buildSpec(false, nil))
+	if err != nil {
+		t.Fatalf("overlaySpec() error: %v", err)
+	}
+	if services := componentList(out["services"]); len(services) != 2 {
+		t.Errorf("Expected the main service added beside api, got %v", services)
+	}
+}
+
+func TestDOBuildSpecDatabase(t *testing.T) {
+	deployer := &DODeployer{
+		AppName: "my-app", RepoURL: "github.com/user/repo", Branch: "main",
+		Database:   &DODatabase{Name: "db", Engine: "PG"},
+		Components: []DOComponent{{Name: "migrate", Kind: "job", RunCommand: "npx prisma migrate deploy"}},
+	}
+	envVars := map[string]string{"DATABASE_URL": "postgres://localhost/dev", "VITE_TITLE": "Shop"}
+
+	spec := deployer.buildSpec(true, envVars)
+	if len(spec.Databases) != 1 || spec.Databases[0].Name != "db" {
+		t.Fatalf("Expected the database in the spec, got %+v", spec.Databases)
+	}
+	if envs := spec.StaticSites[0].Envs; len(envs) != 1 || envs[0].Key != "VITE_TITLE" {
+		t.Errorf("Expected the static site without the local database URL, got %+v", envs)
+	}
+	jobEnvs := spec.Jobs[0].Envs
+	if len(jobEnvs) != 2 || jobEnvs[0].Key != "DATABASE_URL" || jobEnvs[0].Value != "${db.DATABASE_URL}" {
+		t.Errorf("Expected the job bound to the database, got %+v", jobEnvs)
+	}
+
+	deployer.DatabaseEnv = "PRIMARY_DB"
+	spec = deployer.buildSpec(false, envVars)
+	found := false
+	for _, e := range spec.Services[0].Envs {
+		if e.Key == "PRIMARY_DB" {
+			found = e.Value == "${db.DATABASE_URL}"
+		}
+	}
+	if !found {
+		t.Errorf("Expected the service bound through PRIMARY_DB, got %+v", spec.Services[0].Envs)
+	}
+}
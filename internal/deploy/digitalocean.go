@@ -6,23 +6,77 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"sort"
 	"strings"
 	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"mvpbridge/internal/detect"
+	"mvpbridge/internal/git"
 )
 
 const doAPIBase = "https://api.digitalocean.com/v2"
 
+// doAppsPerPage is the page size used when listing apps (the API maximum)
+const doAppsPerPage = 200
+
+// pollInterval is the wait between status checks while waiting on a deployment
+var pollInterval = 10 * time.Second
+
+// ErrAppNotFound is returned when no app matches the deployer's app name
+var ErrAppNotFound = errors.New("app not found")
+
+// ErrAppDeleted is returned when an app addressed by its recorded ID no
+// longer exists, typically because it was deleted outside mvpbridge
+var ErrAppDeleted = errors.New("app no longer exists")
+
+// APIError is returned when a platform API responds with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Body)
+}
+
 // DODeployer handles deployments to DigitalOcean App Platform
 type DODeployer struct {
 	Token   string
 	AppName string
+	AppID   string // when set, the app is addressed by ID instead of by name
 	RepoURL string
 	Branch  string
+
+	// Optional settings; zero values fall back to the defaults below
+	Region           string
+	Component        string        // name of the main static site or service; defaults to AppName
+	BuildCommand     string        // static sites
+	OutputDir        string        // static sites
+	CatchallDocument string        // static SPAs: served for unknown paths
+	ErrorDocument    string        // static multi-page sites: served with a 404
+	InstanceSize     string        // services
+	InstanceCount    int           // services
+	HTTPPort         int           // services
+	KeepEnv          []string      // remote env vars kept when not set locally
+	Domains          []DODomain    // custom domains; when empty an update keeps the app's
+	Components       []DOComponent // extra services, workers and jobs
+	Database         *DODatabase   // attached database
+	DatabaseEnv      string        // env var bound to the database's connection string
+
+	// BaseSpec is the project's .do/app.yaml; when set, the generated spec's
+	// managed fields are overlaid onto it instead of replacing it
+	BaseSpec map[string]interface{}
+
 	client  *http.Client
+	baseURL string
+	ctx     context.Context
 }
 
 // DOAppSpec represents the DigitalOcean App Platform app specification
@@ -31,14 +85,29 @@ type DOAppSpec struct {
 	Region      string         `json:"region,omitempty"`
 	Services    []DOService    `json:"services,omitempty"`
 	StaticSites []DOStaticSite `json:"static_sites,omitempty"`
+	Workers     []DOWorker     `json:"workers,omitempty"`
+	Jobs        []DOJob        `json:"jobs,omitempty"`
+	Ingress     *DOIngress     `json:"ingress,omitempty"`
+	Databases   []DODatabase   `json:"databases,omitempty"`
+	Domains     []DODomain     `json:"domains,omitempty"`
+}
+
+// DODomain represents a custom domain attached to an app
+type DODomain struct {
+	Domain string `json:"domain"`
+	Type   string `json:"type,omitempty"` // DEFAULT, PRIMARY, ALIAS
+	Zone   string `json:"zone,omitempty"`
 }
 
 // DOService represents a DigitalOcean service component (for SSR apps)
 type DOService struct {
-	Name             string     `json:"name"`
-	GitHub           *DOGitHub  `json:"github,omitempty"`
+	Name string `json:"name"`
+	DOSource
 	Dockerfile       string     `json:"dockerfile_path,omitempty"`
 	SourceDir        string     `json:"source_dir,omitempty"`
+	EnvironmentSlug  string     `json:"environment_slug,omitempty"`
+	BuildCommand     string     `json:"build_command,omitempty"`
+	RunCommand       string     `json:"run_command,omitempty"`
 	HTTPPort         int        `json:"http_port,omitempty"`
 	InstanceCount    int        `json:"instance_count,omitempty"`
 	InstanceSizeSlug string     `json:"instance_size_slug,omitempty"`
@@ -47,94 +116,257 @@ type DOService struct {
 
 // DOStaticSite represents a DigitalOcean static site component
 type DOStaticSite struct {
-	Name         string     `json:"name"`
-	GitHub       *DOGitHub  `json:"github,omitempty"`
-	BuildCommand string     `json:"build_command,omitempty"`
-	OutputDir    string     `json:"output_dir,omitempty"`
-	Envs         []DOEnvVar `json:"envs,omitempty"`
+	Name string `json:"name"`
+	DOSource
+	BuildCommand     string     `json:"build_command,omitempty"`
+	OutputDir        string     `json:"output_dir,omitempty"`
+	IndexDocument    string     `json:"index_document,omitempty"`
+	CatchallDocument string     `json:"catchall_document,omitempty"`
+	ErrorDocument    string     `json:"error_document,omitempty"`
+	Envs             []DOEnvVar `json:"envs,omitempty"`
 }
 
-// DOGitHub represents GitHub repository configuration for DigitalOcean
+// DOSource is where a component gets its code; one field is set
+type DOSource struct {
+	GitHub *DOGitHub `json:"github,omitempty"`
+	GitLab *DOGitHub `json:"gitlab,omitempty"`
+	Git    *DOGit    `json:"git,omitempty"`
+}
+
+// DOGitHub represents GitHub or GitLab repository configuration for
+// DigitalOcean
 type DOGitHub struct {
 	Repo         string `json:"repo"`
 	Branch       string `json:"branch"`
 	DeployOnPush bool   `json:"deploy_on_push"`
 }
 
+// DOGit represents a repository DigitalOcean clones over HTTPS. Pushes to it
+// do not trigger deployments.
+type DOGit struct {
+	RepoCloneURL string `json:"repo_clone_url"`
+	Branch       string `json:"branch"`
+}
+
 // DOEnvVar represents an environment variable in DigitalOcean App Platform
 type DOEnvVar struct {
 	Key   string `json:"key"`
 	Value string `json:"value,omitempty"`
-	Type  string `json:"type,omitempty"` // GENERAL or SECRET
+	Type  string `json:"type,omitempty"`  // GENERAL or SECRET
+	Scope string `json:"scope,omitempty"` // RUN_TIME, BUILD_TIME or RUN_AND_BUILD_TIME
 }
 
 // DOAppResponse represents the API response when creating or updating an app
 type DOAppResponse struct {
-	App struct {
-		ID               string `json:"id"`
-		DefaultIngress   string `json:"default_ingress"`
-		LiveURL          string `json:"live_url"`
-		ActiveDeployment struct {
-			ID    string `json:"id"`
-			Phase string `json:"phase"`
-		} `json:"active_deployment"`
-	} `json:"app"`
-}
-
-// NewDODeployer creates a new DigitalOcean deployer instance
+	App DOApp `json:"app"`
+}
+
+// DOApp represents a DigitalOcean App Platform app
+type DOApp struct {
+	ID                   string        `json:"id"`
+	DefaultIngress       string        `json:"default_ingress"`
+	LiveURL              string        `json:"live_url"`
+	ActiveDeployment     DODeployment  `json:"active_deployment"`
+	InProgressDeployment *DODeployment `json:"in_progress_deployment,omitempty"`
+	PendingDeployment    *DODeployment `json:"pending_deployment,omitempty"`
+	Spec                 DOAppSpec     `json:"spec"`
+	Domains              []DOAppDomain `json:"domains,omitempty"`
+}
+
+// DODeployment represents a single deployment of an app
+type DODeployment struct {
+	ID          string                  `json:"id"`
+	Phase       string                  `json:"phase"`
+	Cause       string                  `json:"cause,omitempty"`
+	CreatedAt   time.Time               `json:"created_at"`
+	UpdatedAt   time.Time               `json:"updated_at"`
+	Services    []DODeploymentComponent `json:"services,omitempty"`
+	StaticSites []DODeploymentComponent `json:"static_sites,omitempty"`
+	Workers     []DODeploymentComponent `json:"workers,omitempty"`
+	Jobs        []DODeploymentComponent `json:"jobs,omitempty"`
+}
+
+// DODeploymentComponent records the source a component was built from
+type DODeploymentComponent struct {
+	Name             string `json:"name"`
+	SourceCommitHash string `json:"source_commit_hash,omitempty"`
+}
+
+// CommitHash returns the commit the deployment was built from
+func (d *DODeployment) CommitHash() string {
+	for _, components := range [][]DODeploymentComponent{d.Services, d.StaticSites} {
+		for _, c := range components {
+			if c.SourceCommitHash != "" {
+				return c.SourceCommitHash
+			}
+		}
+	}
+	return ""
+}
+
+// Components returns the names of all components in the app spec
+func (s *DOAppSpec) Components() []string {
+	var names []string
+	for _, svc := range s.Services {
+		names = append(names, "service/"+svc.Name)
+	}
+	for _, site := range s.StaticSites {
+		names = append(names, "static_site/"+site.Name)
+	}
+	for _, w := range s.Workers {
+		names = append(names, "worker/"+w.Name)
+	}
+	for _, j := range s.Jobs {
+		names = append(names, "job/"+j.Name)
+	}
+	return names
+}
+
+// DOAppSummary is a short description of an app returned by the list endpoint
+type DOAppSummary struct {
+	ID        string
+	Name      string
+	CreatedAt time.Time
+}
+
+// NewDODeployer creates a new DigitalOcean deployer instance using the
+// DIGITALOCEAN_TOKEN environment variable
 func NewDODeployer(appName, repoURL, branch string) (*DODeployer, error) {
 	token := os.Getenv("DIGITALOCEAN_TOKEN")
 	if token == "" {
 		return nil, fmt.Errorf("DIGITALOCEAN_TOKEN environment variable not set")
 	}
 
+	return NewDODeployerWithToken(token, appName, repoURL, branch), nil
+}
+
+// NewDODeployerWithToken creates a deployer with an already resolved token
+func NewDODeployerWithToken(token, appName, repoURL, branch string) *DODeployer {
 	return &DODeployer{
 		Token:   token,
 		AppName: appName,
 		RepoURL: repoURL,
 		Branch:  branch,
 		client:  &http.Client{Timeout: 30 * time.Second},
-	}, nil
+		baseURL: doAPIBase,
+	}
+}
+
+// SetContext makes every subsequent API call and wait honor ctx, so an
+// interrupt or --timeout cancels in-flight requests
+func (d *DODeployer) SetContext(ctx context.Context) {
+	d.ctx = ctx
+}
+
+// SetStaticRouting chooses how a static site answers unknown paths. Vite
+// builds are SPAs that route on the client, so every path serves
+// index.html; Next.js static exports have a page per route and a 404.html.
+func (d *DODeployer) SetStaticRouting(fw detect.Framework) {
+	d.CatchallDocument, d.ErrorDocument = "", ""
+	switch fw {
+	case detect.Vite:
+		d.CatchallDocument = "index.html"
+	case detect.NextJS:
+		d.ErrorDocument = "404.html"
+	}
 }
 
 // Deploy creates or updates a DO App Platform app
 func (d *DODeployer) Deploy(isStatic bool, envVars map[string]string) (*DOAppResponse, error) {
 	// Check if app already exists
 	existing, err := d.getApp()
-	if err != nil && !strings.Contains(err.Error(), "not found") {
+	if err != nil && !errors.Is(err, ErrAppNotFound) {
 		return nil, fmt.Errorf("checking existing app: %w", err)
 	}
 
-	// Build app spec
-	spec := d.buildSpec(isStatic, envVars)
-
 	if existing != nil {
-		// Update existing app
-		return d.updateApp(existing.App.ID, spec)
+		envVars = keepEnv(envVars, existing.App.Spec.envValues(), d.KeepEnv)
+		spec := d.buildSpec(isStatic, envVars)
+		// Domains are managed in the dashboard unless the config or spec
+		// file lists them; an update must not drop them
+		if len(spec.Domains) == 0 {
+			spec.Domains = existing.App.Spec.Domains
+		}
+		body, err := d.appSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		return d.updateApp(existing.App.ID, body)
+	}
+
+	// Build app spec
+	body, err := d.appSpec(d.buildSpec(isStatic, envVars))
+	if err != nil {
+		return nil, err
 	}
 
 	// Create new app
-	return d.createApp(spec)
+	return d.createApp(body)
 }
 
-func (d *DODeployer) buildSpec(isStatic bool, envVars map[string]string) *DOAppSpec {
-	// Parse GitHub repo from URL
-	// Expected format: github.com/owner/repo or https://github.com/owner/repo
-	repoPath := strings.TrimPrefix(d.RepoURL, "https://")
-	repoPath = strings.TrimPrefix(repoPath, "github.com/")
-	repoPath = strings.TrimSuffix(repoPath, ".git")
+// appSpec returns the spec sent to the API: the generated spec, or BaseSpec
+// with the managed fields overlaid
+func (d *DODeployer) appSpec(spec *DOAppSpec) (interface{}, error) {
+	if d.BaseSpec == nil {
+		return spec, nil
+	}
+	return overlaySpec(d.BaseSpec, spec)
+}
+
+// ExportSpec returns the spec a deploy would send as YAML, with the env var
+// names but none of their local values, so it can be committed as
+// .do/app.yaml. Values already in the spec file are kept.
+func (d *DODeployer) ExportSpec(isStatic bool, envVars map[string]string) ([]byte, error) {
+	keys := make(map[string]string, len(envVars))
+	for k := range envVars {
+		keys[k] = ""
+	}
+
+	out, err := overlaySpec(d.BaseSpec, d.buildSpec(isStatic, keys))
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(out)
+}
+
+// RemoteSpec returns the full spec of a deployed app as YAML, for use as
+// .do/app.yaml. Every field is kept, including alerts and ingress; secret env
+// values stay encrypted as the API returns them.
+func (d *DODeployer) RemoteSpec(appID string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "GET", fmt.Sprintf("%s/apps/%s", d.apiBase(), appID), nil)
+	if err != nil {
+		return nil, err
+	}
 
-	github := &DOGitHub{
-		Repo:         repoPath,
-		Branch:       d.Branch,
-		DeployOnPush: true,
+	body, err := d.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		App struct {
+			Spec map[string]interface{} `json:"spec"`
+		} `json:"app"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	if result.App.Spec == nil {
+		return nil, fmt.Errorf("app %s has no spec", appID)
 	}
+	return yaml.Marshal(result.App.Spec)
+}
+
+func (d *DODeployer) buildSpec(isStatic bool, envVars map[string]string) *DOAppSpec {
+	source := d.source()
 
 	// Convert env vars
 	var envs []DOEnvVar
 	for k, v := range envVars {
 		envType := "GENERAL"
-		if strings.Contains(strings.ToLower(k), "secret") ||
+		// Kept secrets come back from the API encrypted as EV[...]
+		if strings.HasPrefix(v, "EV[") ||
+			strings.Contains(strings.ToLower(k), "secret") ||
 			strings.Contains(strings.ToLower(k), "key") ||
 			strings.Contains(strings.ToLower(k), "password") ||
 			strings.Contains(strings.ToLower(k), "token") {
@@ -142,44 +374,143 @@ func (d *DODeployer) buildSpec(isStatic bool, envVars map[string]string) *DOAppS
 		}
 		envs = append(envs, DOEnvVar{Key: k, Value: v, Type: envType})
 	}
+	sort.Slice(envs, func(i, j int) bool { return envs[i].Key < envs[j].Key })
 
 	spec := &DOAppSpec{
-		Name:   d.AppName,
-		Region: "nyc", // Default to NYC, could be configurable
+		Name:    d.AppName,
+		Region:  orDefault(d.Region, "nyc"),
+		Domains: d.Domains,
+	}
+
+	// Components that run code get the database's connection string
+	runtimeEnvs := envs
+	if d.Database != nil {
+		spec.Databases = []DODatabase{*d.Database}
+		envs, runtimeEnvs = d.bindDatabase(envs)
 	}
 
 	if isStatic {
 		spec.StaticSites = []DOStaticSite{{
-			Name:         d.AppName,
-			GitHub:       github,
-			BuildCommand: "npm run build",
-			OutputDir:    "dist",
-			Envs:         envs,
+			Name:             d.mainComponent(),
+			DOSource:         source,
+			BuildCommand:     orDefault(d.BuildCommand, "npm run build"),
+			OutputDir:        orDefault(d.OutputDir, "dist"),
+			IndexDocument:    "index.html",
+			CatchallDocument: d.CatchallDocument,
+			ErrorDocument:    d.ErrorDocument,
+			Envs:             envs,
 		}}
 	} else {
 		spec.Services = []DOService{{
-			Name:             d.AppName,
-			GitHub:           github,
+			Name:             d.mainComponent(),
+			DOSource:         source,
 			Dockerfile:       "Dockerfile",
 			SourceDir:        "/",
-			HTTPPort:         3000,
-			InstanceCount:    1,
-			InstanceSizeSlug: "basic-xxs",
-			Envs:             envs,
+			HTTPPort:         orDefaultInt(d.HTTPPort, 3000),
+			InstanceCount:    orDefaultInt(d.InstanceCount, 1),
+			InstanceSizeSlug: orDefault(d.InstanceSize, "basic-xxs"),
+			Envs:             runtimeEnvs,
 		}}
 	}
+	d.addComponents(spec, source, runtimeEnvs)
 
 	return spec
 }
 
-func (d *DODeployer) createApp(spec *DOAppSpec) (*DOAppResponse, error) {
+// mainComponent returns the name of the main static site or service
+func (d *DODeployer) mainComponent() string {
+	return orDefault(d.Component, d.AppName)
+}
+
+// source maps the repository to the App Platform source that can build it.
+// github.com and gitlab.com repos use the integrations, which deploy on push;
+// any other server, such as GitHub Enterprise or Bitbucket, is cloned over
+// HTTPS.
+func (d *DODeployer) source() DOSource {
+	remote, err := git.ParseRemote(d.RepoURL)
+	if err != nil {
+		return DOSource{Git: &DOGit{RepoCloneURL: d.RepoURL, Branch: d.Branch}}
+	}
+
+	integration := &DOGitHub{Repo: remote.Path(), Branch: d.Branch, DeployOnPush: true}
+	switch {
+	case remote.Provider == git.GitHub && remote.IsHosted():
+		return DOSource{GitHub: integration}
+	case remote.Provider == git.GitLab && remote.IsHosted():
+		return DOSource{GitLab: integration}
+	}
+	return DOSource{Git: &DOGit{RepoCloneURL: remote.HTTPSURL() + ".git", Branch: d.Branch}}
+}
+
+// envValues returns the env vars of every component. Secret values come back
+// encrypted, which the API accepts unchanged on update.
+func (s *DOAppSpec) envValues() map[string]string {
+	values := make(map[string]string)
+	for _, svc := range s.Services {
+		for _, e := range svc.Envs {
+			values[e.Key] = e.Value
+		}
+	}
+	for _, site := range s.StaticSites {
+		for _, e := range site.Envs {
+			values[e.Key] = e.Value
+		}
+	}
+	return values
+}
+
+// EnvKeys returns the sorted names of the env vars set on any component
+func (s *DOAppSpec) EnvKeys() []string {
+	values := s.envValues()
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// keepEnv adds the remote value of each kept key that is not set locally
+func keepEnv(local, remote map[string]string, keys []string) map[string]string {
+	merged := make(map[string]string, len(local)+len(keys))
+	for k, v := range local {
+		merged[k] = v
+	}
+	for _, k := range keys {
+		if _, ok := merged[k]; ok {
+			continue
+		}
+		if v, ok := remote[k]; ok {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// orDefault returns s, or def when s is empty
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// orDefaultInt returns n, or def when n is zero
+func orDefaultInt(n, def int) int {
+	if n == 0 {
+		return def
+	}
+	return n
+}
+
+func (d *DODeployer) createApp(spec interface{}) (*DOAppResponse, error) {
 	body := map[string]interface{}{"spec": spec}
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(context.Background(), "POST", doAPIBase+"/apps", bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "POST", d.apiBase()+"/apps", bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, err
 	}
@@ -187,14 +518,14 @@ func (d *DODeployer) createApp(spec *DOAppSpec) (*DOAppResponse, error) {
 	return d.doRequest(req)
 }
 
-func (d *DODeployer) updateApp(appID string, spec *DOAppSpec) (*DOAppResponse, error) {
+func (d *DODeployer) updateApp(appID string, spec interface{}) (*DOAppResponse, error) {
 	body := map[string]interface{}{"spec": spec}
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(context.Background(), "PUT", fmt.Sprintf("%s/apps/%s", doAPIBase, appID), bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "PUT", fmt.Sprintf("%s/apps/%s", d.apiBase(), appID), bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, err
 	}
@@ -203,50 +534,107 @@ func (d *DODeployer) updateApp(appID string, spec *DOAppSpec) (*DOAppResponse, e
 }
 
 func (d *DODeployer) getApp() (*DOAppResponse, error) {
+	if d.AppID != "" {
+		return d.getRecordedApp()
+	}
+
 	// List all apps and find by name
-	req, err := http.NewRequestWithContext(context.Background(), "GET", doAPIBase+"/apps", nil)
+	apps, err := d.ListApps()
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+d.Token)
-	req.Header.Set("Content-Type", "application/json")
+	for _, app := range apps {
+		if app.Name == d.AppName {
+			// Fetch full app details
+			return d.GetAppByID(app.ID)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrAppNotFound, d.AppName)
+}
 
-	resp, err := d.client.Do(req)
+// getRecordedApp fetches the app by its recorded ID. An app renamed outside
+// mvpbridge keeps its new name, so AppName is updated to match.
+func (d *DODeployer) getRecordedApp() (*DOAppResponse, error) {
+	app, err := d.GetAppByID(d.AppID)
 	if err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("%w: %s (%s)", ErrAppDeleted, d.AppName, d.AppID)
+		}
 		return nil, err
 	}
-	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error: %s", resp.Status)
+	if name := app.App.Spec.Name; name != "" {
+		d.AppName = name
 	}
+	return app, nil
+}
 
-	var result struct {
-		Apps []struct {
-			ID   string `json:"id"`
-			Spec struct {
-				Name string `json:"name"`
-			} `json:"spec"`
-		} `json:"apps"`
-	}
+// FindApp looks up the deployer's app by recorded ID or by name
+func (d *DODeployer) FindApp() (*DOAppResponse, error) {
+	return d.getApp()
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
+// ListApps returns all apps visible to the token, following pagination
+func (d *DODeployer) ListApps() ([]DOAppSummary, error) {
+	var apps []DOAppSummary
 
-	for _, app := range result.Apps {
-		if app.Spec.Name == d.AppName {
-			// Fetch full app details
-			return d.getAppByID(app.ID)
+	for page := 1; ; page++ {
+		endpoint := fmt.Sprintf("%s/apps?page=%d&per_page=%d", d.apiBase(), page, doAppsPerPage)
+		req, err := http.NewRequestWithContext(orBackground(d.ctx), "GET", endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := d.send(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Apps []struct {
+				ID        string    `json:"id"`
+				CreatedAt time.Time `json:"created_at"`
+				Spec      struct {
+					Name string `json:"name"`
+				} `json:"spec"`
+			} `json:"apps"`
+			Links struct {
+				Pages struct {
+					Next string `json:"next"`
+				} `json:"pages"`
+			} `json:"links"`
+		}
+
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("parsing response: %w", err)
+		}
+
+		for _, app := range result.Apps {
+			apps = append(apps, DOAppSummary{ID: app.ID, Name: app.Spec.Name, CreatedAt: app.CreatedAt})
+		}
+
+		if result.Links.Pages.Next == "" || len(result.Apps) == 0 {
+			return apps, nil
 		}
 	}
+}
+
+// DeleteApp permanently deletes an app and all of its components
+func (d *DODeployer) DeleteApp(appID string) error {
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "DELETE", fmt.Sprintf("%s/apps/%s", d.apiBase(), appID), nil)
+	if err != nil {
+		return err
+	}
 
-	return nil, fmt.Errorf("app not found: %s", d.AppName)
+	_, err = d.send(req)
+	return err
 }
 
-func (d *DODeployer) getAppByID(id string) (*DOAppResponse, error) {
-	req, err := http.NewRequestWithContext(context.Background(), "GET", fmt.Sprintf("%s/apps/%s", doAPIBase, id), nil)
+// GetAppByID fetches the full details of an app
+func (d *DODeployer) GetAppByID(id string) (*DOAppResponse, error) {
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "GET", fmt.Sprintf("%s/apps/%s", d.apiBase(), id), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -255,23 +643,10 @@ func (d *DODeployer) getAppByID(id string) (*DOAppResponse, error) {
 }
 
 func (d *DODeployer) doRequest(req *http.Request) (*DOAppResponse, error) {
-	req.Header.Set("Authorization", "Bearer "+d.Token)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := d.client.Do(req)
+	body, err := d.send(req)
 	if err != nil {
 		return nil, err
 	}
-	defer func() { _ = resp.Body.Close() }()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
-	}
 
 	var result DOAppResponse
 	if err := json.Unmarshal(body, &result); err != nil {
@@ -281,12 +656,28 @@ func (d *DODeployer) doRequest(req *http.Request) (*DOAppResponse, error) {
 	return &result, nil
 }
 
+// send performs an authenticated request and returns the raw response body
+func (d *DODeployer) send(req *http.Request) ([]byte, error) {
+	return sendWithRetry(d.client, req, func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer "+d.Token)
+		r.Header.Set("Content-Type", "application/json")
+	})
+}
+
+// apiBase returns the API base URL, falling back to the public endpoint
+func (d *DODeployer) apiBase() string {
+	if d.baseURL != "" {
+		return d.baseURL
+	}
+	return doAPIBase
+}
+
 // WaitForDeployment polls until deployment completes or fails
 func (d *DODeployer) WaitForDeployment(appID string, timeout time.Duration) error {
 	deadline := time.Now().Add(timeout)
 
 	for time.Now().Before(deadline) {
-		app, err := d.getAppByID(appID)
+		app, err := d.GetAppByID(appID)
 		if err != nil {
 			return err
 		}
@@ -300,32 +691,64 @@ func (d *DODeployer) WaitForDeployment(appID string, timeout time.Duration) erro
 		}
 
 		fmt.Printf("  Deployment status: %s\n", phase)
-		time.Sleep(10 * time.Second)
+		if err := sleepContext(orBackground(d.ctx), pollInterval); err != nil {
+			return err
+		}
 	}
 
 	return fmt.Errorf("deployment timed out after %v", timeout)
 }
 
-// GetLogs fetches deployment logs (simplified)
+// GetLogs fetches the build logs of a deployment. The API answers with
+// links to the logs, which are then downloaded.
 func (d *DODeployer) GetLogs(appID, deploymentID string) (string, error) {
-	url := fmt.Sprintf("%s/apps/%s/deployments/%s/logs", doAPIBase, appID, deploymentID)
-	req, err := http.NewRequestWithContext(context.Background(), "GET", url, nil)
+	url := fmt.Sprintf("%s/apps/%s/deployments/%s/logs?type=BUILD", d.apiBase(), appID, deploymentID)
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := d.send(req)
 	if err != nil {
 		return "", err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+d.Token)
+	var links struct {
+		LiveURL      string   `json:"live_url"`
+		HistoricURLs []string `json:"historic_urls"`
+	}
+	if err := json.Unmarshal(body, &links); err != nil {
+		return "", fmt.Errorf("parsing response: %w", err)
+	}
+	logURL := links.LiveURL
+	if len(links.HistoricURLs) > 0 {
+		logURL = links.HistoricURLs[0]
+	}
+	if logURL == "" {
+		return "", nil
+	}
+	return fetchLog(orBackground(d.ctx), d.client, logURL)
+}
 
-	resp, err := d.client.Do(req)
+// fetchLog downloads a log from a pre-signed link. The link carries its own
+// authorization, so it is fetched without the API credentials.
+func fetchLog(ctx context.Context, client *http.Client, logURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", logURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	body, err := io.ReadAll(resp.Body)
+	logs, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return "", err
 	}
-
-	return string(body), nil
+	if resp.StatusCode >= 300 {
+		return "", &APIError{StatusCode: resp.StatusCode, Body: string(logs)}
+	}
+	return string(logs), nil
 }
@@ -2,11 +2,14 @@ package deploy
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+
+	"mvpbridge/internal/detect"
 )
 
 const (
@@ -283,6 +286,29 @@ func TestDORepoURLParsing(t *testing.T) {
 	}
 }
 
+func TestDOBuildSpecSource(t *testing.T) {
+	tests := []struct {
+		repoURL string
+		want    string
+	}{
+		{"https://github.com/user/repo", `{"github":{"repo":"user/repo","branch":"main","deploy_on_push":true}}`},
+		{"https://gitlab.com/group/sub/repo", `{"gitlab":{"repo":"group/sub/repo","branch":"main","deploy_on_push":true}}`},
+		{"https://github.example.com/org/repo", `{"git":{"repo_clone_url":"https://github.example.com/org/repo.git","branch":"main"}}`},
+		{"https://bitbucket.org/team/repo", `{"git":{"repo_clone_url":"https://bitbucket.org/team/repo.git","branch":"main"}}`},
+	}
+
+	for _, tt := range tests {
+		deployer := &DODeployer{AppName: "my-app", RepoURL: tt.repoURL, Branch: "main"}
+		got, err := json.Marshal(deployer.buildSpec(true, nil).StaticSites[0].DOSource)
+		if err != nil {
+			t.Fatalf("Marshal() error: %v", err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("Source for %s = %s, want %s", tt.repoURL, got, tt.want)
+		}
+	}
+}
+
 func TestDODeployWithMockServer(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -307,15 +333,7 @@ func TestDODeployWithMockServer(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockResponse := DOAppResponse{
-				App: struct {
-					ID               string `json:"id"`
-					DefaultIngress   string `json:"default_ingress"`
-					LiveURL          string `json:"live_url"`
-					ActiveDeployment struct {
-						ID    string `json:"id"`
-						Phase string `json:"phase"`
-					} `json:"active_deployment"`
-				}{
+				App: DOApp{
 					ID:             "test-app-id",
 					DefaultIngress: "test-app.ondigitalocean.app",
 					LiveURL:        "https://test-app.ondigitalocean.app",
@@ -488,3 +506,269 @@ func TestDOStaticSiteDefaults(t *testing.T) {
 		t.Errorf("Expected output dir 'dist', got '%s'", site.OutputDir)
 	}
 }
+
+func TestDOGetAppByIDParsesSpec(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"app": {"id": "abc", "spec": {
+			"name": "my-app",
+			"services": [{"name": "api"}],
+			"static_sites": [{"name": "web"}],
+			"domains": [{"domain": "example.com", "type": "PRIMARY"}]
+		}}}`))
+	}))
+	defer server.Close()
+
+	deployer := &DODeployer{Token: "test", client: server.Client(), baseURL: server.URL}
+
+	result, err := deployer.GetAppByID("abc")
+	if err != nil {
+		t.Fatalf("GetAppByID() error: %v", err)
+	}
+
+	components := result.App.Spec.Components()
+	if len(components) != 2 || components[0] != "service/api" || components[1] != "static_site/web" {
+		t.Errorf("Unexpected components: %v", components)
+	}
+	if len(result.App.Spec.Domains) != 1 || result.App.Spec.Domains[0].Type != "PRIMARY" {
+		t.Errorf("Unexpected domains: %+v", result.App.Spec.Domains)
+	}
+}
+
+func TestDODeploymentCommitHash(t *testing.T) {
+	var result DOAppResponse
+	body := `{"app": {"id": "abc", "active_deployment": {
+		"id": "dep-1",
+		"phase": "ACTIVE",
+		"cause": "commit abc123 pushed to github.com/user/repo",
+		"created_at": "2024-05-01T10:00:00Z",
+		"static_sites": [{"name": "web", "source_commit_hash": "abc123"}]
+	}}}`
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+
+	dep := result.App.ActiveDeployment
+	if dep.CommitHash() != "abc123" {
+		t.Errorf("Expected commit abc123, got %q", dep.CommitHash())
+	}
+	if dep.CreatedAt.IsZero() {
+		t.Error("Expected created_at to be parsed")
+	}
+	if result.App.InProgressDeployment != nil {
+		t.Error("Expected no in-progress deployment")
+	}
+
+	services := make([]DODeploymentComponent, 0, 2)
+	dep = DODeployment{Services: services, StaticSites: []DODeploymentComponent{{Name: "web", SourceCommitHash: "def456"}}}
+	if dep.CommitHash() != "def456" {
+		t.Errorf("Expected commit def456, got %q", dep.CommitHash())
+	}
+	if spare := services[:1]; spare[0].Name != "" {
+		t.Errorf("Expected services backing array untouched, got %+v", spare[0])
+	}
+}
+
+func TestDOFindAppPaginates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/apps" && r.URL.Query().Get("page") == "1":
+			_, _ = w.Write([]byte(`{"apps": [{"id": "a1", "spec": {"name": "other"}}], "links": {"pages": {"next": "https://api.digitalocean.com/v2/apps?page=2"}}}`))
+		case r.URL.Path == "/apps" && r.URL.Query().Get("page") == "2":
+			_, _ = w.Write([]byte(`{"apps": [{"id": "a2", "spec": {"name": "my-app"}}], "links": {}}`))
+		case r.URL.Path == "/apps/a2":
+			_, _ = w.Write([]byte(`{"app": {"id": "a2", "spec": {"name": "my-app"}}}`))
+		default:
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	deployer := &DODeployer{Token: "test", AppName: "my-app", client: server.Client(), baseURL: server.URL}
+
+	result, err := deployer.FindApp()
+	if err != nil {
+		t.Fatalf("FindApp() error: %v", err)
+	}
+	if result.App.ID != "a2" {
+		t.Errorf("Expected app on second page, got %q", result.App.ID)
+	}
+}
+
+func TestDOFindAppByRecordedID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apps/a1":
+			_, _ = w.Write([]byte(`{"app": {"id": "a1", "spec": {"name": "renamed-app"}}}`))
+		case "/apps":
+			t.Error("Expected no app listing when the ID is known")
+		default:
+			http.Error(w, `{"id": "not_found"}`, http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	deployer := &DODeployer{Token: "test", AppName: "my-app", AppID: "a1", client: server.Client(), baseURL: server.URL}
+
+	result, err := deployer.FindApp()
+	if err != nil {
+		t.Fatalf("FindApp() error: %v", err)
+	}
+	if result.App.ID != "a1" || deployer.AppName != "renamed-app" {
+		t.Errorf("Expected renamed app a1 to be adopted, got %q named %q", result.App.ID, deployer.AppName)
+	}
+
+	deployer.AppID = "gone"
+	if _, err := deployer.FindApp(); !errors.Is(err, ErrAppDeleted) {
+		t.Errorf("Expected ErrAppDeleted, got %v", err)
+	}
+}
+
+func TestDOBuildSpecSettings(t *testing.T) {
+	deployer := &DODeployer{
+		AppName:       "my-app",
+		RepoURL:       "github.com/user/repo",
+		Branch:        "main",
+		Region:        "ams",
+		BuildCommand:  "pnpm build",
+		OutputDir:     "out",
+		InstanceSize:  "professional-xs",
+		InstanceCount: 2,
+		HTTPPort:      8080,
+	}
+
+	static := deployer.buildSpec(true, map[string]string{"API_URL": "https://api", "DB_URL": "EV[1:abc]"})
+	if static.Region != "ams" {
+		t.Errorf("Expected region ams, got %s", static.Region)
+	}
+	site := static.StaticSites[0]
+	if site.BuildCommand != "pnpm build" || site.OutputDir != "out" {
+		t.Errorf("Unexpected static site settings: %+v", site)
+	}
+	for _, env := range site.Envs {
+		if env.Key == "DB_URL" && env.Type != "SECRET" {
+			t.Error("Expected encrypted value to stay a SECRET")
+		}
+	}
+
+	svc := deployer.buildSpec(false, nil).Services[0]
+	if svc.InstanceSizeSlug != "professional-xs" || svc.InstanceCount != 2 || svc.HTTPPort != 8080 {
+		t.Errorf("Unexpected service settings: %+v", svc)
+	}
+}
+
+func TestDOStaticRouting(t *testing.T) {
+	tests := []struct {
+		name         string
+		framework    detect.Framework
+		wantCatchall string
+		wantError    string
+	}{
+		{name: "Vite SPA falls back to index.html", framework: detect.Vite, wantCatchall: "index.html"},
+		{name: "Next.js export serves 404.html", framework: detect.NextJS, wantError: "404.html"},
+		{name: "Unknown framework", framework: detect.Unknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployer := &DODeployer{AppName: "my-app", RepoURL: "github.com/user/repo", Branch: "main"}
+			deployer.SetStaticRouting(tt.framework)
+
+			site := deployer.buildSpec(true, nil).StaticSites[0]
+			if site.IndexDocument != "index.html" {
+				t.Errorf("Expected index document index.html, got %q", site.IndexDocument)
+			}
+			if site.CatchallDocument != tt.wantCatchall || site.ErrorDocument != tt.wantError {
+				t.Errorf("Expected catchall %q and error %q, got %q and %q",
+					tt.wantCatchall, tt.wantError, site.CatchallDocument, site.ErrorDocument)
+			}
+		})
+	}
+}
+
+func TestKeepEnv(t *testing.T) {
+	local := map[string]string{"A": "local"}
+	remote := map[string]string{"A": "remote", "B": "remote", "C": "remote"}
+
+	got := keepEnv(local, remote, []string{"A", "B", "D"})
+
+	if got["A"] != "local" {
+		t.Errorf("Expected local value to win, got %q", got["A"])
+	}
+	if got["B"] != "remote" {
+		t.Errorf("Expected kept remote value, got %q", got["B"])
+	}
+	if _, ok := got["C"]; ok {
+		t.Error("Expected unlisted remote key to be dropped")
+	}
+	if _, ok := got["D"]; ok {
+		t.Error("Expected key missing remotely to stay unset")
+	}
+}
+
+func TestDODeployKeepsDomainsAndEnv(t *testing.T) {
+	var updated DOAppSpec
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/apps/a1":
+			_, _ = w.Write([]byte(`{"app": {"id": "a1", "spec": {
+				"name": "my-app",
+				"domains": [{"domain": "example.com", "type": "PRIMARY"}],
+				"static_sites": [{"name": "my-app", "envs": [{"key": "STRIPE_KEY", "value": "EV[1:xyz]", "type": "SECRET"}]}]
+			}}}`))
+		case r.Method == "PUT" && r.URL.Path == "/apps/a1":
+			var body struct {
+				Spec DOAppSpec `json:"spec"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			updated = body.Spec
+			_, _ = w.Write([]byte(`{"app": {"id": "a1"}}`))
+		default:
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	deployer := &DODeployer{
+		Token: "test", AppName: "my-app", AppID: "a1", RepoURL: "github.com/user/repo", Branch: "main",
+		KeepEnv: []string{"STRIPE_KEY"}, client: server.Client(), baseURL: server.URL,
+	}
+
+	if _, err := deployer.Deploy(true, map[string]string{"API_URL": "https://api"}); err != nil {
+		t.Fatalf("Deploy() error: %v", err)
+	}
+
+	if len(updated.Domains) != 1 || updated.Domains[0].Domain != "example.com" {
+		t.Errorf("Expected domains to be preserved, got %+v", updated.Domains)
+	}
+	keys := updated.EnvKeys()
+	if len(keys) != 2 || keys[0] != "API_URL" || keys[1] != "STRIPE_KEY" {
+		t.Errorf("Expected local and kept env vars, got %v", keys)
+	}
+}
+
+func TestDOGetLogs(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/build.log":
+			if r.Header.Get("Authorization") != "" {
+				t.Error("Expected the log link fetched without the API token")
+			}
+			_, _ = w.Write([]byte("npm ERR! missing script: build\n"))
+		case strings.HasSuffix(r.URL.Path, "/deployments/dep-1/logs") && r.URL.Query().Get("type") == "BUILD":
+			_, _ = w.Write([]byte(`{"historic_urls": ["` + server.URL + `/build.log"]}`))
+		default:
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	deployer := &DODeployer{Token: "test", client: server.Client(), baseURL: server.URL}
+	logs, err := deployer.GetLogs("a1", "dep-1")
+	if err != nil {
+		t.Fatalf("GetLogs() error: %v", err)
+	}
+	if logs != "npm ERR! missing script: build\n" {
+		t.Errorf("Unexpected logs: %q", logs)
+	}
+}
@@ -0,0 +1,94 @@
+package deploy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// doctlConfig is the subset of doctl's config.yaml that holds API tokens
+type doctlConfig struct {
+	AccessToken  string            `yaml:"access-token"`
+	AuthContexts map[string]string `yaml:"auth-contexts"`
+	Context      string            `yaml:"context"`
+}
+
+// DoctlConfigPath returns the location of doctl's config file
+func DoctlConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "doctl", "config.yaml")
+}
+
+// DoctlToken returns the token of a doctl auth context. An empty context
+// selects doctl's current context.
+func DoctlToken(path, context string) (string, error) {
+	cfg, err := readDoctlConfig(path)
+	if err != nil {
+		return "", err
+	}
+
+	if context == "" {
+		context = cfg.Context
+	}
+
+	token := cfg.AccessToken
+	if context != "" && context != "default" {
+		token = cfg.AuthContexts[context]
+	}
+	if token == "" {
+		if context == "" {
+			context = "default"
+		}
+		return "", fmt.Errorf("doctl context %q has no token - run 'doctl auth init --context %s'", context, context)
+	}
+
+	return token, nil
+}
+
+// DoctlContexts returns the names of all doctl auth contexts and the current one
+func DoctlContexts(path string) ([]string, string, error) {
+	cfg, err := readDoctlConfig(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var names []string
+	if cfg.AccessToken != "" {
+		names = append(names, "default")
+	}
+	for name := range cfg.AuthContexts {
+		if name != "default" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	current := cfg.Context
+	if current == "" {
+		current = "default"
+	}
+	return names, current, nil
+}
+
+func readDoctlConfig(path string) (*doctlConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("doctl config not found at %s - run 'doctl auth init'", path)
+		}
+		return nil, err
+	}
+
+	var cfg doctlConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing doctl config: %w", err)
+	}
+
+	return &cfg, nil
+}
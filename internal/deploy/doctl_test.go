@@ -0,0 +1,68 @@
+package deploy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDoctlToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	doctlYAML := `access-token: default-token
+auth-contexts:
+  work: work-token
+  empty: ""
+context: work
+output: text
+`
+	if err := os.WriteFile(path, []byte(doctlYAML), 0600); err != nil {
+		t.Fatalf("Failed to write doctl config: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		context string
+		want    string
+		wantErr bool
+	}{
+		{name: "Current context", context: "", want: "work-token"},
+		{name: "Default context", context: "default", want: "default-token"},
+		{name: "Named context", context: "work", want: "work-token"},
+		{name: "Context without token", context: "empty", wantErr: true},
+		{name: "Unknown context", context: "personal", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DoctlToken(path, tt.context)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DoctlToken() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	names, current, err := DoctlContexts(path)
+	if err != nil {
+		t.Fatalf("DoctlContexts() error: %v", err)
+	}
+	if strings.Join(names, ",") != "default,empty,work" || current != "work" {
+		t.Errorf("Unexpected contexts %v (current %s)", names, current)
+	}
+}
+
+func TestDoctlTokenMissingConfig(t *testing.T) {
+	_, err := DoctlToken(filepath.Join(t.TempDir(), "missing.yaml"), "")
+	if err == nil || !strings.Contains(err.Error(), "doctl auth init") {
+		t.Errorf("Expected hint to run doctl auth init, got %v", err)
+	}
+}
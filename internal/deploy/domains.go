@@ -0,0 +1,377 @@
+package deploy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrDomainNotFound is returned when removing a domain the app does not have
+var ErrDomainNotFound = errors.New("domain not found")
+
+// DNSRecord is a record that must exist at the domain's DNS provider
+type DNSRecord struct {
+	Name  string
+	Type  string
+	Value string
+}
+
+func (r DNSRecord) String() string {
+	return fmt.Sprintf("%s %s %s", r.Name, r.Type, r.Value)
+}
+
+// DOAppDomain is the provisioning state of a custom domain on an app
+type DOAppDomain struct {
+	ID          string   `json:"id"`
+	Spec        DODomain `json:"spec"`
+	Phase       string   `json:"phase"` // PENDING, CONFIGURING, ACTIVE, ERROR
+	Validations []struct {
+		TXTName  string `json:"txt_name"`
+		TXTValue string `json:"txt_value"`
+	} `json:"validations,omitempty"`
+	CertificateExpiresAt time.Time `json:"certificate_expires_at,omitempty"`
+}
+
+// SetDomains replaces the custom domains of an app. The rest of the app's
+// spec is sent back as the API returned it.
+func (d *DODeployer) SetDomains(appID string, domains []DODomain) (*DOAppResponse, error) {
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "GET", fmt.Sprintf("%s/apps/%s", d.apiBase(), appID), nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := d.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var current struct {
+		App struct {
+			Spec map[string]interface{} `json:"spec"`
+		} `json:"app"`
+	}
+	if err := json.Unmarshal(body, &current); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	if current.App.Spec == nil {
+		return nil, fmt.Errorf("app %s has no spec", appID)
+	}
+
+	if len(domains) > 0 {
+		current.App.Spec["domains"] = domains
+	} else {
+		delete(current.App.Spec, "domains")
+	}
+	return d.updateApp(appID, current.App.Spec)
+}
+
+// AddDomain attaches a custom domain to an app, replacing an entry for the
+// same host. A new primary domain demotes the previous one to an alias.
+func (d *DODeployer) AddDomain(app *DOApp, domain DODomain) (*DOAppResponse, error) {
+	domains := []DODomain{}
+	for _, existing := range app.Spec.Domains {
+		switch {
+		case existing.Domain == domain.Domain:
+			continue
+		case domain.Type == "PRIMARY" && existing.Type == "PRIMARY":
+			existing.Type = "ALIAS"
+		}
+		domains = append(domains, existing)
+	}
+	return d.SetDomains(app.ID, append(domains, domain))
+}
+
+// RemoveDomain detaches a custom domain from an app
+func (d *DODeployer) RemoveDomain(app *DOApp, host string) (*DOAppResponse, error) {
+	domains := []DODomain{}
+	found := false
+	for _, existing := range app.Spec.Domains {
+		if existing.Domain == host {
+			found = true
+			continue
+		}
+		domains = append(domains, existing)
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s on app %s", ErrDomainNotFound, host, app.ID)
+	}
+	return d.SetDomains(app.ID, domains)
+}
+
+// WaitForDomain polls until a domain is active and its certificate issued,
+// calling progress with each state seen
+func (d *DODeployer) WaitForDomain(appID, host string, timeout time.Duration, progress func(*DOAppDomain)) (*DOAppDomain, error) {
+	deadline := time.Now().Add(timeout)
+
+	for time.Now().Before(deadline) {
+		app, err := d.GetAppByID(appID)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range app.App.Domains {
+			domain := &app.App.Domains[i]
+			if domain.Spec.Domain != host {
+				continue
+			}
+			switch domain.Phase {
+			case "ACTIVE":
+				return domain, nil
+			case "ERROR":
+				return domain, fmt.Errorf("domain %s failed to configure", host)
+			}
+			if progress != nil {
+				progress(domain)
+			}
+		}
+
+		if err := sleepContext(orBackground(d.ctx), pollInterval); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("domain %s not active after %v", host, timeout)
+}
+
+// DODNSRecords returns the records a domain needs at an external DNS
+// provider. Domains in a zone hosted by DigitalOcean need none.
+func DODNSRecords(domain DODomain, status *DOAppDomain, defaultIngress string) []DNSRecord {
+	var records []DNSRecord
+	if domain.Zone == "" {
+		target := strings.TrimPrefix(strings.TrimPrefix(defaultIngress, "https://"), "http://")
+		target = strings.TrimSuffix(target, "/")
+		recordType := "CNAME"
+		if isApex(domain.Domain) {
+			// Apex domains cannot hold a CNAME
+			recordType = "ALIAS"
+		}
+		records = append(records, DNSRecord{Name: domain.Domain, Type: recordType, Value: target})
+	}
+	if status != nil {
+		for _, v := range status.Validations {
+			records = append(records, DNSRecord{Name: v.TXTName, Type: "TXT", Value: v.TXTValue})
+		}
+	}
+	return records
+}
+
+// isApex guesses whether a host is a registrable domain rather than a
+// subdomain; a zone should be given where this guess is wrong
+func isApex(host string) bool {
+	return strings.Count(host, ".") == 1
+}
+
+// AmplifyDomainParts splits a host into the domain an Amplify domain
+// association is created for and the subdomain prefix. Without a zone the
+// domain is taken to be the last two labels of the host.
+func AmplifyDomainParts(host, zone string) (domain, prefix string) {
+	if zone == "" {
+		labels := strings.Split(host, ".")
+		if len(labels) <= 2 {
+			return host, ""
+		}
+		zone = strings.Join(labels[len(labels)-2:], ".")
+	}
+	if host == zone {
+		return zone, ""
+	}
+	return zone, strings.TrimSuffix(host, "."+zone)
+}
+
+// GetDomainAssociation returns the association for a domain
+func (d *AWSDeployer) GetDomainAssociation(appID, domain string) (*AmplifyDomainAssociation, error) {
+	endpoint := d.apiBase() + "/apps/" + appID + "/domains/" + url.PathEscape(domain)
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := d.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		DomainAssociation AmplifyDomainAssociation `json:"domainAssociation"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	return &result.DomainAssociation, nil
+}
+
+// AddDomain maps a subdomain prefix of a domain to a branch, creating the
+// domain association when the app does not have one yet. An empty prefix is
+// the domain itself.
+func (d *AWSDeployer) AddDomain(appID, domain, prefix, branch string) (*AmplifyDomainAssociation, error) {
+	existing, err := d.GetDomainAssociation(appID, domain)
+	if err != nil && !isNotFound(err) {
+		return nil, err
+	}
+
+	if err != nil {
+		return d.postDomainAssociation("/apps/"+appID+"/domains", map[string]interface{}{
+			"domainName":          domain,
+			"enableAutoSubDomain": false,
+			"subDomainSettings":   []AmplifySubDomainSetting{{Prefix: prefix, BranchName: branch}},
+		})
+	}
+
+	settings := []AmplifySubDomainSetting{}
+	for _, sub := range existing.SubDomains {
+		if sub.SubDomainSetting.Prefix == prefix {
+			if sub.SubDomainSetting.BranchName == branch {
+				return existing, nil
+			}
+			continue
+		}
+		settings = append(settings, sub.SubDomainSetting)
+	}
+	settings = append(settings, AmplifySubDomainSetting{Prefix: prefix, BranchName: branch})
+	return d.postDomainAssociation("/apps/"+appID+"/domains/"+url.PathEscape(domain), map[string]interface{}{
+		"subDomainSettings": settings,
+	})
+}
+
+// RemoveDomain removes a subdomain prefix from a domain association, deleting
+// the association when it was the last one
+func (d *AWSDeployer) RemoveDomain(appID, domain, prefix string) error {
+	existing, err := d.GetDomainAssociation(appID, domain)
+	if isNotFound(err) {
+		return fmt.Errorf("%w: %s", ErrDomainNotFound, domain)
+	}
+	if err != nil {
+		return err
+	}
+
+	settings := []AmplifySubDomainSetting{}
+	found := false
+	for _, sub := range existing.SubDomains {
+		if sub.SubDomainSetting.Prefix == prefix {
+			found = true
+			continue
+		}
+		settings = append(settings, sub.SubDomainSetting)
+	}
+	if !found {
+		return fmt.Errorf("%w: %s has no %q subdomain", ErrDomainNotFound, domain, prefix)
+	}
+
+	if len(settings) == 0 {
+		return d.DeleteDomainAssociation(appID, domain)
+	}
+	_, err = d.postDomainAssociation("/apps/"+appID+"/domains/"+url.PathEscape(domain), map[string]interface{}{
+		"subDomainSettings": settings,
+	})
+	return err
+}
+
+// DeleteDomainAssociation disconnects a domain from an app
+func (d *AWSDeployer) DeleteDomainAssociation(appID, domain string) error {
+	endpoint := d.apiBase() + "/apps/" + appID + "/domains/" + url.PathEscape(domain)
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "DELETE", endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.send(req)
+	return err
+}
+
+// postDomainAssociation creates or updates a domain association
+func (d *AWSDeployer) postDomainAssociation(path string, body map[string]interface{}) (*AmplifyDomainAssociation, error) {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "POST", d.apiBase()+path, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := d.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		DomainAssociation AmplifyDomainAssociation `json:"domainAssociation"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	return &result.DomainAssociation, nil
+}
+
+// WaitForDomain polls until a domain association is available, calling
+// progress with each state seen. The DNS records to create appear while the
+// association is pending verification.
+func (d *AWSDeployer) WaitForDomain(appID, domain string, timeout time.Duration, progress func(*AmplifyDomainAssociation)) (*AmplifyDomainAssociation, error) {
+	deadline := time.Now().Add(timeout)
+
+	for time.Now().Before(deadline) {
+		assoc, err := d.GetDomainAssociation(appID, domain)
+		if err != nil {
+			return nil, err
+		}
+
+		switch assoc.DomainStatus {
+		case "AVAILABLE":
+			return assoc, nil
+		case "FAILED":
+			return assoc, fmt.Errorf("domain %s failed: %s", domain, orDefault(assoc.StatusReason, "no reason given"))
+		}
+		if progress != nil {
+			progress(assoc)
+		}
+
+		if err := sleepContext(orBackground(d.ctx), pollInterval); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("domain %s not available after %v", domain, timeout)
+}
+
+// DNSRecords returns the certificate validation and subdomain records of an
+// association, once Amplify has generated them
+func (a *AmplifyDomainAssociation) DNSRecords() []DNSRecord {
+	var records []DNSRecord
+	if r, ok := parseAmplifyRecord(a.CertificateVerificationDNSRecord, a.DomainName); ok {
+		records = append(records, r)
+	}
+	for _, sub := range a.SubDomains {
+		if r, ok := parseAmplifyRecord(sub.DNSRecord, a.DomainName); ok {
+			records = append(records, r)
+		}
+	}
+	return records
+}
+
+// parseAmplifyRecord parses records as Amplify formats them: "name TYPE
+// value", where the name is relative to the domain or a fully qualified name
+// ending in a dot, and is left out for the domain itself
+func parseAmplifyRecord(record, domain string) (DNSRecord, bool) {
+	fields := strings.Fields(record)
+	switch len(fields) {
+	case 2:
+		return DNSRecord{Name: domain, Type: fields[0], Value: strings.TrimSuffix(fields[1], ".")}, true
+	case 3:
+		name := fields[0]
+		switch {
+		case strings.HasSuffix(name, "."):
+			name = strings.TrimSuffix(name, ".")
+		case name == "@":
+			name = domain
+		default:
+			name += "." + domain
+		}
+		return DNSRecord{Name: name, Type: fields[1], Value: strings.TrimSuffix(fields[2], ".")}, true
+	}
+	return DNSRecord{}, false
+}
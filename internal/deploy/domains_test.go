@@ -0,0 +1,244 @@
+package deploy
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDOAddDomainKeepsSpec(t *testing.T) {
+	var updated map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case httpMethodGet:
+			_, _ = w.Write([]byte(`{"app": {"id": "a1", "spec": {"name": "my-app", "workers": [{"name": "queue"}], "domains": [{"domain": "example.com", "type": "PRIMARY"}]}}}`))
+		case httpMethodPut:
+			var body struct {
+				Spec map[string]interface{} `json:"spec"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			updated = body.Spec
+			_, _ = w.Write([]byte(`{"app": {"id": "a1"}}`))
+		default:
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	deployer := &DODeployer{Token: "test", client: server.Client(), baseURL: server.URL}
+	app := &DOApp{ID: "a1", Spec: DOAppSpec{Domains: []DODomain{{Domain: "example.com", Type: "PRIMARY"}}}}
+
+	if _, err := deployer.AddDomain(app, DODomain{Domain: "www.example.com", Type: "PRIMARY"}); err != nil {
+		t.Fatalf("AddDomain() error: %v", err)
+	}
+
+	if updated["workers"] == nil {
+		t.Error("Expected the rest of the spec to be kept")
+	}
+	domains, _ := updated["domains"].([]interface{})
+	if len(domains) != 2 {
+		t.Fatalf("Expected 2 domains, got %v", updated["domains"])
+	}
+	first, _ := domains[0].(map[string]interface{})
+	second, _ := domains[1].(map[string]interface{})
+	if first["type"] != "ALIAS" || second["domain"] != "www.example.com" || second["type"] != "PRIMARY" {
+		t.Errorf("Expected the old primary demoted, got %v", domains)
+	}
+
+	if _, err := deployer.RemoveDomain(app, "missing.example.com"); !errors.Is(err, ErrDomainNotFound) {
+		t.Error("Expected error removing a domain the app does not have")
+	}
+}
+
+func TestDOWaitForDomain(t *testing.T) {
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = time.Millisecond
+
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		phase := "CONFIGURING"
+		if polls > 1 {
+			phase = "ACTIVE"
+		}
+		_, _ = w.Write([]byte(`{"app": {"id": "a1", "domains": [{"spec": {"domain": "www.example.com"}, "phase": "` + phase + `"}]}}`))
+	}))
+	defer server.Close()
+
+	deployer := &DODeployer{Token: "test", client: server.Client(), baseURL: server.URL}
+
+	var seen []string
+	domain, err := deployer.WaitForDomain("a1", "www.example.com", time.Minute, func(d *DOAppDomain) {
+		seen = append(seen, d.Phase)
+	})
+	if err != nil {
+		t.Fatalf("WaitForDomain() error: %v", err)
+	}
+	if domain.Phase != "ACTIVE" || len(seen) != 1 || seen[0] != "CONFIGURING" {
+		t.Errorf("Unexpected result %+v after progress %v", domain, seen)
+	}
+}
+
+func TestDODNSRecords(t *testing.T) {
+	ingress := "https://my-app-abc.ondigitalocean.app"
+
+	records := DODNSRecords(DODomain{Domain: "www.example.com"}, nil, ingress)
+	if len(records) != 1 || records[0].String() != "www.example.com CNAME my-app-abc.ondigitalocean.app" {
+		t.Errorf("Unexpected subdomain records: %v", records)
+	}
+
+	records = DODNSRecords(DODomain{Domain: "example.com"}, nil, ingress)
+	if len(records) != 1 || records[0].Type != "ALIAS" {
+		t.Errorf("Expected an ALIAS record for an apex domain, got %v", records)
+	}
+
+	if records := DODNSRecords(DODomain{Domain: "example.com", Zone: "example.com"}, nil, ingress); len(records) != 0 {
+		t.Errorf("Expected no records for a DigitalOcean zone, got %v", records)
+	}
+}
+
+func TestAmplifyDomainParts(t *testing.T) {
+	tests := []struct {
+		host, zone     string
+		domain, prefix string
+	}{
+		{host: "example.com", domain: "example.com"},
+		{host: "www.example.com", domain: "example.com", prefix: "www"},
+		{host: "app.shop.example.com", domain: "example.com", prefix: "app.shop"},
+		{host: "www.example.co.uk", zone: "example.co.uk", domain: "example.co.uk", prefix: "www"},
+		{host: "example.co.uk", zone: "example.co.uk", domain: "example.co.uk"},
+	}
+
+	for _, tt := range tests {
+		domain, prefix := AmplifyDomainParts(tt.host, tt.zone)
+		if domain != tt.domain || prefix != tt.prefix {
+			t.Errorf("AmplifyDomainParts(%q, %q) = %q, %q, want %q, %q", tt.host, tt.zone, domain, prefix, tt.domain, tt.prefix)
+		}
+	}
+}
+
+func TestAWSAddDomain(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		wantPath string
+		wantSubs int
+	}{
+		{name: "New association", wantPath: "/apps/app1/domains", wantSubs: 1},
+		{
+			name:     "Existing association gains a subdomain",
+			existing: `{"domainAssociation": {"domainName": "example.com", "subDomains": [{"subDomainSetting": {"prefix": "", "branchName": "main"}}]}}`,
+			wantPath: "/apps/app1/domains/example.com",
+			wantSubs: 2,
+		},
+		{
+			name:     "Mapping already present",
+			existing: `{"domainAssociation": {"domainName": "example.com", "subDomains": [{"subDomainSetting": {"prefix": "www", "branchName": "main"}}]}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var postPath string
+			var posted struct {
+				DomainName        string                    `json:"domainName"`
+				SubDomainSettings []AmplifySubDomainSetting `json:"subDomainSettings"`
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == httpMethodGet && tt.existing != "":
+					_, _ = w.Write([]byte(tt.existing))
+				case r.Method == httpMethodPost:
+					postPath = r.URL.Path
+					_ = json.NewDecoder(r.Body).Decode(&posted)
+					_, _ = w.Write([]byte(`{"domainAssociation": {"domainName": "example.com", "domainStatus": "CREATING"}}`))
+				default:
+					http.Error(w, `{"message": "not found"}`, http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			deployer := &AWSDeployer{Region: "us-east-1", client: server.Client(), endpoint: server.URL}
+			if _, err := deployer.AddDomain("app1", "example.com", "www", "main"); err != nil {
+				t.Fatalf("AddDomain() error: %v", err)
+			}
+
+			if postPath != tt.wantPath || len(posted.SubDomainSettings) != tt.wantSubs {
+				t.Errorf("Expected POST %q with %d subdomains, got %q with %+v", tt.wantPath, tt.wantSubs, postPath, posted.SubDomainSettings)
+			}
+			if tt.wantSubs > 0 && posted.SubDomainSettings[tt.wantSubs-1].Prefix != "www" {
+				t.Errorf("Expected www mapped, got %+v", posted.SubDomainSettings)
+			}
+		})
+	}
+}
+
+func TestAWSRemoveDomain(t *testing.T) {
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		_, _ = w.Write([]byte(`{"domainAssociation": {"domainName": "example.com", "subDomains": [{"subDomainSetting": {"prefix": "www", "branchName": "main"}}]}}`))
+	}))
+	defer server.Close()
+
+	deployer := &AWSDeployer{Region: "us-east-1", client: server.Client(), endpoint: server.URL}
+
+	if err := deployer.RemoveDomain("app1", "example.com", "shop"); !errors.Is(err, ErrDomainNotFound) {
+		t.Error("Expected error removing a missing subdomain")
+	}
+
+	methods = nil
+	if err := deployer.RemoveDomain("app1", "example.com", "www"); err != nil {
+		t.Fatalf("RemoveDomain() error: %v", err)
+	}
+	if strings.Join(methods, ",") != "GET,DELETE" {
+		t.Errorf("Expected the association deleted with its last subdomain, got %v", methods)
+	}
+}
+
+func TestAWSWaitForDomain(t *testing.T) {
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = time.Millisecond
+
+	statuses := []string{"CREATING", "PENDING_VERIFICATION", "AVAILABLE"}
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := statuses[min(polls, len(statuses)-1)]
+		polls++
+		_, _ = w.Write([]byte(`{"domainAssociation": {"domainName": "example.com", "domainStatus": "` + status + `",
+			"certificateVerificationDNSRecord": "_abc.example.com. CNAME _xyz.acm-validations.aws.",
+			"subDomains": [{"subDomainSetting": {"prefix": "www", "branchName": "main"}, "dnsRecord": "www CNAME d1.cloudfront.net"},
+				{"subDomainSetting": {"prefix": "", "branchName": "main"}, "dnsRecord": " CNAME d1.cloudfront.net"}]}}`))
+	}))
+	defer server.Close()
+
+	deployer := &AWSDeployer{Region: "us-east-1", client: server.Client(), endpoint: server.URL}
+
+	var seen []string
+	assoc, err := deployer.WaitForDomain("app1", "example.com", time.Minute, func(a *AmplifyDomainAssociation) {
+		seen = append(seen, a.DomainStatus)
+	})
+	if err != nil {
+		t.Fatalf("WaitForDomain() error: %v", err)
+	}
+	if len(seen) != 2 {
+		t.Errorf("Expected progress for each pending state, got %v", seen)
+	}
+
+	var got []string
+	for _, r := range assoc.DNSRecords() {
+		got = append(got, r.String())
+	}
+	want := []string{
+		"_abc.example.com CNAME _xyz.acm-validations.aws",
+		"www.example.com CNAME d1.cloudfront.net",
+		"example.com CNAME d1.cloudfront.net",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("DNSRecords() = %v, want %v", got, want)
+	}
+}
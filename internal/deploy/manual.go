@@ -0,0 +1,243 @@
+package deploy
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// AmplifyDeployment is the response to CreateDeployment: a pending job and
+// the presigned URL its zip must be uploaded to
+type AmplifyDeployment struct {
+	JobID        string `json:"jobId"`
+	ZipUploadURL string `json:"zipUploadUrl"`
+}
+
+// DeployLocal uploads a locally built static site in dir to an Amplify app
+// without a repository, creating the app and branch when needed. Apps
+// connected to a Git provider are refused, since Amplify only accepts manual
+// deployments on apps it does not build itself.
+func (d *AWSDeployer) DeployLocal(dir string) (*AmplifyAppResponse, error) {
+	archive, err := os.CreateTemp("", "mvpbridge-*.zip")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = archive.Close()
+		_ = os.Remove(archive.Name())
+	}()
+	if err := zipDir(dir, archive); err != nil {
+		return nil, fmt.Errorf("packaging %s: %w", dir, err)
+	}
+
+	existing, err := d.getApp()
+	if err != nil && !errors.Is(err, ErrAppNotFound) {
+		return nil, fmt.Errorf("checking existing app: %w", err)
+	}
+
+	var result *AmplifyAppResponse
+	if existing != nil {
+		if existing.App.Repository != "" {
+			return nil, fmt.Errorf("app %s is connected to %s; manual deployments need an app without a repository", existing.App.Name, existing.App.Repository)
+		}
+		result, err = d.updateManualApp(existing.App.AppID)
+	} else {
+		result, err = d.createManualApp()
+	}
+	if err != nil {
+		return nil, err
+	}
+	appID := result.App.AppID
+
+	if _, err := d.GetBranch(appID, d.Branch); isNotFound(err) {
+		branch := AmplifyBranch{BranchName: d.Branch, Stage: "PRODUCTION"}
+		if err := d.postBranch(appID, branch); err != nil {
+			return nil, fmt.Errorf("creating branch: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("reading branch: %w", err)
+	}
+
+	deployment, err := d.CreateDeployment(appID, d.Branch)
+	if err != nil {
+		return nil, fmt.Errorf("creating deployment: %w", err)
+	}
+	if err := d.uploadZip(deployment.ZipUploadURL, archive.Name()); err != nil {
+		return nil, fmt.Errorf("uploading build: %w", err)
+	}
+	if err := d.StartDeployment(appID, d.Branch, deployment.JobID); err != nil {
+		return nil, fmt.Errorf("starting deployment: %w", err)
+	}
+
+	result.JobID = deployment.JobID
+	return result, nil
+}
+
+// createManualApp creates a static app with no repository
+func (d *AWSDeployer) createManualApp() (*AmplifyAppResponse, error) {
+	body := map[string]interface{}{
+		"name":        d.AppName,
+		"platform":    amplifyPlatformStatic,
+		"customRules": d.reconcileRules(nil, true),
+	}
+	return d.postApp("/apps", body)
+}
+
+// updateManualApp reconciles the rewrite rules of an existing manual app
+func (d *AWSDeployer) updateManualApp(appID string) (*AmplifyAppResponse, error) {
+	settings, err := d.GetAppSettings(appID)
+	if err != nil {
+		return nil, fmt.Errorf("reading app settings: %w", err)
+	}
+
+	body := map[string]interface{}{
+		"customRules": d.reconcileRules(settings.CustomRules, true),
+	}
+	return d.postApp("/apps/"+appID, body)
+}
+
+// postApp sends an app create or update request
+func (d *AWSDeployer) postApp(path string, body map[string]interface{}) (*AmplifyAppResponse, error) {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "POST", d.apiBase()+path, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+
+	return d.doRequest(req)
+}
+
+// CreateDeployment reserves a manual deployment job for a branch
+func (d *AWSDeployer) CreateDeployment(appID, branch string) (*AmplifyDeployment, error) {
+	endpoint := d.apiBase() + "/apps/" + appID + "/branches/" + url.PathEscape(branch) + "/deployments"
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "POST", endpoint, bytes.NewBufferString("{}"))
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := d.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var deployment AmplifyDeployment
+	if err := json.Unmarshal(body, &deployment); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	if deployment.JobID == "" || deployment.ZipUploadURL == "" {
+		return nil, fmt.Errorf("deployment response has no upload URL")
+	}
+	return &deployment, nil
+}
+
+// StartDeployment starts the job reserved by CreateDeployment once its zip
+// has been uploaded
+func (d *AWSDeployer) StartDeployment(appID, branch, jobID string) error {
+	jsonBody, err := json.Marshal(map[string]string{"jobId": jobID})
+	if err != nil {
+		return err
+	}
+
+	endpoint := d.apiBase() + "/apps/" + appID + "/branches/" + url.PathEscape(branch) + "/deployments/start"
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "POST", endpoint, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return err
+	}
+
+	_, err = d.send(req)
+	return err
+}
+
+// uploadZip PUTs the archive file to a presigned S3 URL, streaming it from
+// disk. The URL carries its own signature, so the request is sent unsigned.
+// The API client's 30s limit would cut off any real upload, so the upload is
+// bounded only by the deployer's context, which --timeout sets.
+func (d *AWSDeployer) uploadZip(uploadURL, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "PUT", uploadURL, f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.GetBody = func() (io.ReadCloser, error) { return os.Open(path) }
+
+	client := &http.Client{Transport: d.client.Transport}
+	_, err = sendWithRetry(client, req, func(r *http.Request) {
+		r.Header.Set("Content-Type", "application/zip")
+	})
+	return err
+}
+
+// zipDir archives the files under dir to w with paths relative to dir, using
+// forward slashes whatever the host OS
+func zipDir(dir string, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	files := 0
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		header.Method = zip.Deflate
+
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+
+		if _, err := io.Copy(w, f); err != nil {
+			return err
+		}
+		files++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if files == 0 {
+		return fmt.Errorf("no files found - did the build run?")
+	}
+	return zw.Close()
+}
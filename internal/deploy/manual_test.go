@@ -0,0 +1,160 @@
+package deploy
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestZipDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "assets"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"index.html":       "<html></html>",
+		"assets/app.js":    "console.log(1)",
+		"assets/style.css": "body{}",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var archive bytes.Buffer
+	if err := zipDir(dir, &archive); err != nil {
+		t.Fatalf("zipDir() error: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	if err != nil {
+		t.Fatalf("Reading archive: %v", err)
+	}
+
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		if string(content) != files[f.Name] {
+			t.Errorf("Unexpected content for %s: %q", f.Name, content)
+		}
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "assets/app.js,assets/style.css,index.html" {
+		t.Errorf("Unexpected archive entries: %v", names)
+	}
+}
+
+func TestZipDirEmpty(t *testing.T) {
+	if err := zipDir(t.TempDir(), io.Discard); err == nil {
+		t.Error("Expected error for an empty output directory")
+	}
+}
+
+func TestAWSDeployLocal(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("hi"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		existing   bool
+		repository string
+		wantCreate bool
+		wantErr    string
+	}{
+		{name: "Creates app without repository", wantCreate: true},
+		{name: "Reuses manual app", existing: true},
+		{name: "Refuses Git-connected app", existing: true, repository: "https://github.com/o/r", wantErr: "connected to"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var createBody map[string]interface{}
+			var uploaded []byte
+			var started string
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == httpMethodGet && r.URL.Path == "/apps":
+					if !tt.existing {
+						_, _ = w.Write([]byte(`{"apps": []}`))
+						return
+					}
+					_, _ = w.Write([]byte(`{"apps": [{"appId": "a1", "name": "my-app"}]}`))
+				case r.Method == httpMethodGet && r.URL.Path == "/apps/a1":
+					_, _ = w.Write([]byte(`{"app": {"appId": "a1", "name": "my-app", "repository": "` + tt.repository + `", "customRules": []}}`))
+				case r.Method == httpMethodPost && (r.URL.Path == "/apps" || r.URL.Path == "/apps/a1"):
+					_ = json.NewDecoder(r.Body).Decode(&createBody)
+					_, _ = w.Write([]byte(`{"app": {"appId": "a1", "name": "my-app", "defaultDomain": "a1.amplifyapp.com"}}`))
+				case r.Method == httpMethodPost && r.URL.Path == "/apps/a1/branches":
+					_, _ = w.Write([]byte(`{"branch": {"branchName": "main"}}`))
+				case r.Method == httpMethodPost && r.URL.Path == "/apps/a1/branches/main/deployments":
+					_, _ = w.Write([]byte(`{"jobId": "3", "zipUploadUrl": "` + server.URL + `/upload?X-Amz-Signature=x"}`))
+				case r.Method == "PUT" && r.URL.Path == "/upload":
+					if r.Header.Get("Authorization") != "" {
+						t.Error("Presigned upload must not be signed")
+					}
+					if r.ContentLength <= 0 {
+						t.Errorf("Expected the upload's Content-Length set, got %d", r.ContentLength)
+					}
+					uploaded, _ = io.ReadAll(r.Body)
+				case r.Method == httpMethodPost && r.URL.Path == "/apps/a1/branches/main/deployments/start":
+					var body map[string]string
+					_ = json.NewDecoder(r.Body).Decode(&body)
+					started = body["jobId"]
+					_, _ = w.Write([]byte(`{"jobSummary": {"jobId": "3"}}`))
+				default:
+					http.Error(w, "Not found", http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			deployer := &AWSDeployer{Region: "us-east-1", AppName: "my-app", Branch: "main", client: server.Client(), endpoint: server.URL}
+
+			result, err := deployer.DeployLocal(dir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DeployLocal() error: %v", err)
+			}
+
+			if result.JobID != "3" || started != "3" {
+				t.Errorf("Expected job 3 started, got result %q, started %q", result.JobID, started)
+			}
+			if _, err := zip.NewReader(bytes.NewReader(uploaded), int64(len(uploaded))); err != nil {
+				t.Errorf("Expected a zip upload: %v", err)
+			}
+			if tt.wantCreate {
+				if _, ok := createBody["repository"]; ok {
+					t.Errorf("Expected no repository on create, got %v", createBody)
+				}
+				if createBody["platform"] != "WEB" {
+					t.Errorf("Expected WEB platform, got %v", createBody["platform"])
+				}
+			}
+			if rules, _ := createBody["customRules"].([]interface{}); len(rules) != 1 {
+				t.Errorf("Expected SPA fallback rule, got %v", createBody["customRules"])
+			}
+		})
+	}
+}
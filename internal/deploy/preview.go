@@ -0,0 +1,254 @@
+package deploy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// previewMarker separates the base app name from the preview name in
+	// DigitalOcean preview app names
+	previewMarker = "-pv-"
+	// previewDescription tags Amplify branches created for previews so that
+	// cleanup never touches branches managed by hand
+	previewDescription = "mvpbridge preview"
+	// maxDOAppName is the longest app name DigitalOcean accepts
+	maxDOAppName = 32
+	// maxPreviewBase is how much of the base app name is kept in preview names
+	maxPreviewBase = 12
+	// previewHashLen is the length of the base name hash in preview names
+	previewHashLen = 4
+)
+
+// Preview describes a preview deployment found on a platform
+type Preview struct {
+	Name      string
+	AppID     string
+	Branch    string
+	CreatedAt time.Time
+}
+
+// PreviewAppName returns the DigitalOcean app name for a preview. The base
+// name is always shortened the same way so previews can be found by prefix.
+func PreviewAppName(base, name string) string {
+	return previewPrefix(base) + previewSuffix(base, name)
+}
+
+// PreviewName returns the name a preview is listed under on DigitalOcean:
+// name as shortened in its app name
+func (d *DODeployer) PreviewName(name string) string {
+	return previewSuffix(d.AppName, name)
+}
+
+// previewSuffix returns the preview part of a preview app name
+func previewSuffix(base, name string) string {
+	name = sanitizeName(name)
+	maxName := maxDOAppName - len(previewPrefix(base))
+	if len(name) > maxName {
+		name = strings.TrimRight(name[:maxName], "-")
+	}
+	return name
+}
+
+// previewPrefix returns the prefix shared by all preview apps of base. A hash
+// of the whole name keeps apart apps whose names start the same way.
+func previewPrefix(base string) string {
+	base = sanitizeName(base)
+	sum := sha256.Sum256([]byte(base))
+	short := base
+	if len(short) > maxPreviewBase {
+		short = strings.TrimRight(short[:maxPreviewBase], "-")
+	}
+	return short + "-" + hex.EncodeToString(sum[:])[:previewHashLen] + previewMarker
+}
+
+// sanitizeName lowercases s and replaces anything that is not a letter,
+// digit or hyphen, producing a valid app name or subdomain label
+func sanitizeName(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('-')
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+// UsePreview points the deployer at the preview app for name, built from
+// branch. Custom domains belong to the production app, so the preview gets
+// none, neither from the config nor from the spec file.
+//
+// A preview runs unmerged code, so it must not touch production data either:
+// a managed cluster is replaced by a dev database when the engine allows it
+// and dropped otherwise. Without a database of its own the preview also loses
+// its pre-deploy jobs, which run migrations.
+func (d *DODeployer) UsePreview(name, branch string) error {
+	d.AppName = PreviewAppName(d.AppName, name)
+	d.AppID = ""
+	d.Branch = branch
+	d.Domains = nil
+
+	if db := d.Database; db != nil && (db.Production || db.ClusterName != "") {
+		d.Database = nil
+		if db.Engine == "" || db.Engine == "PG" {
+			d.Database = &DODatabase{Name: db.Name, Engine: "PG"}
+		}
+	}
+
+	var base map[string]interface{}
+	if d.BaseSpec != nil {
+		var err error
+		if base, err = toGeneric(d.BaseSpec); err != nil {
+			return err
+		}
+		delete(base, "domains")
+		setList(base, "databases", devDatabases(base["databases"]))
+		d.BaseSpec = base
+	}
+
+	if d.Database == nil && len(componentList(base["databases"])) == 0 {
+		var components []DOComponent
+		for _, c := range d.Components {
+			if c.Kind != "job" || orDefault(c.JobKind, "PRE_DEPLOY") != "PRE_DEPLOY" {
+				components = append(components, c)
+			}
+		}
+		d.Components = components
+		if base != nil {
+			setList(base, "jobs", withoutPreDeployJobs(base["jobs"]))
+		}
+	}
+	return nil
+}
+
+// setList sets a list in a spec file, removing the key when the list is empty
+func setList(spec map[string]interface{}, key string, list []interface{}) {
+	if len(list) == 0 {
+		delete(spec, key)
+		return
+	}
+	spec[key] = list
+}
+
+// devDatabases returns the databases of a spec file that are not managed
+// clusters
+func devDatabases(raw interface{}) []interface{} {
+	var kept []interface{}
+	for _, db := range componentList(raw) {
+		production, _ := db["production"].(bool)
+		if cluster, _ := db["cluster_name"].(string); !production && cluster == "" {
+			kept = append(kept, db)
+		}
+	}
+	return kept
+}
+
+// withoutPreDeployJobs returns the jobs of a spec file that do not run
+// before deploys
+func withoutPreDeployJobs(raw interface{}) []interface{} {
+	var kept []interface{}
+	for _, job := range componentList(raw) {
+		if kind, _ := job["kind"].(string); kind != "PRE_DEPLOY" {
+			kept = append(kept, job)
+		}
+	}
+	return kept
+}
+
+// ListPreviews returns the preview apps created for the deployer's app
+func (d *DODeployer) ListPreviews() ([]Preview, error) {
+	apps, err := d.ListApps()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := previewPrefix(d.AppName)
+	var previews []Preview
+	for _, app := range apps {
+		if strings.HasPrefix(app.Name, prefix) {
+			previews = append(previews, Preview{
+				Name:      strings.TrimPrefix(app.Name, prefix),
+				AppID:     app.ID,
+				CreatedAt: app.CreatedAt,
+			})
+		}
+	}
+
+	return previews, nil
+}
+
+// PreviewName returns the name a preview is listed under on Amplify: its
+// branch display name
+func (d *AWSDeployer) PreviewName(name string) string {
+	return sanitizeName(name)
+}
+
+// DeployPreview creates a DEVELOPMENT branch on an existing app for the
+// deployer's branch and starts a build. It returns the preview URL.
+func (d *AWSDeployer) DeployPreview(appID, defaultDomain, name string, envVars map[string]string) (string, error) {
+	displayName := sanitizeName(name)
+	if displayName == "" {
+		return "", fmt.Errorf("invalid preview name: %q", name)
+	}
+
+	existing, err := d.ListPreviews(appID)
+	if err != nil {
+		return "", err
+	}
+
+	found := false
+	for _, p := range existing {
+		if p.Branch == d.Branch {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		branch := AmplifyBranch{
+			BranchName:           d.Branch,
+			DisplayName:          displayName,
+			Description:          previewDescription,
+			EnableAutoBuild:      true,
+			Stage:                "DEVELOPMENT",
+			EnvironmentVariables: envVars,
+		}
+		if err := d.postBranch(appID, branch); err != nil {
+			return "", fmt.Errorf("creating branch: %w", err)
+		}
+	}
+
+	if _, err := d.StartJob(appID, d.Branch, d.CommitID); err != nil {
+		return "", fmt.Errorf("starting build: %w", err)
+	}
+
+	return fmt.Sprintf("https://%s.%s", displayName, defaultDomain), nil
+}
+
+// ListPreviews returns the preview branches of an app
+func (d *AWSDeployer) ListPreviews(appID string) ([]Preview, error) {
+	branches, err := d.ListBranches(appID)
+	if err != nil {
+		return nil, err
+	}
+
+	var previews []Preview
+	for _, b := range branches {
+		if b.Description == previewDescription {
+			previews = append(previews, Preview{
+				Name:      b.DisplayName,
+				AppID:     appID,
+				Branch:    b.BranchName,
+				CreatedAt: b.Created(),
+			})
+		}
+	}
+
+	return previews, nil
+}
@@ -0,0 +1,334 @@
+package deploy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPreviewAppName(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    string
+		preview string
+		want    string
+	}{
+		{
+			name:    "Short names",
+			base:    "my-app",
+			preview: "pr-42",
+			want:    "my-app-4c9a-pv-pr-42",
+		},
+		{
+			name:    "Sanitizes preview name",
+			base:    "my-app",
+			preview: "Feature/Login_Page",
+			want:    "my-app-4c9a-pv-feature-login-pag",
+		},
+		{
+			name:    "Truncates long base name",
+			base:    "a-really-long-application-name",
+			preview: "pr-7",
+			want:    "a-really-lon-9a6e-pv-pr-7",
+		},
+		{
+			name:    "Truncates long preview name",
+			base:    "shop",
+			preview: "this-preview-name-is-far-too-long",
+			want:    "shop-8d90-pv-this-preview-name-i",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PreviewAppName(tt.base, tt.preview)
+			if got != tt.want {
+				t.Errorf("PreviewAppName(%q, %q) = %q, want %q", tt.base, tt.preview, got, tt.want)
+			}
+			if len(got) > maxDOAppName {
+				t.Errorf("Preview app name %q exceeds %d characters", got, maxDOAppName)
+			}
+			if !strings.HasPrefix(got, previewPrefix(tt.base)) {
+				t.Errorf("Preview app name %q missing prefix %q", got, previewPrefix(tt.base))
+			}
+		})
+	}
+
+	// Apps that differ only after the kept characters get their own previews
+	if a, b := previewPrefix("a-really-long-application-name"), previewPrefix("a-really-long-application-other"); a == b {
+		t.Errorf("Expected different prefixes for different apps, both got %q", a)
+	}
+}
+
+func TestPreviewName(t *testing.T) {
+	do := &DODeployer{AppName: "shop"}
+	for _, name := range []string{"PR_42", "this-preview-name-is-far-too-long"} {
+		if got, want := PreviewAppName("shop", name), previewPrefix("shop")+do.PreviewName(name); got != want {
+			t.Errorf("DigitalOcean preview %q is listed as %q, want the suffix of %q", name, want, got)
+		}
+	}
+	if got := do.PreviewName("PR_42"); got != "pr-42" {
+		t.Errorf("PreviewName(PR_42) = %q, want pr-42", got)
+	}
+	if got := (&AWSDeployer{}).PreviewName("Feature/Login"); got != "feature-login" {
+		t.Errorf("Amplify PreviewName(Feature/Login) = %q, want feature-login", got)
+	}
+}
+
+func TestDOListPreviews(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != httpMethodGet || r.URL.Path != "/apps" {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"apps": [
+			{"id": "1", "created_at": "2024-01-02T03:04:05Z", "spec": {"name": "my-app"}},
+			{"id": "2", "created_at": "2024-01-02T03:04:05Z", "spec": {"name": "my-app-4c9a-pv-pr-1"}},
+			{"id": "3", "created_at": "2024-01-02T03:04:05Z", "spec": {"name": "my-app-0000-pv-pr-1"}}
+		]}`))
+	}))
+	defer server.Close()
+
+	deployer := &DODeployer{Token: "test", AppName: "my-app", client: server.Client(), baseURL: server.URL}
+
+	previews, err := deployer.ListPreviews()
+	if err != nil {
+		t.Fatalf("ListPreviews() error: %v", err)
+	}
+
+	if len(previews) != 1 {
+		t.Fatalf("Expected 1 preview, got %d", len(previews))
+	}
+	if previews[0].Name != "pr-1" || previews[0].AppID != "2" {
+		t.Errorf("Unexpected preview: %+v", previews[0])
+	}
+	if !previews[0].CreatedAt.Equal(created) {
+		t.Errorf("Expected created time %v, got %v", created, previews[0].CreatedAt)
+	}
+}
+
+func TestDODeleteApp(t *testing.T) {
+	var gotMethod, gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath = r.Method, r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	deployer := &DODeployer{Token: "test", client: server.Client(), baseURL: server.URL}
+
+	if err := deployer.DeleteApp("abc"); err != nil {
+		t.Fatalf("DeleteApp() error: %v", err)
+	}
+	if gotMethod != "DELETE" || gotPath != "/apps/abc" {
+		t.Errorf("Expected DELETE /apps/abc, got %s %s", gotMethod, gotPath)
+	}
+}
+
+func TestDOUsePreviewSpec(t *testing.T) {
+	deployer := &DODeployer{
+		AppName: "shop",
+		AppID:   "prod-id",
+		RepoURL: "https://github.com/acme/shop",
+		Branch:  "main",
+		Domains: []DODomain{{Domain: "shop.example.com", Type: "PRIMARY"}},
+		BaseSpec: map[string]interface{}{
+			"name":    "shop",
+			"domains": []interface{}{map[string]interface{}{"domain": "www.shop.example.com"}},
+			"alerts":  []interface{}{map[string]interface{}{"rule": "DEPLOYMENT_FAILED"}},
+		},
+	}
+	base := deployer.BaseSpec
+
+	if err := deployer.UsePreview("pr-42", "feature"); err != nil {
+		t.Fatalf("UsePreview() error: %v", err)
+	}
+	if deployer.AppName != PreviewAppName("shop", "pr-42") || deployer.AppID != "" || deployer.Branch != "feature" {
+		t.Errorf("Unexpected preview deployer: name %q, ID %q, branch %q", deployer.AppName, deployer.AppID, deployer.Branch)
+	}
+
+	body, err := deployer.appSpec(deployer.buildSpec(true, nil))
+	if err != nil {
+		t.Fatalf("appSpec() error: %v", err)
+	}
+	spec, err := toGeneric(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := spec["domains"]; ok {
+		t.Errorf("Expected no domains in preview spec, got %v", spec["domains"])
+	}
+	if _, ok := spec["alerts"]; !ok {
+		t.Error("Expected the rest of the spec file kept")
+	}
+	if _, ok := base["domains"]; !ok {
+		t.Error("Expected the loaded spec file left unchanged")
+	}
+}
+
+func TestDOUsePreviewDatabase(t *testing.T) {
+	migrate := DOComponent{Name: "migrate", Kind: "job", RunCommand: "npx prisma migrate deploy"}
+	worker := DOComponent{Name: "worker", Kind: "worker", RunCommand: "node worker.js"}
+
+	tests := []struct {
+		name        string
+		database    *DODatabase
+		base        map[string]interface{}
+		wantDB      *DODatabase
+		wantMigrate bool
+	}{
+		{
+			name:        "PostgreSQL cluster becomes a dev database",
+			database:    &DODatabase{Name: "db", Engine: "PG", Production: true, ClusterName: "prod-pg", DBName: "shop"},
+			wantDB:      &DODatabase{Name: "db", Engine: "PG"},
+			wantMigrate: true,
+		},
+		{
+			name:     "MySQL cluster is dropped with the migration job",
+			database: &DODatabase{Name: "db", Engine: "MYSQL", Production: true, ClusterName: "prod-mysql"},
+		},
+		{
+			name:        "Dev database is kept",
+			database:    &DODatabase{Name: "db", Engine: "PG"},
+			wantDB:      &DODatabase{Name: "db", Engine: "PG"},
+			wantMigrate: true,
+		},
+		{
+			name: "Cluster in the spec file is dropped with its jobs",
+			base: map[string]interface{}{
+				"name":      "shop",
+				"databases": []interface{}{map[string]interface{}{"name": "db", "production": true, "cluster_name": "prod-pg"}},
+				"jobs": []interface{}{
+					map[string]interface{}{"name": "seed", "kind": "PRE_DEPLOY"},
+					map[string]interface{}{"name": "notify", "kind": "POST_DEPLOY"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployer := &DODeployer{
+				AppName:    "shop",
+				Components: []DOComponent{worker, migrate},
+				Database:   tt.database,
+				BaseSpec:   tt.base,
+			}
+			if err := deployer.UsePreview("pr-1", "feature"); err != nil {
+				t.Fatalf("UsePreview() error: %v", err)
+			}
+
+			switch {
+			case tt.wantDB == nil && deployer.Database != nil:
+				t.Errorf("Expected no database, got %+v", *deployer.Database)
+			case tt.wantDB != nil && (deployer.Database == nil || *deployer.Database != *tt.wantDB):
+				t.Errorf("Database = %+v, want %+v", deployer.Database, *tt.wantDB)
+			}
+
+			hasMigrate := false
+			for _, c := range deployer.Components {
+				hasMigrate = hasMigrate || c.Name == "migrate"
+			}
+			if hasMigrate != tt.wantMigrate {
+				t.Errorf("Migration job kept = %v, want %v", hasMigrate, tt.wantMigrate)
+			}
+			if len(deployer.Components) == 0 || deployer.Components[0].Name != "worker" {
+				t.Errorf("Expected the worker kept, got %+v", deployer.Components)
+			}
+
+			if tt.base != nil {
+				if _, ok := deployer.BaseSpec["databases"]; ok {
+					t.Errorf("Expected the cluster removed, got %v", deployer.BaseSpec["databases"])
+				}
+				jobs := componentList(deployer.BaseSpec["jobs"])
+				if len(jobs) != 1 || jobs[0]["name"] != "notify" {
+					t.Errorf("Expected only the post-deploy job kept, got %v", jobs)
+				}
+			}
+		})
+	}
+}
+
+func TestAWSDeployPreview(t *testing.T) {
+	var created AmplifyBranch
+	var jobStarted bool
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == httpMethodGet && r.URL.Path == "/apps/app1/branches":
+			_, _ = w.Write([]byte(`{"branches": [{"branchName": "main", "stage": "PRODUCTION"}]}`))
+		case r.Method == httpMethodPost && r.URL.Path == "/apps/app1/branches":
+			_ = json.NewDecoder(r.Body).Decode(&created)
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == httpMethodPost && r.URL.Path == "/apps/app1/branches/feature-x/jobs":
+			jobStarted = true
+			_, _ = w.Write([]byte(`{"jobSummary": {"jobId": "1"}}`))
+		default:
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	deployer := &AWSDeployer{
+		AccessKey: "key",
+		SecretKey: "secret",
+		Region:    "us-east-1",
+		Branch:    "feature-x",
+		client:    server.Client(),
+		endpoint:  server.URL,
+	}
+
+	url, err := deployer.DeployPreview("app1", "d123.amplifyapp.com", "PR 12", nil)
+	if err != nil {
+		t.Fatalf("DeployPreview() error: %v", err)
+	}
+
+	if url != "https://pr-12.d123.amplifyapp.com" {
+		t.Errorf("Unexpected preview URL: %s", url)
+	}
+	if created.Stage != "DEVELOPMENT" {
+		t.Errorf("Expected DEVELOPMENT stage, got %s", created.Stage)
+	}
+	if created.Description != previewDescription {
+		t.Errorf("Expected preview description, got %q", created.Description)
+	}
+	if created.BranchName != "feature-x" {
+		t.Errorf("Expected branch feature-x, got %s", created.BranchName)
+	}
+	if !jobStarted {
+		t.Error("Expected a build job to be started")
+	}
+}
+
+func TestAWSListPreviews(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"branches": [
+			{"branchName": "main", "stage": "PRODUCTION", "createTime": 1700000000},
+			{"branchName": "feature-x", "displayName": "pr-12", "description": "mvpbridge preview", "stage": "DEVELOPMENT", "createTime": 1700000000},
+			{"branchName": "staging", "stage": "DEVELOPMENT", "createTime": 1700000000}
+		]}`))
+	}))
+	defer server.Close()
+
+	deployer := &AWSDeployer{Region: "us-east-1", client: server.Client(), endpoint: server.URL}
+
+	previews, err := deployer.ListPreviews("app1")
+	if err != nil {
+		t.Fatalf("ListPreviews() error: %v", err)
+	}
+
+	if len(previews) != 1 {
+		t.Fatalf("Expected 1 preview, got %d", len(previews))
+	}
+	if previews[0].Branch != "feature-x" || previews[0].Name != "pr-12" {
+		t.Errorf("Unexpected preview: %+v", previews[0])
+	}
+	if previews[0].CreatedAt.Unix() != 1700000000 {
+		t.Errorf("Unexpected created time: %v", previews[0].CreatedAt)
+	}
+}
@@ -0,0 +1,69 @@
+package deploy
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// secretPatterns match credentials an error message can echo back
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`dop_v1_[0-9a-f]+`),          // DigitalOcean tokens
+	regexp.MustCompile(`gh[pousr]_[A-Za-z0-9]+`),    // GitHub tokens
+	regexp.MustCompile(`glpat-[A-Za-z0-9_-]+`),      // GitLab tokens
+	regexp.MustCompile(`\b(AKIA|ASIA)[A-Z0-9]{16}`), // AWS access key IDs
+	regexp.MustCompile(`(?i)bearer\s+\S+`),
+	regexp.MustCompile(`EV\[[^\]]*\]`), // DigitalOcean encrypted env values
+}
+
+// RedactError returns an error's message in a form that is safe to store.
+// API error bodies can echo the request, env values included, so they are
+// cut down to their message; anything that looks like a credential is masked.
+func RedactError(err error) string {
+	msg := err.Error()
+
+	for _, apiErr := range apiErrors(err) {
+		if apiErr.Body != "" {
+			msg = strings.ReplaceAll(msg, apiErr.Body, apiErrorMessage(apiErr.Body))
+		}
+	}
+
+	for _, pattern := range secretPatterns {
+		msg = pattern.ReplaceAllString(msg, "[REDACTED]")
+	}
+	return msg
+}
+
+// apiErrors returns every API error wrapped in err, including each branch of
+// errors joined with several %w verbs
+func apiErrors(err error) []*APIError {
+	if err == nil {
+		return nil
+	}
+
+	var found []*APIError
+	if apiErr, ok := err.(*APIError); ok {
+		found = append(found, apiErr)
+	}
+	switch wrapped := err.(type) {
+	case interface{ Unwrap() error }:
+		found = append(found, apiErrors(wrapped.Unwrap())...)
+	case interface{ Unwrap() []error }:
+		for _, e := range wrapped.Unwrap() {
+			found = append(found, apiErrors(e)...)
+		}
+	}
+	return found
+}
+
+// apiErrorMessage returns the message field of a JSON error body, which both
+// platforms send, and nothing else
+func apiErrorMessage(body string) string {
+	var parsed struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(body), &parsed); err == nil && parsed.Message != "" {
+		return parsed.Message
+	}
+	return "[response body redacted]"
+}
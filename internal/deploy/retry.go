@@ -0,0 +1,236 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// retryPolicy controls how failed API requests are retried
+type retryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration // first backoff, doubled on every attempt
+	MaxDelay    time.Duration // cap on backoff and on server-requested waits
+}
+
+// defaultRetry is used for every cloud API request
+var defaultRetry = retryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    30 * time.Second,
+}
+
+// awsThrottlingCodes are AWS error codes for requests rejected before being
+// processed, which are safe to retry whatever the method
+var awsThrottlingCodes = map[string]bool{
+	"Throttling":                true,
+	"ThrottlingException":       true,
+	"ThrottledException":        true,
+	"TooManyRequestsException":  true,
+	"RequestLimitExceeded":      true,
+	"RequestThrottled":          true,
+	"RequestThrottledException": true,
+	"SlowDown":                  true,
+}
+
+// sendWithRetry performs req and returns the body of a 2xx response. Rate
+// limited and throttled requests are always retried, since the server
+// rejected them unprocessed; network errors and 5xx responses are retried
+// only for idempotent methods. prepare runs before every attempt to set
+// headers and signatures, which must be fresh on each try.
+func sendWithRetry(client *http.Client, req *http.Request, prepare func(*http.Request)) ([]byte, error) {
+	ctx := req.Context()
+
+	for attempt := 1; ; attempt++ {
+		r, err := cloneRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		prepare(r)
+
+		resp, body, err := roundTrip(client, r)
+		if err == nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return body, nil
+		}
+		if err == nil {
+			err = &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if attempt >= defaultRetry.MaxAttempts || !retryable(req, resp, body) {
+			return nil, err
+		}
+
+		wait, ok := serverDelay(resp, time.Now())
+		if !ok {
+			wait = backoff(attempt)
+		}
+		if wait > defaultRetry.MaxDelay {
+			return nil, fmt.Errorf("%w (server asked to retry in %s)", err, wait.Round(time.Second))
+		}
+
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// cloneRequest copies req with a fresh body so it can be sent again
+func cloneRequest(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	return r, nil
+}
+
+// roundTrip sends a request and reads the whole response body
+func roundTrip(client *http.Client, req *http.Request) (*http.Response, []byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return resp, body, nil
+}
+
+// retryable decides whether a failed attempt may be repeated
+func retryable(req *http.Request, resp *http.Response, body []byte) bool {
+	// A body that cannot be replayed can only be sent once
+	if req.Body != nil && req.GetBody == nil {
+		return false
+	}
+
+	if resp == nil {
+		return idempotent(req.Method)
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || awsThrottlingCodes[awsErrorCode(resp.Header, body)] {
+		return true
+	}
+
+	return resp.StatusCode >= 500 && idempotent(req.Method)
+}
+
+// idempotent reports whether repeating a request with this method is safe
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// serverDelay returns how long the server asked us to wait, from Retry-After
+// or, on DigitalOcean, from an exhausted RateLimit-Remaining with RateLimit-Reset
+func serverDelay(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second, true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return max(t.Sub(now), 0), true
+		}
+	}
+
+	if resp.Header.Get("RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Unix(reset, 0).Sub(now), 0), true
+		}
+	}
+
+	return 0, false
+}
+
+// backoff returns an exponential delay for the given attempt with jitter, so
+// concurrent clients do not retry in lockstep
+func backoff(attempt int) time.Duration {
+	d := defaultRetry.BaseDelay << (attempt - 1)
+	if d <= 0 || d > defaultRetry.MaxDelay {
+		d = defaultRetry.MaxDelay
+	}
+	half := int64(d / 2)
+	if half == 0 {
+		return d
+	}
+	// #nosec G404 - jitter does not need a cryptographic source
+	return time.Duration(half + rand.Int63n(half))
+}
+
+// awsErrorCode extracts the error code from an AWS error response, which is
+// sent in X-Amzn-ErrorType, a JSON __type/code field or an XML <Code> element
+func awsErrorCode(header http.Header, body []byte) string {
+	if v := header.Get("X-Amzn-ErrorType"); v != "" {
+		code, _, _ := strings.Cut(v, ":")
+		return code
+	}
+
+	var jsonErr struct {
+		Type string `json:"__type"`
+		Code string `json:"code"`
+	}
+	if json.Unmarshal(body, &jsonErr) == nil {
+		code := jsonErr.Type
+		if code == "" {
+			code = jsonErr.Code
+		}
+		if i := strings.LastIndex(code, "#"); i >= 0 {
+			code = code[i+1:]
+		}
+		if code != "" {
+			return code
+		}
+	}
+
+	var xmlErr struct {
+		Code string `xml:"Error>Code"`
+	}
+	if xml.Unmarshal(body, &xmlErr) == nil {
+		return xmlErr.Code
+	}
+	return ""
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// orBackground returns ctx, or context.Background() when none was set
+func orBackground(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
@@ -0,0 +1,174 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fastRetry shortens backoff for the duration of a test
+func fastRetry(t *testing.T) {
+	t.Helper()
+	saved := defaultRetry
+	defaultRetry.BaseDelay = time.Millisecond
+	defaultRetry.MaxDelay = time.Second
+	t.Cleanup(func() { defaultRetry = saved })
+}
+
+func TestSendWithRetry(t *testing.T) {
+	fastRetry(t)
+
+	tests := []struct {
+		name      string
+		method    string
+		failures  int
+		status    int
+		header    http.Header
+		body      string
+		wantCalls int32
+		wantErr   bool
+	}{
+		{name: "Rate limited GET recovers", method: "GET", failures: 2, status: http.StatusTooManyRequests, header: http.Header{"Retry-After": {"0"}}, wantCalls: 3},
+		{name: "Rate limited POST recovers", method: "POST", failures: 1, status: http.StatusTooManyRequests, wantCalls: 2},
+		{name: "Server error GET recovers", method: "GET", failures: 1, status: http.StatusServiceUnavailable, wantCalls: 2},
+		{name: "Server error POST is not retried", method: "POST", failures: 1, status: http.StatusServiceUnavailable, wantCalls: 1, wantErr: true},
+		{name: "AWS throttling POST recovers", method: "POST", failures: 1, status: http.StatusBadRequest, body: `{"__type": "com.amazonaws#ThrottlingException"}`, wantCalls: 2},
+		{name: "AWS throttling header recovers", method: "POST", failures: 1, status: http.StatusBadRequest, header: http.Header{"X-Amzn-Errortype": {"TooManyRequestsException:http://internal.amazon.com/"}}, wantCalls: 2},
+		{name: "Client error is not retried", method: "GET", failures: 1, status: http.StatusNotFound, wantCalls: 1, wantErr: true},
+		{name: "Gives up after max attempts", method: "GET", failures: 10, status: http.StatusBadGateway, wantCalls: 5, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&calls, 1)
+				if r.Header.Get("Authorization") != "Bearer test" {
+					t.Errorf("Attempt %d missing prepared header", n)
+				}
+				if int(n) <= tt.failures {
+					for k, v := range tt.header {
+						w.Header()[k] = v
+					}
+					w.WriteHeader(tt.status)
+					_, _ = w.Write([]byte(tt.body))
+					return
+				}
+				_, _ = w.Write([]byte("ok"))
+			}))
+			defer server.Close()
+
+			req, _ := http.NewRequest(tt.method, server.URL, nil)
+			body, err := sendWithRetry(server.Client(), req, func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer test")
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("sendWithRetry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(body) != "ok" {
+				t.Errorf("Expected body ok, got %q", body)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("Expected %d calls, got %d", tt.wantCalls, calls)
+			}
+		})
+	}
+}
+
+func TestSendWithRetryReplaysBody(t *testing.T) {
+	fastRetry(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := make([]byte, 64)
+		n, _ := r.Body.Read(buf)
+		if string(buf[:n]) != `{"a":1}` {
+			t.Errorf("Unexpected body on attempt %d: %q", calls+1, buf[:n])
+		}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest("PUT", server.URL, strings.NewReader(`{"a":1}`))
+	if _, err := sendWithRetry(server.Client(), req, func(*http.Request) {}); err != nil {
+		t.Fatalf("sendWithRetry() error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls)
+	}
+}
+
+func TestSendWithRetryCanceled(t *testing.T) {
+	saved := defaultRetry
+	defer func() { defaultRetry = saved }()
+	defaultRetry.BaseDelay = time.Minute
+	defaultRetry.MaxDelay = time.Hour
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	start := time.Now()
+	_, err := sendWithRetry(server.Client(), req, func(*http.Request) {})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Error("Expected the backoff wait to be interrupted")
+	}
+}
+
+func TestServerDelay(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "Retry-After seconds", header: http.Header{"Retry-After": {"7"}}, want: 7 * time.Second, wantOK: true},
+		{name: "Retry-After date", header: http.Header{"Retry-After": {now.Add(3 * time.Second).Format(http.TimeFormat)}}, want: 3 * time.Second, wantOK: true},
+		{name: "DO rate limit reset", header: http.Header{"Ratelimit-Remaining": {"0"}, "Ratelimit-Reset": {strconv.FormatInt(now.Add(5*time.Second).Unix(), 10)}}, want: 5 * time.Second, wantOK: true},
+		{name: "DO rate limit not exhausted", header: http.Header{"Ratelimit-Remaining": {"10"}, "Ratelimit-Reset": {"0"}}},
+		{name: "No headers", header: http.Header{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := serverDelay(&http.Response{Header: tt.header}, now)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("serverDelay() = %v, %v; want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	saved := defaultRetry
+	defer func() { defaultRetry = saved }()
+	defaultRetry.BaseDelay = 100 * time.Millisecond
+	defaultRetry.MaxDelay = time.Second
+
+	for attempt := 1; attempt <= 8; attempt++ {
+		ceiling := min(defaultRetry.BaseDelay<<(attempt-1), defaultRetry.MaxDelay)
+		got := backoff(attempt)
+		if got < ceiling/2 || got > ceiling {
+			t.Errorf("backoff(%d) = %v, want between %v and %v", attempt, got, ceiling/2, ceiling)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package deploy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ListDeployments returns the most recent deployments of an app, newest first
+func (d *DODeployer) ListDeployments(appID string, limit int) ([]DODeployment, error) {
+	endpoint := fmt.Sprintf("%s/apps/%s/deployments?per_page=%d", d.apiBase(), appID, limit)
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := d.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Deployments []DODeployment `json:"deployments"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	return result.Deployments, nil
+}
+
+// StartedDeployment returns the deployment that creating or updating an app
+// started: the pending or in-progress deployment in the response. App
+// Platform starts none when the spec is unchanged, so one is then created,
+// building the branch tip.
+func (d *DODeployer) StartedDeployment(app *DOApp) (*DODeployment, error) {
+	for _, dep := range []*DODeployment{app.PendingDeployment, app.InProgressDeployment} {
+		if dep != nil && dep.ID != "" {
+			return dep, nil
+		}
+	}
+	return d.CreateDeployment(app.ID)
+}
+
+// CreateDeployment starts a deployment of an app, rebuilding its source
+func (d *DODeployer) CreateDeployment(appID string) (*DODeployment, error) {
+	jsonBody, err := json.Marshal(map[string]bool{"force_build": true})
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s/apps/%s/deployments", d.apiBase(), appID)
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "POST", endpoint, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := d.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Deployment DODeployment `json:"deployment"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	return &result.Deployment, nil
+}
+
+// GetDeployment fetches a single deployment of an app
+func (d *DODeployer) GetDeployment(appID, deploymentID string) (*DODeployment, error) {
+	endpoint := fmt.Sprintf("%s/apps/%s/deployments/%s", d.apiBase(), appID, deploymentID)
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := d.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Deployment DODeployment `json:"deployment"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	return &result.Deployment, nil
+}
+
+// WaitForDeploymentID polls a specific deployment until it is live or fails
+func (d *DODeployer) WaitForDeploymentID(appID, deploymentID string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for time.Now().Before(deadline) {
+		dep, err := d.GetDeployment(appID, deploymentID)
+		if err != nil {
+			return err
+		}
+
+		switch dep.Phase {
+		case "ACTIVE":
+			return nil
+		case "ERROR", "CANCELED", "SUPERSEDED":
+			return fmt.Errorf("deployment failed: %s", dep.Phase)
+		}
+
+		fmt.Printf("  Deployment status: %s\n", dep.Phase)
+		if err := sleepContext(orBackground(d.ctx), pollInterval); err != nil {
+			return err
+		}
+	}
+
+	return fmt.Errorf("deployment timed out after %v", timeout)
+}
+
+// Rollback validates and then starts a rollback to an earlier deployment.
+// The app stays pinned to that deployment until CommitRollback or
+// RevertRollback is called.
+func (d *DODeployer) Rollback(appID, deploymentID string) (*DODeployment, error) {
+	var validation struct {
+		Valid bool `json:"valid"`
+		Error *struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := d.rollbackCall(appID, "/rollback/validate", deploymentID, &validation); err != nil {
+		return nil, fmt.Errorf("validating rollback: %w", err)
+	}
+	if !validation.Valid {
+		if validation.Error != nil {
+			return nil, fmt.Errorf("rollback not possible: %s", validation.Error.Message)
+		}
+		return nil, fmt.Errorf("rollback not possible")
+	}
+
+	var result struct {
+		Deployment DODeployment `json:"deployment"`
+	}
+	if err := d.rollbackCall(appID, "/rollback", deploymentID, &result); err != nil {
+		return nil, err
+	}
+
+	return &result.Deployment, nil
+}
+
+// CommitRollback makes a rollback permanent and unpins the app
+func (d *DODeployer) CommitRollback(appID string) error {
+	return d.rollbackCall(appID, "/rollback/commit", "", nil)
+}
+
+// RevertRollback undoes a rollback that has not been committed
+func (d *DODeployer) RevertRollback(appID string) error {
+	return d.rollbackCall(appID, "/rollback/revert", "", nil)
+}
+
+func (d *DODeployer) rollbackCall(appID, path, deploymentID string, out interface{}) error {
+	payload := map[string]interface{}{}
+	if deploymentID != "" {
+		payload["deployment_id"] = deploymentID
+	}
+	jsonBody, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/apps/%s%s", d.apiBase(), appID, path)
+	req, err := http.NewRequestWithContext(orBackground(d.ctx), "POST", endpoint, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return err
+	}
+
+	body, err := d.send(req)
+	if err != nil {
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+	return nil
+}
+
+// PreviousDeployment returns the newest successful deployment older than the
+// active one, or nil if there is none
+func PreviousDeployment(deployments []DODeployment, activeID string) *DODeployment {
+	seenActive := false
+	for i := range deployments {
+		dep := &deployments[i]
+		if dep.ID == activeID {
+			seenActive = true
+			continue
+		}
+		if seenActive && (dep.Phase == "ACTIVE" || dep.Phase == "SUPERSEDED") {
+			return dep
+		}
+	}
+	return nil
+}
+
+// PreviousJob returns the newest successful job older than the newest
+// successful one, or nil if there is none
+func PreviousJob(jobs []AmplifyJobSummary) *AmplifyJobSummary {
+	seenCurrent := false
+	for i := range jobs {
+		if jobs[i].Status != "SUCCEED" {
+			continue
+		}
+		if seenCurrent {
+			return &jobs[i]
+		}
+		seenCurrent = true
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package deploy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDORollbackValidatesFirst(t *testing.T) {
+	tests := []struct {
+		name      string
+		valid     bool
+		wantErr   bool
+		wantCalls []string
+	}{
+		{
+			name:      "Valid rollback",
+			valid:     true,
+			wantErr:   false,
+			wantCalls: []string{"/apps/app1/rollback/validate", "/apps/app1/rollback"},
+		},
+		{
+			name:      "Invalid rollback",
+			valid:     false,
+			wantErr:   true,
+			wantCalls: []string{"/apps/app1/rollback/validate"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, r.URL.Path)
+
+				var payload map[string]string
+				_ = json.NewDecoder(r.Body).Decode(&payload)
+				if payload["deployment_id"] != "dep-1" {
+					t.Errorf("Expected deployment_id dep-1, got %q", payload["deployment_id"])
+				}
+
+				switch r.URL.Path {
+				case "/apps/app1/rollback/validate":
+					if tt.valid {
+						_, _ = w.Write([]byte(`{"valid": true}`))
+					} else {
+						_, _ = w.Write([]byte(`{"valid": false, "error": {"code": "incompatible", "message": "spec changed"}}`))
+					}
+				case "/apps/app1/rollback":
+					_, _ = w.Write([]byte(`{"deployment": {"id": "dep-3", "phase": "PENDING_BUILD"}}`))
+				default:
+					http.Error(w, "Not found", http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			deployer := &DODeployer{Token: "test", client: server.Client(), baseURL: server.URL}
+
+			dep, err := deployer.Rollback("app1", "dep-1")
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Rollback() error: %v", err)
+				}
+				if dep.ID != "dep-3" {
+					t.Errorf("Expected new deployment dep-3, got %s", dep.ID)
+				}
+			}
+
+			if len(calls) != len(tt.wantCalls) {
+				t.Fatalf("Expected calls %v, got %v", tt.wantCalls, calls)
+			}
+			for i := range calls {
+				if calls[i] != tt.wantCalls[i] {
+					t.Errorf("Call %d: expected %s, got %s", i, tt.wantCalls[i], calls[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPreviousDeployment(t *testing.T) {
+	deployments := []DODeployment{
+		{ID: "dep-4", Phase: "ERROR"},
+		{ID: "dep-3", Phase: "ACTIVE"},
+		{ID: "dep-2", Phase: "ERROR"},
+		{ID: "dep-1", Phase: "SUPERSEDED"},
+	}
+
+	prev := PreviousDeployment(deployments, "dep-3")
+	if prev == nil || prev.ID != "dep-1" {
+		t.Errorf("Expected dep-1, got %+v", prev)
+	}
+
+	if prev := PreviousDeployment(deployments, "dep-1"); prev != nil {
+		t.Errorf("Expected no previous deployment, got %+v", prev)
+	}
+}
+
+func TestDOStartedDeployment(t *testing.T) {
+	var gotMethod, gotPath string
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath = r.Method, r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		_, _ = w.Write([]byte(`{"deployment": {"id": "dep-4", "phase": "PENDING_BUILD"}}`))
+	}))
+	defer server.Close()
+
+	deployer := &DODeployer{Token: "test", client: server.Client(), baseURL: server.URL}
+
+	pending := &DOApp{ID: "a1", PendingDeployment: &DODeployment{ID: "dep-3"}}
+	if dep, err := deployer.StartedDeployment(pending); err != nil || dep.ID != "dep-3" {
+		t.Errorf("Expected the pending deployment, got %+v, %v", dep, err)
+	}
+	if gotPath != "" {
+		t.Errorf("Expected no request for a started deployment, got %s %s", gotMethod, gotPath)
+	}
+
+	// An unchanged spec starts nothing, so a deployment is created rather
+	// than an older one picked up
+	unchanged := &DOApp{ID: "a1", ActiveDeployment: DODeployment{ID: "dep-1"}}
+	dep, err := deployer.StartedDeployment(unchanged)
+	if err != nil || dep == nil || dep.ID != "dep-4" {
+		t.Fatalf("Expected the created deployment, got %+v, %v", dep, err)
+	}
+	if gotMethod != "POST" || gotPath != "/apps/a1/deployments" || payload["force_build"] != true {
+		t.Errorf("Unexpected request: %s %s %v", gotMethod, gotPath, payload)
+	}
+}
+
+func TestDOWaitForDeploymentID(t *testing.T) {
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = time.Millisecond
+
+	tests := []struct {
+		name    string
+		phases  []string
+		wantErr string
+	}{
+		{name: "Deployment goes live", phases: []string{"BUILDING", "DEPLOYING", "ACTIVE"}},
+		// The app then has no in-progress deployment and keeps the old
+		// one active, so only the deployment itself shows the failure
+		{name: "Deployment fails", phases: []string{"BUILDING", "ERROR"}, wantErr: "ERROR"},
+		{name: "Deployment canceled", phases: []string{"CANCELED"}, wantErr: "CANCELED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/apps/app1":
+					_, _ = w.Write([]byte(`{"app": {"id": "app1", "active_deployment": {"id": "old", "phase": "ACTIVE"}}}`))
+				case "/apps/app1/deployments/new":
+					phase := tt.phases[len(tt.phases)-1]
+					if calls < len(tt.phases) {
+						phase = tt.phases[calls]
+					}
+					calls++
+					_, _ = w.Write([]byte(`{"deployment": {"id": "new", "phase": "` + phase + `"}}`))
+				default:
+					http.Error(w, "Not found", http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			deployer := &DODeployer{Token: "test", client: server.Client(), baseURL: server.URL}
+			err := deployer.WaitForDeploymentID("app1", "new", time.Minute)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("WaitForDeploymentID() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected a %s failure, got %v", tt.wantErr, err)
+			}
+			if calls != len(tt.phases) {
+				t.Errorf("Expected polling to stop after %d calls, got %d", len(tt.phases), calls)
+			}
+		})
+	}
+}
+
+func TestPreviousJob(t *testing.T) {
+	jobs := []AmplifyJobSummary{
+		{JobID: "5", Status: "FAILED"},
+		{JobID: "4", Status: "SUCCEED"},
+		{JobID: "3", Status: "CANCELLED"},
+		{JobID: "2", Status: "SUCCEED"},
+	}
+
+	prev := PreviousJob(jobs)
+	if prev == nil || prev.JobID != "2" {
+		t.Errorf("Expected job 2, got %+v", prev)
+	}
+
+	if prev := PreviousJob(jobs[:2]); prev != nil {
+		t.Errorf("Expected no previous job, got %+v", prev)
+	}
+}
+
+func TestAWSRetryJob(t *testing.T) {
+	var payload map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		_, _ = w.Write([]byte(`{"jobSummary": {"jobId": "9"}}`))
+	}))
+	defer server.Close()
+
+	deployer := &AWSDeployer{Region: "us-east-1", client: server.Client(), endpoint: server.URL}
+
+	jobID, err := deployer.RetryJob("app1", "main", "2")
+	if err != nil {
+		t.Fatalf("RetryJob() error: %v", err)
+	}
+	if jobID != "9" {
+		t.Errorf("Expected job 9, got %s", jobID)
+	}
+	if payload["jobType"] != "RETRY" || payload["jobId"] != "2" {
+		t.Errorf("Unexpected payload: %v", payload)
+	}
+}
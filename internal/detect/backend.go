@@ -0,0 +1,71 @@
+package detect
+
+import "path/filepath"
+
+// Backend is an API server kept in its own directory beside the frontend
+type Backend struct {
+	Dir            string // relative to the project root
+	Framework      string // express, fastify, koa, hono or nestjs
+	BuildCommand   string
+	RunCommand     string
+	WorkerCommand  string // background worker, from a "worker" script
+	MigrateCommand string // run before each deploy, from a "migrate" script
+}
+
+// backendDirs are the directories searched for an API server, in order
+var backendDirs = []string{"server", "api", "backend"}
+
+// serverFrameworks maps the dependencies of Node server frameworks to their names
+var serverFrameworks = []struct {
+	dependency string
+	name       string
+}{
+	{"@nestjs/core", "nestjs"},
+	{"express", "express"},
+	{"fastify", "fastify"},
+	{"koa", "koa"},
+	{"hono", "hono"},
+}
+
+// DetectBackend finds an API server in server/, api/ or backend/: a
+// directory with its own package.json depending on a Node server framework
+func DetectBackend(root string) *Backend {
+	for _, dir := range backendDirs {
+		pkg, err := readPackageJSON(filepath.Join(root, dir))
+		if err != nil {
+			continue
+		}
+
+		framework := ""
+		for _, fw := range serverFrameworks {
+			if _, ok := pkg.Dependencies[fw.dependency]; ok {
+				framework = fw.name
+				break
+			}
+		}
+		if framework == "" {
+			continue
+		}
+
+		b := &Backend{Dir: dir, Framework: framework}
+		if _, ok := pkg.Scripts["build"]; ok {
+			b.BuildCommand = "npm run build"
+		}
+		switch {
+		case pkg.Scripts["start"] != "":
+			b.RunCommand = "npm start"
+		case pkg.Main != "":
+			b.RunCommand = "node " + pkg.Main
+		default:
+			b.RunCommand = "node index.js"
+		}
+		if _, ok := pkg.Scripts["worker"]; ok {
+			b.WorkerCommand = "npm run worker"
+		}
+		if _, ok := pkg.Scripts["migrate"]; ok {
+			b.MigrateCommand = "npm run migrate"
+		}
+		return b
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package detect
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectBackend(t *testing.T) {
+	tests := []struct {
+		name     string
+		dir      string
+		pkg      string
+		expected *Backend
+	}{
+		{
+			name: "Express API with worker and migrations",
+			dir:  "server",
+			pkg:  `{"scripts": {"start": "node dist/index.js", "build": "tsc", "worker": "node dist/worker.js", "migrate": "knex migrate:latest"}, "dependencies": {"express": "^4.18.0"}}`,
+			expected: &Backend{
+				Dir: "server", Framework: "express", BuildCommand: "npm run build", RunCommand: "npm start",
+				WorkerCommand: "npm run worker", MigrateCommand: "npm run migrate",
+			},
+		},
+		{
+			name:     "Fastify API without a start script",
+			dir:      "api",
+			pkg:      `{"main": "src/server.js", "dependencies": {"fastify": "^4.0.0"}}`,
+			expected: &Backend{Dir: "api", Framework: "fastify", RunCommand: "node src/server.js"},
+		},
+		{
+			name: "Package without a server framework",
+			dir:  "server",
+			pkg:  `{"dependencies": {"lodash": "^4.0.0"}}`,
+		},
+		{
+			name: "Unrelated directory",
+			dir:  "scripts",
+			pkg:  `{"dependencies": {"express": "^4.18.0"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			dir := filepath.Join(tmpDir, tt.dir)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(tt.pkg), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			result := DetectBackend(tmpDir)
+			switch {
+			case tt.expected == nil && result != nil:
+				t.Errorf("Expected no backend, got %+v", result)
+			case tt.expected != nil && (result == nil || *result != *tt.expected):
+				t.Errorf("Expected %+v, got %+v", tt.expected, result)
+			}
+		})
+	}
+}
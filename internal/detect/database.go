@@ -0,0 +1,119 @@
+package detect
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ORM represents a database toolkit used by the project
+type ORM string
+
+const (
+	// Prisma represents the Prisma ORM
+	Prisma ORM = "prisma"
+	// Drizzle represents the Drizzle ORM
+	Drizzle ORM = "drizzle"
+)
+
+// Database describes the database a project needs
+type Database struct {
+	Engine         string // PG or MYSQL
+	ORM            ORM    // empty when a driver is used directly
+	Dir            string // package using the database, relative to the project root
+	EnvVar         string // env var holding the connection string
+	MigrateCommand string // applies pending migrations, when the ORM has one
+}
+
+// databaseDrivers maps driver dependencies to App Platform database engines
+var databaseDrivers = []struct {
+	dependency string
+	engine     string
+}{
+	{"pg", "PG"},
+	{"postgres", "PG"},
+	{"@neondatabase/serverless", "PG"},
+	{"mysql2", "MYSQL"},
+	{"mysql", "MYSQL"},
+}
+
+// Prisma schema datasource settings; generator blocks also have a provider
+var (
+	prismaProvider = regexp.MustCompile(`datasource\s+\w+\s*\{[^}]*provider\s*=\s*"(\w+)"`)
+	prismaURLEnv   = regexp.MustCompile(`datasource\s+\w+\s*\{[^}]*url\s*=\s*env\("(\w+)"\)`)
+)
+
+// DetectDatabase finds the database used by the project or its API server,
+// from a Prisma schema, a Drizzle or driver dependency, or a DATABASE_URL in
+// .env.example. It returns nil when no database is used.
+func DetectDatabase(root string) *Database {
+	for _, dir := range append([]string{""}, backendDirs...) {
+		if db := detectDatabaseIn(root, dir); db != nil {
+			return db
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(root, ".env.example")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.HasPrefix(strings.TrimSpace(line), "DATABASE_URL=") {
+				return &Database{Engine: "PG", EnvVar: "DATABASE_URL"}
+			}
+		}
+	}
+	return nil
+}
+
+// detectDatabaseIn checks a single package for an ORM or database driver
+func detectDatabaseIn(root, dir string) *Database {
+	path := filepath.Join(root, dir)
+	pkg, err := readPackageJSON(path)
+	if err != nil {
+		return nil
+	}
+	deps := make(map[string]bool)
+	for name := range pkg.Dependencies {
+		deps[name] = true
+	}
+	for name := range pkg.DevDependencies {
+		deps[name] = true
+	}
+
+	db := &Database{Dir: dir, EnvVar: "DATABASE_URL"}
+	for _, driver := range databaseDrivers {
+		if deps[driver.dependency] {
+			db.Engine = driver.engine
+			break
+		}
+	}
+
+	schema, err := os.ReadFile(filepath.Join(path, "prisma", "schema.prisma"))
+	switch {
+	case err == nil || deps["prisma"] || deps["@prisma/client"]:
+		db.ORM = Prisma
+		db.Engine = "PG"
+		if m := prismaProvider.FindSubmatch(schema); m != nil {
+			switch string(m[1]) {
+			case "mysql":
+				db.Engine = "MYSQL"
+			case "postgresql", "postgres":
+			default:
+				// SQLite, MongoDB and others have no App Platform engine
+				return nil
+			}
+		}
+		if m := prismaURLEnv.FindSubmatch(schema); m != nil {
+			db.EnvVar = string(m[1])
+		}
+		db.MigrateCommand = "npx prisma migrate deploy"
+	case deps["drizzle-orm"]:
+		db.ORM = Drizzle
+		if db.Engine == "" {
+			db.Engine = "PG"
+		}
+		db.MigrateCommand = "npx drizzle-kit migrate"
+	case db.Engine == "":
+		return nil
+	}
+	return db
+}
@@ -0,0 +1,79 @@
+package detect
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectDatabase(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected *Database
+	}{
+		{
+			name: "Prisma schema with MySQL and a custom env var",
+			files: map[string]string{
+				"package.json":         `{"dependencies": {"@prisma/client": "^5.0.0"}}`,
+				"prisma/schema.prisma": "generator client {\n  provider = \"prisma-client-js\"\n}\n\ndatasource db {\n  provider = \"mysql\"\n  url      = env(\"PRIMARY_DB\")\n}\n",
+			},
+			expected: &Database{Engine: "MYSQL", ORM: Prisma, EnvVar: "PRIMARY_DB", MigrateCommand: "npx prisma migrate deploy"},
+		},
+		{
+			name: "Drizzle in the API server",
+			files: map[string]string{
+				"package.json":        `{"devDependencies": {"vite": "^5.0.0"}}`,
+				"server/package.json": `{"dependencies": {"drizzle-orm": "^0.30.0", "postgres": "^3.4.0"}}`,
+			},
+			expected: &Database{Engine: "PG", ORM: Drizzle, Dir: "server", EnvVar: "DATABASE_URL", MigrateCommand: "npx drizzle-kit migrate"},
+		},
+		{
+			name:     "Plain mysql2 driver",
+			files:    map[string]string{"package.json": `{"dependencies": {"mysql2": "^3.0.0"}}`},
+			expected: &Database{Engine: "MYSQL", EnvVar: "DATABASE_URL"},
+		},
+		{
+			name: "DATABASE_URL in .env.example",
+			files: map[string]string{
+				"package.json": `{"dependencies": {}}`,
+				".env.example": "PORT=3000\nDATABASE_URL=postgres://localhost/app\n",
+			},
+			expected: &Database{Engine: "PG", EnvVar: "DATABASE_URL"},
+		},
+		{
+			name: "Prisma with SQLite",
+			files: map[string]string{
+				"package.json":         `{"devDependencies": {"prisma": "^5.0.0"}}`,
+				"prisma/schema.prisma": "datasource db {\n  provider = \"sqlite\"\n  url      = \"file:./dev.db\"\n}\n",
+			},
+		},
+		{
+			name:  "No database",
+			files: map[string]string{"package.json": `{"dependencies": {"react": "^18.0.0"}}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(tmpDir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result := DetectDatabase(tmpDir)
+			switch {
+			case tt.expected == nil && result != nil:
+				t.Errorf("Expected no database, got %+v", result)
+			case tt.expected != nil && (result == nil || *result != *tt.expected):
+				t.Errorf("Expected %+v, got %+v", tt.expected, result)
+			}
+		})
+	}
+}
@@ -53,6 +53,8 @@ type Detection struct {
 	NodeVersion    string
 	BuildCommand   string
 	OutputDir      string
+	Backend        *Backend  // nil when the project has no separate API server
+	Database       *Database // nil when the project uses no database
 	Issues         []Issue
 }
 
@@ -61,10 +63,22 @@ type Issue struct {
 	Code        string
 	Description string
 	Fixable     bool
+	Severity    Severity
 }
 
+// Severity is how much an issue matters for a deploy
+type Severity string
+
+const (
+	// SeverityWarning issues are reported but do not block a deploy
+	SeverityWarning Severity = "warning"
+	// SeverityError issues block a deploy unless it is forced
+	SeverityError Severity = "error"
+)
+
 type packageJSON struct {
 	Name            string            `json:"name"`
+	Main            string            `json:"main"`
 	Scripts         map[string]string `json:"scripts"`
 	Dependencies    map[string]string `json:"dependencies"`
 	DevDependencies map[string]string `json:"devDependencies"`
@@ -87,6 +101,7 @@ func DetectAll(root string) (*Detection, error) {
 			Code:        "UNKNOWN_FRAMEWORK",
 			Description: "Could not detect framework",
 			Fixable:     false,
+			Severity:    SeverityError,
 		})
 	} else {
 		d.Framework = fw
@@ -102,6 +117,7 @@ func DetectAll(root string) (*Detection, error) {
 			Code:        "NODE_NOT_PINNED",
 			Description: "Node version not pinned",
 			Fixable:     true,
+			Severity:    SeverityWarning,
 		})
 	}
 
@@ -111,6 +127,12 @@ func DetectAll(root string) (*Detection, error) {
 	// Detect output type
 	d.OutputType = DetectOutputType(root, d.Framework)
 
+	// Detect an API server beside the frontend
+	d.Backend = DetectBackend(root)
+
+	// Detect the database the app or its API needs
+	d.Database = DetectDatabase(root)
+
 	// Check for missing files
 	d.Issues = append(d.Issues, CheckMissingFiles(root)...)
 
@@ -247,6 +269,7 @@ func CheckMissingFiles(root string) []Issue {
 				Code:        c.code,
 				Description: c.description,
 				Fixable:     true,
+				Severity:    SeverityWarning,
 			})
 		}
 	}
@@ -254,6 +277,20 @@ func CheckMissingFiles(root string) []Issue {
 	return issues
 }
 
+// DeployIssues returns the detected issues with the severity they have when
+// deploying to target: an SSR app on DigitalOcean is built from its Dockerfile
+func DeployIssues(d *Detection, target string) []Issue {
+	issues := make([]Issue, 0, len(d.Issues))
+	for _, issue := range d.Issues {
+		if issue.Code == "MISSING_DOCKERFILE" && target == "do" && d.OutputType == SSR {
+			issue.Severity = SeverityError
+			issue.Description = "Missing Dockerfile, needed for SSR on DigitalOcean"
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
 // Helper functions
 
 func fileExists(path string) bool {
@@ -204,3 +204,34 @@ func TestDetectOutputType(t *testing.T) {
 		})
 	}
 }
+
+func TestDeployIssues(t *testing.T) {
+	missing := []Issue{
+		{Code: "MISSING_DOCKERFILE", Description: "Missing Dockerfile", Severity: SeverityWarning},
+		{Code: "NODE_NOT_PINNED", Description: "Node version not pinned", Severity: SeverityWarning},
+	}
+
+	tests := []struct {
+		name       string
+		outputType OutputType
+		target     string
+		expected   Severity
+	}{
+		{name: "SSR on DigitalOcean needs a Dockerfile", outputType: SSR, target: "do", expected: SeverityError},
+		{name: "Static site on DigitalOcean", outputType: Static, target: "do", expected: SeverityWarning},
+		{name: "SSR on Amplify", outputType: SSR, target: "aws", expected: SeverityWarning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Detection{OutputType: tt.outputType, Issues: missing}
+			issues := DeployIssues(d, tt.target)
+			if issues[0].Severity != tt.expected || issues[1].Severity != SeverityWarning {
+				t.Errorf("Unexpected severities: %+v", issues)
+			}
+			if d.Issues[0].Severity != SeverityWarning {
+				t.Error("Expected the detection to be left unmodified")
+			}
+		})
+	}
+}
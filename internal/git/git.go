@@ -0,0 +1,107 @@
+// Package git reads the state of the local repository that mvpbridge
+// deploys: uncommitted changes, the HEAD commit and what has been pushed.
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Repo runs git commands in a working tree
+type Repo struct {
+	Dir string
+	ctx context.Context
+}
+
+// Open returns a Repo for the working tree at dir. Commands run under ctx.
+func Open(ctx context.Context, dir string) *Repo {
+	return &Repo{Dir: dir, ctx: ctx}
+}
+
+// run executes git with args and returns its trimmed output
+func (r *Repo) run(args ...string) (string, error) {
+	out, err := r.output(args...)
+	return strings.TrimSpace(out), err
+}
+
+// output executes git with args and returns its output
+func (r *Repo) output(args ...string) (string, error) {
+	// #nosec G204 - arguments are git subcommands and refs, not shell input
+	cmd := exec.CommandContext(r.ctx, "git", args...)
+	cmd.Dir = r.Dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s (%w)", args[0], msg, err)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
+
+// Changes returns the tracked files with uncommitted changes. Untracked
+// files are not deployed, so they are not reported.
+func (r *Repo) Changes() ([]string, error) {
+	// Porcelain lines start with a two-letter status that may be blank
+	out, err := r.output("status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) > 3 {
+			files = append(files, line[3:])
+		}
+	}
+	return files, nil
+}
+
+// Config returns the value of a git config key
+func (r *Repo) Config(key string) (string, error) {
+	return r.run("config", "--get", key)
+}
+
+// Head returns the SHA of the checked-out commit
+func (r *Repo) Head() (string, error) {
+	return r.run("rev-parse", "HEAD")
+}
+
+// RemoteBranch returns the SHA a branch points to on a remote, asking the
+// remote rather than trusting local tracking refs. It returns "" when the
+// remote has no such branch.
+func (r *Repo) RemoteBranch(remote, branch string) (string, error) {
+	out, err := r.run("ls-remote", "--heads", remote, "refs/heads/"+branch)
+	if err != nil {
+		return "", err
+	}
+	if fields := strings.Fields(out); len(fields) > 0 {
+		return fields[0], nil
+	}
+	return "", nil
+}
+
+// IsAncestor reports whether commit is reachable from descendant. It fails
+// when either commit is not in the local repository.
+func (r *Repo) IsAncestor(commit, descendant string) (bool, error) {
+	if commit == descendant {
+		return true, nil
+	}
+
+	_, err := r.run("merge-base", "--is-ancestor", commit, descendant)
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		return false, nil
+	}
+	return false, err
+}
@@ -0,0 +1,106 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// newTestRepo creates a repository with one commit and a bare remote named
+// origin that has it on main
+func newTestRepo(t *testing.T) *Repo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	root := t.TempDir()
+	remote := filepath.Join(root, "remote.git")
+	dir := filepath.Join(root, "work")
+	repo := Open(context.Background(), root)
+
+	mustRun(t, repo, "init", "--bare", remote)
+	mustRun(t, repo, "init", dir)
+	repo.Dir = dir
+	mustRun(t, repo, "config", "user.email", "test@example.com")
+	mustRun(t, repo, "config", "user.name", "Test")
+	mustRun(t, repo, "remote", "add", "origin", remote)
+	commitFile(t, repo, "index.html", "hello")
+	mustRun(t, repo, "push", "origin", "HEAD:refs/heads/main")
+	return repo
+}
+
+func mustRun(t *testing.T, r *Repo, args ...string) string {
+	t.Helper()
+	out, err := r.run(args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func commitFile(t *testing.T, r *Repo, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(r.Dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mustRun(t, r, "add", name)
+	mustRun(t, r, "commit", "-m", "Update "+name)
+}
+
+func TestChanges(t *testing.T) {
+	repo := newTestRepo(t)
+
+	if changes, err := repo.Changes(); err != nil || len(changes) != 0 {
+		t.Fatalf("Expected a clean tree, got %v, %v", changes, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(repo.Dir, "untracked.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo.Dir, "index.html"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := repo.Changes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0] != "index.html" {
+		t.Errorf("Expected only the tracked change, got %v", changes)
+	}
+}
+
+func TestRemoteBranchAndIsAncestor(t *testing.T) {
+	repo := newTestRepo(t)
+
+	pushed, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := repo.RemoteBranch("origin", "main")
+	if err != nil || remote != pushed {
+		t.Fatalf("RemoteBranch() = %q, %v, want %q", remote, err, pushed)
+	}
+	if missing, err := repo.RemoteBranch("origin", "develop"); err != nil || missing != "" {
+		t.Errorf("Expected no SHA for a missing branch, got %q, %v", missing, err)
+	}
+
+	commitFile(t, repo, "about.html", "about")
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := repo.IsAncestor(pushed, head); err != nil || !ok {
+		t.Errorf("Expected the pushed commit to be an ancestor of HEAD, got %v, %v", ok, err)
+	}
+	if ok, err := repo.IsAncestor(head, pushed); err != nil || ok {
+		t.Errorf("Expected the unpushed HEAD not to be on the remote branch, got %v, %v", ok, err)
+	}
+	if _, err := repo.IsAncestor(head, "0123456789012345678901234567890123456789"); err == nil {
+		t.Error("Expected an error for an unknown commit")
+	}
+}
@@ -0,0 +1,172 @@
+package git
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Provider identifies the service hosting a repository
+type Provider string
+
+const (
+	// GitHub is github.com or a GitHub Enterprise server
+	GitHub Provider = "github"
+	// GitLab is gitlab.com or a self-managed GitLab
+	GitLab Provider = "gitlab"
+	// Bitbucket is bitbucket.org or a Bitbucket server
+	Bitbucket Provider = "bitbucket"
+	// CodeCommit is AWS CodeCommit
+	CodeCommit Provider = "codecommit"
+	// Other is any other Git server
+	Other Provider = "git"
+)
+
+// publicHosts are the web hosts of the hosted services
+var publicHosts = map[Provider]string{
+	GitHub:    "github.com",
+	GitLab:    "gitlab.com",
+	Bitbucket: "bitbucket.org",
+}
+
+// hostedProviders maps the hosted services' web and alternate SSH hosts
+var hostedProviders = map[string]Provider{
+	"github.com":           GitHub,
+	"ssh.github.com":       GitHub,
+	"gitlab.com":           GitLab,
+	"altssh.gitlab.com":    GitLab,
+	"bitbucket.org":        Bitbucket,
+	"altssh.bitbucket.org": Bitbucket,
+}
+
+// Remote is a parsed Git remote URL
+type Remote struct {
+	Provider Provider
+	Host     string // without user or port
+	Owner    string // user, organization or group path, e.g. group/subgroup
+	Repo     string // without the .git suffix
+}
+
+// ParseRemote parses the URL forms Git accepts for a remote: HTTPS,
+// ssh:// and scp-like user@host:path, plus host/owner/repo without a scheme
+// and AWS codecommit::region://repo URLs
+func ParseRemote(raw string) (*Remote, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("empty remote URL")
+	}
+
+	var host, path string
+	switch {
+	case strings.HasPrefix(raw, "codecommit::"):
+		return parseCodeCommit(raw)
+	case strings.Contains(raw, "://"):
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parsing remote URL %q: %w", raw, err)
+		}
+		host, path = u.Hostname(), u.Path
+	case isSCPLike(raw):
+		host, path, _ = strings.Cut(raw, ":")
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+	default:
+		host, path, _ = strings.Cut(raw, "/")
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	owner, repo, ok := cutLast(path, "/")
+	if host == "" || !ok || owner == "" || repo == "" {
+		return nil, fmt.Errorf("remote URL %q has no owner/repo path", raw)
+	}
+
+	host = strings.ToLower(host)
+	remote := &Remote{Provider: providerFor(host), Host: host, Owner: owner, Repo: repo}
+	if _, ok := hostedProviders[host]; ok {
+		remote.Host = publicHosts[remote.Provider]
+	}
+	return remote, nil
+}
+
+// isSCPLike reports whether raw is in the user@host:path form, where the
+// colon comes before any slash
+func isSCPLike(raw string) bool {
+	colon := strings.Index(raw, ":")
+	slash := strings.Index(raw, "/")
+	return colon > 0 && (slash < 0 || colon < slash)
+}
+
+// parseCodeCommit parses a git-remote-codecommit URL,
+// codecommit::<region>://[profile@]<repo>
+func parseCodeCommit(raw string) (*Remote, error) {
+	region, repo, ok := strings.Cut(strings.TrimPrefix(raw, "codecommit::"), "://")
+	if at := strings.LastIndex(repo, "@"); at >= 0 {
+		repo = repo[at+1:]
+	}
+	if !ok || region == "" || repo == "" {
+		return nil, fmt.Errorf("remote URL %q is not codecommit::<region>://<repo>", raw)
+	}
+	return &Remote{
+		Provider: CodeCommit,
+		Host:     "git-codecommit." + region + ".amazonaws.com",
+		Owner:    "v1/repos",
+		Repo:     repo,
+	}, nil
+}
+
+// providerFor guesses the provider from the host name, so GitHub Enterprise
+// and self-managed GitLab or Bitbucket servers are recognized
+func providerFor(host string) Provider {
+	if p, ok := hostedProviders[host]; ok {
+		return p
+	}
+	switch {
+	case strings.HasPrefix(host, "git-codecommit.") && strings.HasSuffix(host, ".amazonaws.com"):
+		return CodeCommit
+	case strings.Contains(host, "github"):
+		return GitHub
+	case strings.Contains(host, "gitlab"):
+		return GitLab
+	case strings.Contains(host, "bitbucket"):
+		return Bitbucket
+	}
+	return Other
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return "", s, false
+}
+
+// IsHosted reports whether the repository is on the provider's public
+// service rather than a self-managed server
+func (r *Remote) IsHosted() bool {
+	return r.Host == publicHosts[r.Provider]
+}
+
+// Path returns owner/repo
+func (r *Remote) Path() string {
+	return r.Owner + "/" + r.Repo
+}
+
+// HTTPSURL returns the repository's web and HTTPS clone URL, without .git
+func (r *Remote) HTTPSURL() string {
+	return "https://" + r.Host + "/" + r.Path()
+}
+
+// String returns the HTTPS URL of the repository
+func (r *Remote) String() string {
+	return r.HTTPSURL()
+}
+
+// RemoteURL returns the URL of a named remote
+func (r *Repo) RemoteURL(name string) (string, error) {
+	out, err := r.run("remote", "get-url", name)
+	if err != nil {
+		return "", fmt.Errorf("no git remote %q configured: %w", name, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,49 @@
+package git
+
+import "testing"
+
+func TestParseRemote(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want Remote
+	}{
+		{"https://github.com/user/repo.git", Remote{GitHub, "github.com", "user", "repo"}},
+		{"git@github.com:user/repo.git", Remote{GitHub, "github.com", "user", "repo"}},
+		{"ssh://git@ssh.github.com:443/user/repo.git", Remote{GitHub, "github.com", "user", "repo"}},
+		{"github.com/user/repo", Remote{GitHub, "github.com", "user", "repo"}},
+		{"https://token@github.example.com/org/repo/", Remote{GitHub, "github.example.com", "org", "repo"}},
+		{"git@gitlab.com:group/subgroup/repo.git", Remote{GitLab, "gitlab.com", "group/subgroup", "repo"}},
+		{"https://gitlab.internal.io/team/app", Remote{GitLab, "gitlab.internal.io", "team", "app"}},
+		{"git@bitbucket.org:team/app.git", Remote{Bitbucket, "bitbucket.org", "team", "app"}},
+		{"ssh://git@git.example.com:2222/srv/app.git", Remote{Other, "git.example.com", "srv", "app"}},
+		{"https://git-codecommit.us-east-1.amazonaws.com/v1/repos/app", Remote{CodeCommit, "git-codecommit.us-east-1.amazonaws.com", "v1/repos", "app"}},
+		{"codecommit::eu-west-1://deploy@app", Remote{CodeCommit, "git-codecommit.eu-west-1.amazonaws.com", "v1/repos", "app"}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRemote(tt.raw)
+		if err != nil {
+			t.Errorf("ParseRemote(%q) error: %v", tt.raw, err)
+			continue
+		}
+		if *got != tt.want {
+			t.Errorf("ParseRemote(%q) = %+v, want %+v", tt.raw, *got, tt.want)
+		}
+	}
+
+	for _, raw := range []string{"", "https://github.com/repo", "/srv/git/app", "codecommit::us-east-1"} {
+		if _, err := ParseRemote(raw); err == nil {
+			t.Errorf("ParseRemote(%q) expected an error", raw)
+		}
+	}
+}
+
+func TestRemoteIsHosted(t *testing.T) {
+	hosted := &Remote{Provider: GitLab, Host: "gitlab.com", Owner: "group", Repo: "app"}
+	if !hosted.IsHosted() || hosted.HTTPSURL() != "https://gitlab.com/group/app" {
+		t.Errorf("Unexpected hosted remote: %v", hosted)
+	}
+	if (&Remote{Provider: GitHub, Host: "github.example.com"}).IsHosted() {
+		t.Error("Expected GitHub Enterprise not to be hosted")
+	}
+}
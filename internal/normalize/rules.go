@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"mvpbridge/internal/buildspec"
 	"mvpbridge/internal/detect"
 )
 
@@ -51,6 +52,14 @@ func New(root string, fw detect.Framework, dryRun bool) *Normalizer {
 	return n
 }
 
+// WithTarget adds the rules specific to a deployment target
+func (n *Normalizer) WithTarget(target string) *Normalizer {
+	if target == "aws" {
+		n.Rules = append(n.Rules, awsRules()...)
+	}
+	return n
+}
+
 // Run executes all normalization rules in sequence
 func (n *Normalizer) Run() error {
 	for i, rule := range n.Rules {
@@ -198,6 +207,24 @@ func nextjsRules() []Rule {
 	}
 }
 
+// AWS-specific rules
+
+func awsRules() []Rule {
+	return []Rule{
+		{
+			Name:        "Add amplify.yml",
+			Description: "Add Amplify build spec",
+			Check:       amplifyBuildSpecComplete,
+			Apply: func(root string, dryRun bool) error {
+				if dryRun {
+					return nil
+				}
+				return writeAmplifyBuildSpec(root)
+			},
+		},
+	}
+}
+
 // Helper functions
 
 func fileExists(path string) bool {
@@ -303,6 +330,50 @@ func updateGitignore(root string) error {
 	return os.WriteFile(path, []byte(content), 0600)
 }
 
+// amplifyBuildSpec merges the detected build settings into the project's
+// amplify.yml, returning the merged contents
+func amplifyBuildSpec(root string) ([]byte, []buildspec.Conflict, error) {
+	current, err := os.ReadFile(filepath.Join(root, buildspec.AmplifyFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+
+	d, err := detect.DetectAll(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	return buildspec.Merge(current, buildspec.SettingsFor(d, d.OutputType == detect.Static))
+}
+
+// amplifyBuildSpecComplete reports whether amplify.yml exists and already has
+// every required setting. Formatting differences are ignored.
+func amplifyBuildSpecComplete(root string) bool {
+	current, err := os.ReadFile(filepath.Join(root, buildspec.AmplifyFile))
+	if err != nil {
+		return false
+	}
+
+	d, err := detect.DetectAll(root)
+	if err != nil {
+		return false
+	}
+	complete, _, err := buildspec.Complete(current, buildspec.SettingsFor(d, d.OutputType == detect.Static))
+	return err == nil && complete
+}
+
+// writeAmplifyBuildSpec creates amplify.yml or adds the missing settings to
+// it, keeping conflicting values
+func writeAmplifyBuildSpec(root string) error {
+	merged, conflicts, err := amplifyBuildSpec(root)
+	if err != nil {
+		return err
+	}
+	for _, c := range conflicts {
+		fmt.Printf("      → Kept: %s\n", c)
+	}
+	return os.WriteFile(filepath.Join(root, buildspec.AmplifyFile), merged, 0600)
+}
+
 func createGitHubWorkflow(root string) error {
 	dir := filepath.Join(root, ".github", "workflows")
 	if err := os.MkdirAll(dir, 0755); err != nil {
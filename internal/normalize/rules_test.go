@@ -462,3 +462,63 @@ func TestFileExists(t *testing.T) {
 		t.Error("fileExists should return false for non-existent file")
 	}
 }
+
+func TestWithTarget(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if n := New(tmpDir, detect.Vite, false).WithTarget("do"); len(n.Rules) != 6 {
+		t.Errorf("Expected no extra rules for do, got %d rules", len(n.Rules))
+	}
+
+	n := New(tmpDir, detect.Vite, false).WithTarget("aws")
+	if len(n.Rules) != 7 || n.Rules[6].Name != "Add amplify.yml" {
+		t.Errorf("Expected amplify.yml rule last for aws, got %d rules", len(n.Rules))
+	}
+}
+
+func TestAmplifyBuildSpecRule(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"package.json":   `{"scripts": {"build": "vite build"}, "devDependencies": {"vite": "^5.0.0"}}`,
+		".nvmrc":         "20\n",
+		"vite.config.ts": "export default {}\n",
+		"amplify.yml": `version: 1
+frontend:
+  phases:
+    build:
+      commands:
+        - npm test
+        - npm run build
+  artifacts:
+    baseDirectory: dist
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	rule := awsRules()[0]
+	if rule.Check(tmpDir) {
+		t.Error("Expected Check to return false when amplify.yml lacks required settings")
+	}
+
+	if err := rule.Apply(tmpDir, false); err != nil {
+		t.Fatalf("Failed to apply rule: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "amplify.yml"))
+	if err != nil {
+		t.Fatalf("Failed to read amplify.yml: %v", err)
+	}
+	for _, want := range []string{"- nvm install 20", "- npm ci", "- npm test", "- '**/*'"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected amplify.yml to contain %q, got:\n%s", want, data)
+		}
+	}
+
+	if !rule.Check(tmpDir) {
+		t.Error("Expected Check to return true after applying rule")
+	}
+}
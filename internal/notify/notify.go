@@ -0,0 +1,290 @@
+// Package notify sends deploy events to webhooks, as a generic JSON payload
+// or in the message formats Slack and Discord incoming webhooks accept.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultRetries is how many times a failed delivery is retried
+	DefaultRetries = 3
+	// DefaultInterval is the wait before the first retry; it doubles after
+	// each attempt
+	DefaultInterval = time.Second
+	// logExcerptLines is how many trailing log lines a failure event carries
+	logExcerptLines = 20
+)
+
+// Format is the payload shape a webhook expects
+type Format string
+
+const (
+	// Generic posts the Event as JSON
+	Generic Format = "generic"
+	// Slack posts a Slack-compatible {"text": ...} message
+	Slack Format = "slack"
+	// Discord posts a Discord-compatible message with an embed
+	Discord Format = "discord"
+)
+
+// EventType is what happened to a deploy
+type EventType string
+
+const (
+	// Start is sent when a deploy begins
+	Start EventType = "start"
+	// Success is sent when a deploy finishes
+	Success EventType = "success"
+	// Failure is sent when a deploy fails
+	Failure EventType = "failure"
+	// Rollback is sent when an app is rolled back, by hand or after failed
+	// smoke tests
+	Rollback EventType = "rollback"
+)
+
+// Results of a finished deploy or rollback
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// EventTypes lists every event type
+var EventTypes = []EventType{Start, Success, Failure, Rollback}
+
+// Webhook is an endpoint notified of deploy events
+type Webhook struct {
+	URL    string      `yaml:"url"`
+	Format Format      `yaml:"format,omitempty"` // generic (default), slack or discord
+	Events []EventType `yaml:"events,omitempty"` // defaults to all events
+}
+
+// Wants reports whether the webhook is subscribed to an event type
+func (w Webhook) Wants(t EventType) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Event describes a deploy or rollback
+type Event struct {
+	Type         EventType `json:"event"`
+	App          string    `json:"app"`
+	Target       string    `json:"target"`
+	Preview      string    `json:"preview,omitempty"`
+	URL          string    `json:"url,omitempty"`
+	Commit       string    `json:"commit,omitempty"`
+	DeploymentID string    `json:"deployment_id,omitempty"`
+	User         string    `json:"user,omitempty"`
+	DurationMS   int64     `json:"duration_ms,omitempty"`
+	Result       string    `json:"result,omitempty"` // success or failure, once finished
+	Error        string    `json:"error,omitempty"`
+	Logs         string    `json:"logs,omitempty"` // failures: the end of the build log
+	Time         time.Time `json:"time"`
+}
+
+// Summary returns a one-line description of the event
+func (e Event) Summary() string {
+	app := e.App
+	if e.Preview != "" {
+		app += " preview " + e.Preview
+	}
+
+	var s string
+	switch e.Type {
+	case Start:
+		s = fmt.Sprintf("🚀 Deploying %s to %s", app, e.Target)
+	case Success:
+		s = fmt.Sprintf("✅ Deployed %s to %s", app, e.Target)
+	case Failure:
+		s = fmt.Sprintf("❌ Deploy of %s to %s failed", app, e.Target)
+	case Rollback:
+		s = fmt.Sprintf("↩️ Rolled back %s on %s", app, e.Target)
+		if e.Result == ResultFailure {
+			s = fmt.Sprintf("❌ Rollback of %s on %s failed", app, e.Target)
+		}
+	default:
+		s = fmt.Sprintf("%s: %s on %s", e.Type, app, e.Target)
+	}
+
+	if e.Commit != "" {
+		s += " at " + shortSHA(e.Commit)
+	}
+	if e.DurationMS > 0 {
+		s += " in " + (time.Duration(e.DurationMS) * time.Millisecond).Round(time.Second).String()
+	}
+	return s
+}
+
+// details returns the event's URL, deployment, error and logs as lines of
+// Markdown, which Slack and Discord both render
+func (e Event) details() string {
+	var lines []string
+	if e.URL != "" {
+		lines = append(lines, e.URL)
+	}
+	if e.DeploymentID != "" {
+		lines = append(lines, "Deployment: "+e.DeploymentID)
+	}
+	if e.User != "" {
+		lines = append(lines, "By: "+e.User)
+	}
+	if e.Error != "" {
+		lines = append(lines, "Error: "+e.Error)
+	}
+	if e.Logs != "" {
+		lines = append(lines, "```\n"+e.Logs+"\n```")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Payload returns the request body for a webhook format
+func Payload(format Format, e Event) ([]byte, error) {
+	switch format {
+	case "", Generic:
+		return json.Marshal(e)
+	case Slack:
+		text := e.Summary()
+		if details := e.details(); details != "" {
+			text += "\n" + details
+		}
+		return json.Marshal(map[string]string{"text": text})
+	case Discord:
+		color := 0x2ecc71
+		switch {
+		case e.Type == Failure || e.Result == ResultFailure:
+			color = 0xe74c3c
+		case e.Type == Start || e.Type == Rollback:
+			color = 0x3498db
+		}
+		embed := map[string]interface{}{
+			"title":       e.Summary(),
+			"description": truncate(e.details(), 4000),
+			"color":       color,
+			"timestamp":   e.Time.Format(time.RFC3339),
+		}
+		if e.URL != "" {
+			embed["url"] = e.URL
+		}
+		return json.Marshal(map[string]interface{}{
+			"username": "mvpbridge",
+			"embeds":   []interface{}{embed},
+		})
+	}
+	return nil, fmt.Errorf("unknown webhook format: %s (supported: generic, slack, discord)", format)
+}
+
+// Notifier delivers events to webhooks, retrying failed deliveries
+type Notifier struct {
+	Client   *http.Client
+	Retries  int
+	Interval time.Duration
+}
+
+// New creates a notifier, applying defaults for zero values
+func New(retries int) *Notifier {
+	if retries <= 0 {
+		retries = DefaultRetries
+	}
+	return &Notifier{
+		Client:   &http.Client{Timeout: 10 * time.Second},
+		Retries:  retries,
+		Interval: DefaultInterval,
+	}
+}
+
+// Send delivers an event to one webhook. Network errors, 429s and 5xx
+// responses are retried with exponential backoff.
+func (n *Notifier) Send(ctx context.Context, w Webhook, e Event) error {
+	body, err := Payload(w.Format, e)
+	if err != nil {
+		return err
+	}
+
+	wait := n.Interval
+	for attempt := 0; ; attempt++ {
+		retry, err := n.post(ctx, w.URL, body)
+		if err == nil || !retry || attempt >= n.Retries {
+			return err
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+		wait *= 2
+	}
+}
+
+// post sends one request, reporting whether a failure is worth retrying
+func (n *Notifier) post(ctx context.Context, url string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		// Webhook URLs carry their secret in the path, so only the host is
+		// named in errors
+		var urlErr *neturl.Error
+		if errors.As(err, &urlErr) {
+			err = fmt.Errorf("posting to %s: %w", req.URL.Host, urlErr.Err)
+		}
+		return ctx.Err() == nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode >= 300 {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return false, nil
+}
+
+// LogExcerpt returns the last lines of a log, for failure events
+func LogExcerpt(logs string) string {
+	lines := strings.Split(strings.TrimRight(logs, "\n"), "\n")
+	if len(lines) > logExcerptLines {
+		lines = lines[len(lines)-logExcerptLines:]
+	}
+	return truncate(strings.Join(lines, "\n"), 2000)
+}
+
+// truncate shortens s to at most n bytes, keeping its end, which holds the
+// most recent log lines
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	start := len(s) - n
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	return "…" + s[start:]
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,149 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testEvent = Event{
+	Type:       Failure,
+	App:        "shop",
+	Target:     "do",
+	URL:        "https://shop.ondigitalocean.app",
+	Commit:     "0123456789abcdef",
+	DurationMS: 95000,
+	Result:     ResultFailure,
+	Error:      "deployment failed: BUILD phase ERROR",
+	Logs:       "npm ERR! missing script: build",
+	Time:       time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC),
+}
+
+func TestPayload(t *testing.T) {
+	if got := testEvent.Summary(); got != "❌ Deploy of shop to do failed at 0123456 in 1m35s" {
+		t.Errorf("Summary() = %q", got)
+	}
+
+	body, err := Payload(Generic, testEvent)
+	if err != nil {
+		t.Fatalf("Payload(generic) error: %v", err)
+	}
+	var generic Event
+	if err := json.Unmarshal(body, &generic); err != nil || generic.Commit != testEvent.Commit || generic.Type != Failure {
+		t.Errorf("Unexpected generic payload %s (%v)", body, err)
+	}
+
+	body, err = Payload(Slack, testEvent)
+	if err != nil {
+		t.Fatalf("Payload(slack) error: %v", err)
+	}
+	var slack map[string]string
+	_ = json.Unmarshal(body, &slack)
+	if !strings.HasPrefix(slack["text"], "❌ Deploy of shop") || !strings.Contains(slack["text"], "```\nnpm ERR!") {
+		t.Errorf("Unexpected slack payload: %s", body)
+	}
+
+	body, err = Payload(Discord, testEvent)
+	if err != nil {
+		t.Fatalf("Payload(discord) error: %v", err)
+	}
+	var discord struct {
+		Embeds []struct {
+			Title string `json:"title"`
+			URL   string `json:"url"`
+			Color int    `json:"color"`
+		} `json:"embeds"`
+	}
+	_ = json.Unmarshal(body, &discord)
+	if len(discord.Embeds) != 1 || discord.Embeds[0].URL != testEvent.URL || discord.Embeds[0].Color != 0xe74c3c {
+		t.Errorf("Unexpected discord payload: %s", body)
+	}
+
+	rollback := Event{Type: Rollback, App: "shop", Target: "aws", Result: ResultSuccess}
+	if got := rollback.Summary(); got != "↩️ Rolled back shop on aws" {
+		t.Errorf("Summary() = %q", got)
+	}
+	rollback.Result = ResultFailure
+	if got := rollback.Summary(); got != "❌ Rollback of shop on aws failed" {
+		t.Errorf("Summary() = %q", got)
+	}
+	body, _ = Payload(Generic, rollback)
+	if !strings.Contains(string(body), `"result":"failure"`) {
+		t.Errorf("Expected the rollback result in the payload: %s", body)
+	}
+
+	if _, err := Payload("teams", testEvent); err == nil {
+		t.Error("Expected error for an unknown format")
+	}
+}
+
+func TestWebhookWants(t *testing.T) {
+	if !(Webhook{}).Wants(Start) {
+		t.Error("Expected a webhook without events to want all of them")
+	}
+	w := Webhook{Events: []EventType{Failure, Rollback}}
+	if w.Wants(Success) || !w.Wants(Rollback) {
+		t.Errorf("Unexpected subscriptions for %v", w.Events)
+	}
+}
+
+func TestSendRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "Retries server errors", statuses: []int{500, 429, 200}, wantCalls: 3},
+		{name: "Gives up after retries", statuses: []int{503, 503, 503}, wantCalls: 3, wantErr: true},
+		{name: "Does not retry client errors", statuses: []int{404}, wantCalls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("Unexpected content type %q", r.Header.Get("Content-Type"))
+				}
+				w.WriteHeader(tt.statuses[min(calls, len(tt.statuses)-1)])
+				calls++
+			}))
+			defer server.Close()
+
+			n := &Notifier{Client: server.Client(), Retries: 2, Interval: time.Millisecond}
+			err := n.Send(context.Background(), Webhook{URL: server.URL, Format: Slack}, testEvent)
+			if (err != nil) != tt.wantErr || calls != tt.wantCalls {
+				t.Errorf("Send() = %v after %d calls, want error %v after %d", err, calls, tt.wantErr, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestLogExcerpt(t *testing.T) {
+	var lines []string
+	for i := 0; i < 50; i++ {
+		lines = append(lines, "line")
+	}
+	lines = append(lines, "last")
+
+	excerpt := LogExcerpt(strings.Join(lines, "\n") + "\n")
+	if got := strings.Split(excerpt, "\n"); len(got) != logExcerptLines || got[len(got)-1] != "last" {
+		t.Errorf("Expected the last %d lines, got %d ending %q", logExcerptLines, len(got), got[len(got)-1])
+	}
+	if got := truncate("ab€", 2); got != "…" {
+		t.Errorf("Expected truncation on a rune boundary, got %q", got)
+	}
+}
+
+func TestSendHidesWebhookURL(t *testing.T) {
+	n := &Notifier{Client: &http.Client{Timeout: time.Second}, Interval: time.Millisecond}
+	err := n.Send(context.Background(), Webhook{URL: "http://127.0.0.1:1/services/secret-token"}, testEvent)
+	if err == nil || strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("Expected an error without the webhook path, got %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+// Package smoke provides post-deploy smoke tests for MVPBridge. It requests
+// configured paths on the live app and verifies status codes, content and
+// headers, retrying while the new deployment settles.
+package smoke
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRetries is how many times a failing check is retried
+	DefaultRetries = 5
+	// DefaultInterval is the wait between attempts of a failing check
+	DefaultInterval = 10 * time.Second
+)
+
+// Check is a single smoke test against a path of the live app
+type Check struct {
+	Path     string            `yaml:"path"`
+	Status   int               `yaml:"status,omitempty"`   // defaults to 200
+	Contains string            `yaml:"contains,omitempty"` // substring of the body
+	Headers  map[string]string `yaml:"headers,omitempty"`  // substring of each header value
+}
+
+// Result is the outcome of a single check
+type Result struct {
+	Check    Check
+	URL      string
+	Attempts int
+	Err      error
+}
+
+// Runner executes checks with retries
+type Runner struct {
+	Client   *http.Client
+	Retries  int
+	Interval time.Duration
+}
+
+// NewRunner creates a runner. Negative retries and a zero interval fall back
+// to the defaults; zero retries runs each check once.
+func NewRunner(retries int, interval time.Duration) *Runner {
+	if retries < 0 {
+		retries = DefaultRetries
+	}
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	return &Runner{
+		Client:   &http.Client{Timeout: 15 * time.Second},
+		Retries:  retries,
+		Interval: interval,
+	}
+}
+
+// Run executes all checks against baseURL and returns one result per check
+func (r *Runner) Run(baseURL string, checks []Check) []Result {
+	return r.RunContext(context.Background(), baseURL, checks)
+}
+
+// RunContext is Run with a context; once ctx is done remaining checks fail
+// without further retries
+func (r *Runner) RunContext(ctx context.Context, baseURL string, checks []Check) []Result {
+	baseURL = strings.TrimRight(baseURL, "/")
+
+	results := make([]Result, 0, len(checks))
+	for _, c := range checks {
+		res := Result{Check: c, URL: baseURL + "/" + strings.TrimLeft(c.Path, "/")}
+		for res.Attempts = 1; ; res.Attempts++ {
+			res.Err = r.runOnce(ctx, res.URL, c)
+			if res.Err == nil || res.Attempts > r.Retries || ctx.Err() != nil {
+				break
+			}
+			select {
+			case <-time.After(r.Interval):
+			case <-ctx.Done():
+			}
+		}
+		results = append(results, res)
+	}
+
+	return results
+}
+
+// Failed returns the results that did not pass
+func Failed(results []Result) []Result {
+	var failed []Result
+	for _, res := range results {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+func (r *Runner) runOnce(ctx context.Context, url string, c Check) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading body: %w", err)
+	}
+
+	return c.verify(resp, string(body))
+}
+
+func (c Check) verify(resp *http.Response, body string) error {
+	want := c.Status
+	if want == 0 {
+		want = http.StatusOK
+	}
+	if resp.StatusCode != want {
+		return fmt.Errorf("expected status %d, got %d", want, resp.StatusCode)
+	}
+
+	if c.Contains != "" && !strings.Contains(body, c.Contains) {
+		return fmt.Errorf("body does not contain %q", c.Contains)
+	}
+
+	for name, value := range c.Headers {
+		got := resp.Header.Get(name)
+		if got == "" {
+			return fmt.Errorf("missing header %s", name)
+		}
+		if !strings.Contains(got, value) {
+			return fmt.Errorf("header %s is %q, expected it to contain %q", name, got, value)
+		}
+	}
+
+	return nil
+}
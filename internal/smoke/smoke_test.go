@@ -0,0 +1,113 @@
+package smoke
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte(`<div id="root"></div>`))
+		case "/health":
+			_, _ = w.Write([]byte(`ok`))
+		case "/gone":
+			http.Error(w, "gone", http.StatusGone)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		check   Check
+		wantErr bool
+	}{
+		{
+			name:    "Default status",
+			check:   Check{Path: "/health"},
+			wantErr: false,
+		},
+		{
+			name:    "Body and header match",
+			check:   Check{Path: "/", Contains: `id="root"`, Headers: map[string]string{"Content-Type": "text/html"}},
+			wantErr: false,
+		},
+		{
+			name:    "Expected non-200 status",
+			check:   Check{Path: "/gone", Status: http.StatusGone},
+			wantErr: false,
+		},
+		{
+			name:    "Wrong status",
+			check:   Check{Path: "/missing"},
+			wantErr: true,
+		},
+		{
+			name:    "Missing content",
+			check:   Check{Path: "/health", Contains: "healthy"},
+			wantErr: true,
+		},
+		{
+			name:    "Missing header",
+			check:   Check{Path: "/health", Headers: map[string]string{"X-Version": "1"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Runner{Client: server.Client(), Retries: 0, Interval: time.Millisecond}
+
+			results := r.Run(server.URL+"/", []Check{tt.check})
+			if len(results) != 1 {
+				t.Fatalf("Expected 1 result, got %d", len(results))
+			}
+			if (results[0].Err != nil) != tt.wantErr {
+				t.Errorf("Err = %v, wantErr %v", results[0].Err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunRetriesUntilHealthy(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		if calls < 3 {
+			http.Error(w, "starting", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`ok`))
+	}))
+	defer server.Close()
+
+	r := &Runner{Client: server.Client(), Retries: 5, Interval: time.Millisecond}
+
+	results := r.Run(server.URL, []Check{{Path: "/"}})
+	if len(Failed(results)) != 0 {
+		t.Fatalf("Expected check to pass, got %v", results[0].Err)
+	}
+	if results[0].Attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", results[0].Attempts)
+	}
+}
+
+func TestNewRunnerDefaults(t *testing.T) {
+	r := NewRunner(-1, 0)
+	if r.Retries != DefaultRetries {
+		t.Errorf("Expected %d retries, got %d", DefaultRetries, r.Retries)
+	}
+	if r.Interval != DefaultInterval {
+		t.Errorf("Expected interval %v, got %v", DefaultInterval, r.Interval)
+	}
+
+	if r := NewRunner(0, 0); r.Retries != 0 {
+		t.Errorf("Expected retries disabled, got %d", r.Retries)
+	}
+}
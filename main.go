@@ -4,21 +4,56 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
+	"os/signal"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
+	"mvpbridge/internal/buildspec"
 	"mvpbridge/internal/config"
 	"mvpbridge/internal/deploy"
 	"mvpbridge/internal/detect"
+	"mvpbridge/internal/git"
 	"mvpbridge/internal/normalize"
+	"mvpbridge/internal/notify"
+	"mvpbridge/internal/smoke"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 var version = "0.1.0"
 
+// defaultDeployBranch is the branch production deploys build from
+const defaultDeployBranch = "main"
+
+// awsProfile is the AWS profile selected with the global --profile flag
+var awsProfile string
+
+// doContext is the doctl auth context selected with the global --context flag
+var doContext string
+
+// gitRemote is the Git remote apps build from, selected with the global
+// --remote flag
+var gitRemote string
+
+// rootCtx is cancelled on Ctrl-C/SIGTERM or when --timeout expires; every
+// cloud API call and external command runs under it
+var rootCtx = context.Background()
+
 func main() {
 	rootCmd := &cobra.Command{
 		Use:   "mvpbridge",
@@ -30,12 +65,48 @@ gets your MVP from "works on my machine" to "works everywhere."`,
 		Version: version,
 	}
 
+	rootCmd.PersistentFlags().StringVar(&awsProfile, "profile", "", "AWS profile from ~/.aws/config or ~/.aws/credentials")
+	rootCmd.PersistentFlags().StringVar(&doContext, "context", "", "doctl auth context for DigitalOcean")
+	rootCmd.PersistentFlags().StringVar(&gitRemote, "remote", "origin", "Git remote the app is built from")
+
+	var timeout time.Duration
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "Abort after this long, e.g. 10m (default no limit)")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		// Restore default handling so a second Ctrl-C exits immediately
+		<-ctx.Done()
+		stop()
+	}()
+
+	cancel := func() {}
+	rootCmd.PersistentPreRun = func(_ *cobra.Command, _ []string) {
+		rootCtx = ctx
+		if timeout > 0 {
+			rootCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+	}
+
 	rootCmd.AddCommand(initCmd())
 	rootCmd.AddCommand(inspectCmd())
 	rootCmd.AddCommand(normalizeCmd())
 	rootCmd.AddCommand(deployCmd())
-
-	if err := rootCmd.Execute(); err != nil {
+	rootCmd.AddCommand(previewCmd())
+	rootCmd.AddCommand(destroyCmd())
+	rootCmd.AddCommand(statusCmd())
+	rootCmd.AddCommand(rollbackCmd())
+	rootCmd.AddCommand(authCmd())
+	rootCmd.AddCommand(linkCmd())
+	rootCmd.AddCommand(importCmd())
+	rootCmd.AddCommand(specCmd())
+	rootCmd.AddCommand(domainCmd())
+	rootCmd.AddCommand(historyCmd())
+	rootCmd.AddCommand(notifyCmd())
+
+	err := rootCmd.Execute()
+	cancel()
+	stop()
+	if err != nil {
 		os.Exit(1)
 	}
 }
@@ -90,21 +161,403 @@ func normalizeCmd() *cobra.Command {
 }
 
 func deployCmd() *cobra.Command {
+	var preview string
+	var local bool
+	var force bool
+
 	cmd := &cobra.Command{
 		Use:   "deploy [target]",
 		Short: "Deploy to target platform",
-		Long:  `Deploys your application to the specified platform (do for DigitalOcean, aws for AWS).`,
+		Long: `Deploys your application to the specified platform (do for DigitalOcean, aws for AWS).
+
+With --preview, deploys the current branch as an isolated preview instead of
+the production app and prints the preview URL as preview_url=<url>.
+
+With --local (aws only), builds the static site on this machine and uploads
+the output directory to an Amplify app that has no Git repository, so no
+GITHUB_TOKEN or GitHub remote is needed.
+
+Before deploying, the inspect checks run and the working tree must be clean
+with HEAD pushed to the deployed branch. Errors stop the deploy unless
+--force is given.
+
+The app builds from the repository of the origin remote; select another with
+--remote. GitHub, GitLab, Bitbucket, CodeCommit and other Git servers are
+recognized from the remote URL.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return runDeploy(args[0], preview, local, force)
+		},
+	}
+
+	cmd.Flags().StringVar(&preview, "preview", "", "Deploy an isolated preview with this name (e.g. pr-42)")
+	cmd.Flags().BoolVar(&local, "local", false, "Build locally and upload the output instead of building from Git (aws only)")
+	cmd.Flags().BoolVar(&force, "force", false, "Deploy even when pre-flight checks find errors")
+
+	return cmd
+}
+
+func previewCmd() *cobra.Command {
+	var target string
+
+	cmd := &cobra.Command{
+		Use:   "preview",
+		Short: "Manage preview deployments",
+		Long:  `Lists and cleans up preview deployments created with deploy --preview.`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&target, "target", "t", "", "Deployment target (do, aws)")
+
+	var olderThan string
+	gc := &cobra.Command{
+		Use:   "gc",
+		Short: "Destroy old preview deployments",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return runPreviewGC(target, olderThan)
+		},
+	}
+	gc.Flags().StringVar(&olderThan, "older-than", "7d", "Destroy previews created before this age (e.g. 36h, 7d)")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "destroy <name>",
+		Short: "Destroy a preview deployment",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(_ *cobra.Command, args []string) error {
-			return runDeploy(args[0])
+			return runPreviewDestroy(target, args[0])
+		},
+	})
+	cmd.AddCommand(gc)
+
+	return cmd
+}
+
+func destroyCmd() *cobra.Command {
+	var confirm string
+
+	cmd := &cobra.Command{
+		Use:   "destroy [target]",
+		Short: "Delete the deployed app",
+		Long: `Deletes the app created by deploy, including its components, domains and
+branches. You must type the app name to confirm, or pass it with --confirm.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			target := ""
+			if len(args) > 0 {
+				target = args[0]
+			}
+			return runDestroy(target, confirm)
+		},
+	}
+
+	cmd.Flags().StringVar(&confirm, "confirm", "", "App name, to confirm deletion without prompting")
+
+	return cmd
+}
+
+func statusCmd() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "status [target]",
+		Short: "Show the deployed app's status",
+		Long:  `Shows the live URL, current deployment, components and domains of the deployed app.`,
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			target := ""
+			if len(args) > 0 {
+				target = args[0]
+			}
+			return runStatus(target, output)
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "Output format (text, json)")
+
+	return cmd
+}
+
+func rollbackCmd() *cobra.Command {
+	var target string
+	var toPrevious bool
+
+	cmd := &cobra.Command{
+		Use:   "rollback [deployment-id]",
+		Short: "Roll back to a previous deployment",
+		Long: `Lists recent deployments and rolls the app back to the chosen one.
+
+On DigitalOcean the rollback is validated first and committed once the
+rolled-back deployment is live. On Amplify the job that built the chosen
+commit is re-run. Use --to-previous to pick the last good deployment before
+the current one without prompting.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			id := ""
+			if len(args) > 0 {
+				id = args[0]
+			}
+			return runRollback(target, id, toPrevious)
+		},
+	}
+
+	cmd.Flags().StringVarP(&target, "target", "t", "", "Deployment target (do, aws)")
+	cmd.Flags().BoolVar(&toPrevious, "to-previous", false, "Roll back to the last good deployment before the current one")
+
+	return cmd
+}
+
+func authCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Manage and verify cloud credentials",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "check [target]",
+		Short: "Verify credentials for a deployment target",
+		Long: `Verifies credentials against the platform API and reports the account or
+identity they belong to and any missing permissions.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			target := ""
+			if len(args) > 0 {
+				target = args[0]
+			}
+			return runAuthCheck(target)
+		},
+	})
+
+	var token string
+	login := &cobra.Command{
+		Use:   "login [target]",
+		Short: "Store credentials for a deployment target",
+		Long: `Prompts for credentials (or takes --token for DigitalOcean), validates them
+against the platform API and stores them in the user config directory with
+owner-only permissions. Stored credentials are used when no environment
+variables are set.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			target := ""
+			if len(args) > 0 {
+				target = args[0]
+			}
+			return runAuthLogin(target, token)
+		},
+	}
+	login.Flags().StringVar(&token, "token", "", "DigitalOcean API token (prompted if omitted)")
+	cmd.AddCommand(login)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "logout [target]",
+		Short: "Remove stored credentials for a deployment target",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			target := ""
+			if len(args) > 0 {
+				target = args[0]
+			}
+			return runAuthLogout(target)
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List stored credentials and doctl contexts",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return runAuthList()
+		},
+	})
+
+	return cmd
+}
+
+func linkCmd() *cobra.Command {
+	var target string
+
+	cmd := &cobra.Command{
+		Use:   "link <app-id-or-name>",
+		Short: "Bind the project to an existing app",
+		Long: `Looks up an existing DigitalOcean or Amplify app by ID or name and records it
+in .mvpbridge/state.yaml, so later deploys update that app instead of
+creating a new one.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return runLink(target, args[0])
+		},
+	}
+
+	cmd.Flags().StringVarP(&target, "target", "t", "", "Deployment target (do, aws)")
+
+	return cmd
+}
+
+func importCmd() *cobra.Command {
+	var target string
+
+	cmd := &cobra.Command{
+		Use:   "import <app-id-or-name>",
+		Short: "Generate config from an existing app",
+		Long: `Links an existing app like 'link' and writes its settings to
+.mvpbridge/config.yaml: region, build settings, instance size, the Amplify
+build spec and custom rules, and remote env vars missing from .env (listed
+under deploy.keep_env so deploys preserve them). The first deploy then keeps
+what was configured by hand.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return runImport(target, args[0])
+		},
+	}
+
+	cmd.Flags().StringVarP(&target, "target", "t", "", "Deployment target (do, aws)")
+
+	return cmd
+}
+
+func specCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "spec",
+		Short: "Manage the DigitalOcean app spec",
+		Long: `When .do/app.yaml exists, deploys to DigitalOcean use it as the app spec and
+only set the fields mvpbridge manages: the app name and, on the deployed
+component, the repository and branch, build and run commands and env vars.
+Alerts, ingress rules, jobs, workers and other settings are kept as written.`,
+	}
+
+	var output string
+	export := &cobra.Command{
+		Use:   "export",
+		Short: "Print the app spec a deploy would send",
+		Long: `Prints the DigitalOcean app spec that 'deploy do' would send, with env var
+values from .env left out, so it can be committed as .do/app.yaml and reviewed.`,
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return runSpecExport(output)
+		},
+	}
+	export.Flags().StringVarP(&output, "output", "o", "", "Write to this file instead of stdout (e.g. .do/app.yaml)")
+	cmd.AddCommand(export)
+
+	return cmd
+}
+
+func domainCmd() *cobra.Command {
+	var target string
+
+	cmd := &cobra.Command{
+		Use:   "domain",
+		Short: "Manage custom domains",
+		Long: `Custom domains are listed under domains: in .mvpbridge/config.yaml and
+attached to the deployed app. On DigitalOcean they are set in the app spec; on
+Amplify each host is a subdomain of a domain association, mapped to a branch.
+Deploys attach any listed domain the app is missing.`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&target, "target", "t", "", "Deployment target (do, aws)")
+
+	var domain config.Domain
+	var wait time.Duration
+	add := &cobra.Command{
+		Use:   "add <host>",
+		Short: "Add a custom domain",
+		Long: `Adds a custom domain to the config and attaches it to the deployed app,
+prints the DNS records to create, then waits until the domain is verified and
+its certificate issued.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			domain.Host = args[0]
+			return runDomainAdd(target, domain, wait)
 		},
 	}
+	add.Flags().StringVar(&domain.Type, "type", "ALIAS", "Domain type on DigitalOcean (PRIMARY, ALIAS)")
+	add.Flags().StringVar(&domain.Zone, "zone", "", "Apex domain; on DigitalOcean, a zone hosted in DigitalOcean DNS")
+	add.Flags().StringVar(&domain.Branch, "branch", "", "Branch served on the host on Amplify (default: the deployed branch)")
+	add.Flags().DurationVar(&wait, "wait", 30*time.Minute, "How long to wait for verification and the certificate (0 to return immediately)")
+
+	cmd.AddCommand(add)
+	cmd.AddCommand(&cobra.Command{
+		Use:   "remove <host>",
+		Short: "Remove a custom domain",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return runDomainRemove(target, args[0])
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List custom domains and their status",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return runDomainList(target)
+		},
+	})
 
 	return cmd
 }
 
 // Implementation functions
 
+// historyFilter selects history entries; zero fields match everything
+type historyFilter struct {
+	target string
+	action string
+	result string
+	since  string
+	limit  int
+}
+
+func historyCmd() *cobra.Command {
+	var filter historyFilter
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show deploys, rollbacks and destroys run from this project",
+		Long: `Lists the actions recorded in .mvpbridge/history.jsonl, newest first: the
+target, app, deployment or job ID, commit, user, duration and result of every
+deploy, rollback and destroy. Error messages are stored redacted.`,
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return runHistory(filter, output)
+		},
+	}
+
+	cmd.Flags().StringVar(&filter.target, "target", "", "Only this target (do, aws)")
+	cmd.Flags().StringVar(&filter.action, "action", "", "Only this action (deploy, rollback, destroy)")
+	cmd.Flags().StringVar(&filter.result, "result", "", "Only this result (success, failure)")
+	cmd.Flags().StringVar(&filter.since, "since", "", "Only entries newer than this, e.g. 7d or 12h")
+	cmd.Flags().IntVarP(&filter.limit, "limit", "n", 20, "Show at most this many entries (0 for all)")
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "Output format (text, json)")
+
+	return cmd
+}
+
+func notifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "notify",
+		Short: "Manage deploy notifications",
+		Long: `Webhooks listed under notifications: in .mvpbridge/config.yaml are notified
+when a deploy starts, succeeds or fails and when an app is rolled back. Each
+webhook takes a generic JSON payload or a Slack- or Discord-compatible message.`,
+	}
+
+	var event string
+	test := &cobra.Command{
+		Use:   "test",
+		Short: "Send a sample event to every configured webhook",
+		Long: `Sends a sample event to each webhook, whatever events it is subscribed to,
+and reports which deliveries failed.`,
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return runNotifyTest(notify.EventType(event))
+		},
+	}
+	test.Flags().StringVar(&event, "event", string(notify.Success), "Event to send (start, success, failure, rollback)")
+
+	cmd.AddCommand(test)
+	return cmd
+}
+
 func runInit(target, framework string) error {
 	fmt.Println("Initializing MVPBridge...")
 
@@ -146,6 +599,15 @@ func runInit(target, framework string) error {
 
 	// Create config from detection
 	cfg := config.NewFromDetection(d, target)
+	if b := d.Backend; b != nil {
+		fmt.Printf("  Detected %s API in %s/, added to components: and routed at /api\n", b.Framework, b.Dir)
+	}
+	if db := cfg.Database; db != nil {
+		fmt.Printf("  Detected a %s database; a dev database is attached on DigitalOcean as %s\n", db.Engine, db.Name)
+		if db.Engine != "PG" {
+			fmt.Println("  Set database.cluster to an existing managed cluster: dev databases are PostgreSQL only")
+		}
+	}
 	if err := cfg.Save("."); err != nil {
 		return fmt.Errorf("saving config: %w", err)
 	}
@@ -191,6 +653,20 @@ func runInspect() error {
 		fmt.Printf("│  Output Type:   %-32s│\n", string(d.OutputType))
 	}
 
+	// Display API server
+	if b := d.Backend; b != nil {
+		fmt.Printf("│  Backend:       %-32s│\n", fmt.Sprintf("%s in %s/", b.Framework, b.Dir))
+	}
+
+	// Display database
+	if db := d.Database; db != nil {
+		dbDisplay := db.Engine
+		if db.ORM != "" {
+			dbDisplay = fmt.Sprintf("%s via %s", db.Engine, db.ORM)
+		}
+		fmt.Printf("│  Database:      %-32s│\n", dbDisplay)
+	}
+
 	fmt.Println("├─────────────────────────────────────────────────┤")
 
 	// Display issues
@@ -251,7 +727,7 @@ func runNormalize(dryRun, yes bool) error {
 	fmt.Println()
 
 	// Create normalizer
-	n := normalize.New(".", cfg.GetFramework(), dryRun)
+	n := normalize.New(".", cfg.GetFramework(), dryRun).WithTarget(cfg.Target)
 
 	// Run normalization
 	if err := n.Run(); err != nil {
@@ -268,12 +744,15 @@ func runNormalize(dryRun, yes bool) error {
 	return nil
 }
 
-func runDeploy(target string) error {
+func runDeploy(target, preview string, local, force bool) error {
 	// Load config
 	cfg, err := config.Load(".")
 	if err != nil {
 		return fmt.Errorf("config not found - run 'mvpbridge init' first: %w", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid .mvpbridge/config.yaml: %w", err)
+	}
 
 	// Use config target if not specified
 	if target == "" || target == "do" {
@@ -283,95 +762,2317 @@ func runDeploy(target string) error {
 		}
 	}
 
+	if local && target != "aws" {
+		return fmt.Errorf("--local is only supported for aws")
+	}
+	if local && preview != "" {
+		return fmt.Errorf("--local cannot be combined with --preview")
+	}
+
+	if target != "do" && target != "aws" {
+		return fmt.Errorf("unknown target: %s (supported: do, aws)", target)
+	}
+
+	entry := &config.HistoryEntry{Action: "deploy", Target: target, Preview: preview}
+	started := time.Now()
+	commit, err := preflight(target, preview, local, force)
+	if err != nil {
+		recordHistory(entry, started, err)
+		return err
+	}
+	entry.Commit = commit
+
+	notifyEvent(cfg, notify.Start, entry, "")
+	err = deployTo(cfg, target, preview, local, commit, entry)
+	recordHistory(entry, started, err)
+	if err != nil {
+		notifyEvent(cfg, notify.Failure, entry, failureLogs(cfg, entry))
+	} else {
+		notifyEvent(cfg, notify.Success, entry, "")
+	}
+	return err
+}
+
+// deployTo deploys commit, noting the app and deployment in entry
+func deployTo(cfg *config.Config, target, preview string, local bool, commit string, entry *config.HistoryEntry) error {
 	switch target {
 	case "do":
-		return deployDigitalOcean(cfg)
+		return deployDigitalOcean(cfg, preview, commit, entry)
 	case "aws":
-		return deployAWS(cfg)
+		if local {
+			return deployAWSLocal(cfg, entry)
+		}
+		return deployAWS(cfg, preview, commit, entry)
 	default:
 		return fmt.Errorf("unknown target: %s (supported: do, aws)", target)
 	}
 }
 
-// Helper functions
+func runDestroy(target, confirm string) error {
+	cfg, err := config.Load(".")
+	if err != nil {
+		return fmt.Errorf("config not found - run 'mvpbridge init' first: %w", err)
+	}
 
-func checkGit() error {
-	cmd := exec.CommandContext(context.Background(), "git", "--version")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("git not installed")
+	target = targetFor(cfg, target)
+	entry := &config.HistoryEntry{Action: "destroy", Target: target}
+	started := time.Now()
+	switch target {
+	case "do":
+		err = destroyDigitalOcean(cfg, confirm, entry)
+	case "aws":
+		err = destroyAWS(cfg, confirm, entry)
+	default:
+		return fmt.Errorf("unknown target: %s (supported: do, aws)", target)
+	}
+	recordHistory(entry, started, err)
+	if err != nil {
+		return err
 	}
-	return nil
-}
 
-func checkGitRepo() error {
-	if _, err := os.Stat(".git"); os.IsNotExist(err) {
-		return fmt.Errorf("not a git repository")
+	state, err := config.LoadState(".")
+	if err != nil {
+		return fmt.Errorf("loading state: %w", err)
 	}
+	state.Clear(target)
+	if err := state.Save("."); err != nil {
+		return fmt.Errorf("clearing state: %w", err)
+	}
+
 	return nil
 }
 
-func checkNode() error {
-	cmd := exec.CommandContext(context.Background(), "node", "--version")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("node not installed")
+func destroyDigitalOcean(cfg *config.Config, confirm string, entry *config.HistoryEntry) error {
+	deployer, err := newDODeployer(cfg)
+	if err != nil {
+		return err
 	}
-	return nil
-}
 
-func formatFramework(fw detect.Framework) string {
-	switch fw {
-	case detect.Vite:
-		return "Vite"
-	case detect.NextJS:
-		return "Next.js"
-	default:
-		return "Unknown"
+	result, err := deployer.FindApp()
+	if err != nil {
+		return fmt.Errorf("looking up app: %w", err)
 	}
-}
+	app := result.App
+	entry.AppID = app.ID
 
-func formatNodeVersion(version string) string {
-	if version == "" {
-		return "Not pinned"
+	fmt.Printf("This will permanently delete DigitalOcean app %s (%s):\n", deployer.AppName, app.ID)
+	for _, c := range app.Spec.Components() {
+		fmt.Printf("  component: %s\n", c)
 	}
-	return version + " (pinned)"
-}
+	for _, dom := range app.Spec.Domains {
+		fmt.Printf("  domain:    %s\n", dom.Domain)
+	}
+	fmt.Println()
 
-func getGitHubRepo() (string, error) {
-	cmd := exec.CommandContext(context.Background(), "git", "config", "--get", "remote.origin.url")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("no git remote configured")
+	if err := confirmAppName(deployer.AppName, confirm); err != nil {
+		return err
 	}
 
-	url := strings.TrimSpace(string(output))
-	// Convert SSH to HTTPS format if needed
-	if strings.HasPrefix(url, "git@github.com:") {
-		url = strings.Replace(url, "git@github.com:", "https://github.com/", 1)
+	if err := deployer.DeleteApp(app.ID); err != nil {
+		return fmt.Errorf("deleting app: %w", err)
 	}
-	url = strings.TrimSuffix(url, ".git")
 
-	return url, nil
+	fmt.Printf("✓ Deleted %s\n", deployer.AppName)
+	return nil
 }
 
-func extractEnvVars() (map[string]string, error) {
-	envVars := make(map[string]string)
+func destroyAWS(cfg *config.Config, confirm string, entry *config.HistoryEntry) error {
+	deployer, err := newAWSDeployer(cfg)
+	if err != nil {
+		return err
+	}
 
-	data, err := os.ReadFile(".env")
+	result, err := deployer.FindApp()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return envVars, nil
-		}
-		return nil, err
+		return fmt.Errorf("looking up app: %w", err)
 	}
+	appID := result.App.AppID
+	entry.AppID = appID
 
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-		if idx := strings.Index(line, "="); idx > 0 {
-			key := strings.TrimSpace(line[:idx])
+	branches, err := deployer.ListBranches(appID)
+	if err != nil {
+		return fmt.Errorf("listing branches: %w", err)
+	}
+	domains, err := deployer.ListDomainAssociations(appID)
+	if err != nil {
+		return fmt.Errorf("listing domains: %w", err)
+	}
+
+	fmt.Printf("This will permanently delete Amplify app %s (%s):\n", deployer.AppName, appID)
+	for _, b := range branches {
+		fmt.Printf("  branch: %s (%s)\n", b.BranchName, b.Stage)
+	}
+	for _, dom := range domains {
+		fmt.Printf("  domain: %s\n", dom.DomainName)
+	}
+	fmt.Println()
+
+	if err := confirmAppName(deployer.AppName, confirm); err != nil {
+		return err
+	}
+
+	if err := deployer.DeleteApp(appID); err != nil {
+		return fmt.Errorf("deleting app: %w", err)
+	}
+
+	fmt.Printf("✓ Deleted %s\n", deployer.AppName)
+	return nil
+}
+
+// confirmAppName requires the user to type the app name before a destructive
+// action, unless it was already supplied with --confirm
+func confirmAppName(appName, confirm string) error {
+	if confirm == "" {
+		fmt.Printf("Type the app name (%s) to confirm: ", appName)
+		if _, err := fmt.Scanln(&confirm); err != nil {
+			return fmt.Errorf("canceled by user")
+		}
+	}
+
+	if strings.TrimSpace(confirm) != appName {
+		return fmt.Errorf("canceled: %q does not match app name %s", confirm, appName)
+	}
+	return nil
+}
+
+// appStatus is the target-independent view of a deployed app
+type appStatus struct {
+	Target     string            `json:"target"`
+	AppID      string            `json:"app_id"`
+	AppName    string            `json:"app_name"`
+	URL        string            `json:"url,omitempty"`
+	Deployment *deploymentStatus `json:"deployment,omitempty"`
+	InProgress *deploymentStatus `json:"in_progress,omitempty"`
+	LastDeploy *lastDeploy       `json:"last_deploy,omitempty"`
+	Components []string          `json:"components,omitempty"`
+	Domains    []string          `json:"domains,omitempty"`
+	Branches   []branchStatus    `json:"branches,omitempty"`
+}
+
+// lastDeploy is the deployment recorded in state by the last deploy from
+// this project
+type lastDeploy struct {
+	ID     string `json:"id,omitempty"`
+	Commit string `json:"commit"`
+}
+
+type deploymentStatus struct {
+	ID        string    `json:"id"`
+	Phase     string    `json:"phase"`
+	Commit    string    `json:"commit,omitempty"`
+	Cause     string    `json:"cause,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type branchStatus struct {
+	Name    string            `json:"name"`
+	Stage   string            `json:"stage"`
+	URL     string            `json:"url,omitempty"`
+	LastJob *deploymentStatus `json:"last_job,omitempty"`
+}
+
+func runStatus(target, output string) error {
+	if output != "text" && output != "json" {
+		return fmt.Errorf("unknown output format: %s (supported: text, json)", output)
+	}
+
+	cfg, err := config.Load(".")
+	if err != nil {
+		return fmt.Errorf("config not found - run 'mvpbridge init' first: %w", err)
+	}
+
+	var status *appStatus
+	switch target = targetFor(cfg, target); target {
+	case "do":
+		status, err = statusDigitalOcean(cfg)
+	case "aws":
+		status, err = statusAWS(cfg)
+	default:
+		return fmt.Errorf("unknown target: %s (supported: do, aws)", target)
+	}
+	if err != nil {
+		return err
+	}
+	if state, err := config.LoadState("."); err == nil {
+		if ts, ok := state.Targets[target]; ok && ts.Commit != "" {
+			status.LastDeploy = &lastDeploy{ID: ts.DeploymentID, Commit: ts.Commit}
+		}
+	}
+
+	if output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(status)
+	}
+
+	printStatus(status)
+	return nil
+}
+
+func statusDigitalOcean(cfg *config.Config) (*appStatus, error) {
+	deployer, err := newDODeployer(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := deployer.FindApp()
+	if err != nil {
+		return nil, fmt.Errorf("looking up app: %w", err)
+	}
+	app := result.App
+
+	status := &appStatus{
+		Target:     "do",
+		AppID:      app.ID,
+		AppName:    deployer.AppName,
+		URL:        app.LiveURL,
+		Components: app.Spec.Components(),
+	}
+	if app.ActiveDeployment.ID != "" {
+		status.Deployment = doDeploymentStatus(&app.ActiveDeployment)
+	}
+	if app.InProgressDeployment != nil && app.InProgressDeployment.ID != "" {
+		status.InProgress = doDeploymentStatus(app.InProgressDeployment)
+	}
+	for _, d := range app.Spec.Domains {
+		status.Domains = append(status.Domains, d.Domain)
+	}
+
+	return status, nil
+}
+
+func doDeploymentStatus(d *deploy.DODeployment) *deploymentStatus {
+	return &deploymentStatus{
+		ID:        d.ID,
+		Phase:     d.Phase,
+		Commit:    d.CommitHash(),
+		Cause:     d.Cause,
+		CreatedAt: d.CreatedAt,
+	}
+}
+
+func statusAWS(cfg *config.Config) (*appStatus, error) {
+	deployer, err := newAWSDeployer(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := deployer.FindApp()
+	if err != nil {
+		return nil, fmt.Errorf("looking up app: %w", err)
+	}
+	appID := result.App.AppID
+
+	status := &appStatus{
+		Target:  "aws",
+		AppID:   appID,
+		AppName: deployer.AppName,
+	}
+
+	branches, err := deployer.ListBranches(appID)
+	if err != nil {
+		return nil, fmt.Errorf("listing branches: %w", err)
+	}
+	for _, b := range branches {
+		bs := branchStatus{Name: b.BranchName, Stage: b.Stage}
+		if b.DisplayName != "" {
+			bs.URL = amplifyBranchURL(b.DisplayName, result.App.DefaultDomain)
+		} else {
+			bs.URL = amplifyBranchURL(b.BranchName, result.App.DefaultDomain)
+		}
+
+		jobs, err := deployer.ListJobs(appID, b.BranchName, 1)
+		if err != nil {
+			return nil, fmt.Errorf("listing jobs for %s: %w", b.BranchName, err)
+		}
+		if len(jobs) > 0 {
+			bs.LastJob = &deploymentStatus{
+				ID:        jobs[0].JobID,
+				Phase:     jobs[0].Status,
+				Commit:    jobs[0].CommitID,
+				Cause:     jobs[0].JobType,
+				CreatedAt: jobs[0].Started(),
+			}
+		}
+
+		if b.BranchName == deployer.Branch {
+			status.URL = bs.URL
+			status.Deployment = bs.LastJob
+		}
+		status.Branches = append(status.Branches, bs)
+	}
+
+	domains, err := deployer.ListDomainAssociations(appID)
+	if err != nil {
+		return nil, fmt.Errorf("listing domains: %w", err)
+	}
+	for _, d := range domains {
+		status.Domains = append(status.Domains, d.DomainName)
+	}
+
+	return status, nil
+}
+
+func printStatus(s *appStatus) {
+	fmt.Printf("App:         %s (%s)\n", s.AppName, s.AppID)
+	if s.URL != "" {
+		fmt.Printf("URL:         %s\n", s.URL)
+	}
+	if d := s.Deployment; d != nil {
+		fmt.Printf("Deployment:  %s %s\n", d.ID, d.Phase)
+		if d.Commit != "" {
+			fmt.Printf("  Commit:    %s\n", d.Commit)
+		}
+		if !d.CreatedAt.IsZero() {
+			fmt.Printf("  Deployed:  %s\n", d.CreatedAt.Local().Format(time.RFC1123))
+		}
+		if d.Cause != "" {
+			fmt.Printf("  Cause:     %s\n", d.Cause)
+		}
+	} else {
+		fmt.Println("Deployment:  none")
+	}
+	if d := s.InProgress; d != nil {
+		fmt.Printf("In progress: %s %s\n", d.ID, d.Phase)
+	}
+	if d := s.LastDeploy; d != nil && d.ID != "" {
+		fmt.Printf("Last deploy: %s from commit %s\n", d.ID, d.Commit)
+	} else if d != nil {
+		fmt.Printf("Last deploy: commit %s\n", d.Commit)
+	}
+	for _, c := range s.Components {
+		fmt.Printf("Component:   %s\n", c)
+	}
+	for _, b := range s.Branches {
+		job := "no builds"
+		if b.LastJob != nil {
+			job = fmt.Sprintf("job %s %s", b.LastJob.ID, b.LastJob.Phase)
+		}
+		fmt.Printf("Branch:      %s (%s) - %s\n", b.Name, b.Stage, job)
+	}
+	for _, d := range s.Domains {
+		fmt.Printf("Domain:      %s\n", d)
+	}
+}
+
+func runRollback(target, id string, toPrevious bool) error {
+	if id != "" && toPrevious {
+		return fmt.Errorf("pass either a deployment ID or --to-previous, not both")
+	}
+
+	cfg, err := config.Load(".")
+	if err != nil {
+		return fmt.Errorf("config not found - run 'mvpbridge init' first: %w", err)
+	}
+
+	target = targetFor(cfg, target)
+	entry := &config.HistoryEntry{Action: "rollback", Target: target}
+	started := time.Now()
+	var rollback func() error
+	switch target {
+	case "do":
+		rollback, err = rollbackDigitalOcean(cfg, id, toPrevious, entry)
+	case "aws":
+		rollback, err = rollbackAWS(cfg, id, toPrevious, entry)
+	default:
+		return fmt.Errorf("unknown target: %s (supported: do, aws)", target)
+	}
+	if err != nil {
+		// Nothing was rolled back, so there is nothing to notify about
+		recordHistory(entry, started, err)
+		return err
+	}
+
+	err = rollback()
+	recordHistory(entry, started, err)
+	notifyEvent(cfg, notify.Rollback, entry, "")
+	return err
+}
+
+// rollbackDigitalOcean picks the deployment to roll back to and returns the
+// rollback, ready to run
+func rollbackDigitalOcean(cfg *config.Config, id string, toPrevious bool, entry *config.HistoryEntry) (func() error, error) {
+	deployer, err := newDODeployer(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := deployer.FindApp()
+	if err != nil {
+		return nil, fmt.Errorf("looking up app: %w", err)
+	}
+	app := result.App
+	entry.AppID, entry.AppName, entry.URL = app.ID, deployer.AppName, app.LiveURL
+
+	deployments, err := deployer.ListDeployments(app.ID, 10)
+	if err != nil {
+		return nil, fmt.Errorf("listing deployments: %w", err)
+	}
+
+	switch {
+	case toPrevious:
+		prev := deploy.PreviousDeployment(deployments, app.ActiveDeployment.ID)
+		if prev == nil {
+			return nil, fmt.Errorf("no earlier successful deployment to roll back to")
+		}
+		id = prev.ID
+	case id == "":
+		fmt.Println("Recent deployments:")
+		for _, dep := range deployments {
+			marker := " "
+			if dep.ID == app.ActiveDeployment.ID {
+				marker = "*"
+			}
+			fmt.Printf(" %s %s  %-12s %-8s %s\n", marker, dep.ID, dep.Phase,
+				shortSHA(dep.CommitHash()), dep.CreatedAt.Local().Format(time.RFC1123))
+		}
+		if id, err = promptDeploymentID(); err != nil {
+			return nil, err
+		}
+	}
+
+	entry.DeploymentID = id
+	for _, dep := range deployments {
+		if dep.ID == id {
+			entry.Commit = dep.CommitHash()
+		}
+	}
+	return func() error { return doRollback(deployer, app.ID, id) }, nil
+}
+
+// doRollback rolls a DigitalOcean app back to a deployment, committing the
+// rollback once it is live and reverting it if it fails
+func doRollback(deployer *deploy.DODeployer, appID, deploymentID string) error {
+	fmt.Printf("Rolling back to deployment %s...\n", deploymentID)
+
+	dep, err := deployer.Rollback(appID, deploymentID)
+	if err != nil {
+		return fmt.Errorf("rollback failed: %w", err)
+	}
+
+	if err := deployer.WaitForDeploymentID(appID, dep.ID, 15*time.Minute); err != nil {
+		if revertErr := deployer.RevertRollback(appID); revertErr != nil {
+			return fmt.Errorf("rollback failed: %w (revert also failed: %w)", err, revertErr)
+		}
+		return fmt.Errorf("rollback failed and was reverted: %w", err)
+	}
+
+	if err := deployer.CommitRollback(appID); err != nil {
+		return fmt.Errorf("committing rollback: %w", err)
+	}
+
+	fmt.Printf("✓ Rolled back to %s (new deployment %s)\n", deploymentID, dep.ID)
+	return nil
+}
+
+// rollbackAWS picks the job to re-run and returns the rollback, ready to run
+func rollbackAWS(cfg *config.Config, id string, toPrevious bool, entry *config.HistoryEntry) (func() error, error) {
+	deployer, err := newAWSDeployer(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := deployer.FindApp()
+	if err != nil {
+		return nil, fmt.Errorf("looking up app: %w", err)
+	}
+	appID := result.App.AppID
+	entry.AppID, entry.AppName = appID, deployer.AppName
+	entry.URL = amplifyBranchURL(deployer.Branch, result.App.DefaultDomain)
+	if result.App.Repository == "" {
+		return nil, fmt.Errorf("app %s is deployed from local builds, which cannot be re-run; deploy a good build again with --local", deployer.AppName)
+	}
+
+	jobs, err := deployer.ListJobs(appID, deployer.Branch, 10)
+	if err != nil {
+		return nil, fmt.Errorf("listing jobs: %w", err)
+	}
+
+	switch {
+	case toPrevious:
+		prev := deploy.PreviousJob(jobs)
+		if prev == nil {
+			return nil, fmt.Errorf("no earlier successful job to roll back to")
+		}
+		id = prev.JobID
+	case id == "":
+		fmt.Printf("Recent jobs on %s:\n", deployer.Branch)
+		for _, job := range jobs {
+			fmt.Printf("   %-6s %-10s %-8s %s\n", job.JobID, job.Status,
+				shortSHA(job.CommitID), job.Started().Local().Format(time.RFC1123))
+		}
+		if id, err = promptDeploymentID(); err != nil {
+			return nil, err
+		}
+	}
+
+	entry.DeploymentID = id
+	for _, job := range jobs {
+		if job.JobID == id {
+			entry.Commit = job.CommitID
+		}
+	}
+	return func() error { return awsRollback(deployer, appID, id) }, nil
+}
+
+// awsRollback re-runs an earlier Amplify job to redeploy its commit
+func awsRollback(deployer *deploy.AWSDeployer, appID, jobID string) error {
+	fmt.Printf("Rolling back to job %s...\n", jobID)
+
+	newJobID, err := deployer.RetryJob(appID, deployer.Branch, jobID)
+	if err != nil {
+		return fmt.Errorf("rollback failed: %w", err)
+	}
+
+	fmt.Printf("✓ Started job %s to redeploy job %s\n", newJobID, jobID)
+	return nil
+}
+
+func promptDeploymentID() (string, error) {
+	fmt.Print("\nRoll back to (ID): ")
+	var id string
+	if _, err := fmt.Scanln(&id); err != nil || strings.TrimSpace(id) == "" {
+		return "", fmt.Errorf("canceled by user")
+	}
+	return strings.TrimSpace(id), nil
+}
+
+// shortSHA abbreviates a commit hash for display
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+func runHistory(filter historyFilter, output string) error {
+	if output != "text" && output != "json" {
+		return fmt.Errorf("unknown output format: %s (supported: text, json)", output)
+	}
+
+	var cutoff time.Time
+	if filter.since != "" {
+		age, err := parseAge(filter.since)
+		if err != nil {
+			return err
+		}
+		cutoff = time.Now().Add(-age)
+	}
+
+	entries, err := config.LoadHistory(".")
+	if err != nil {
+		return fmt.Errorf("reading history: %w", err)
+	}
+
+	matched := []config.HistoryEntry{}
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if (filter.target != "" && e.Target != filter.target) ||
+			(filter.action != "" && e.Action != filter.action) ||
+			(filter.result != "" && e.Result != filter.result) ||
+			e.Time.Before(cutoff) {
+			continue
+		}
+		matched = append(matched, e)
+		if filter.limit > 0 && len(matched) == filter.limit {
+			break
+		}
+	}
+
+	if output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(matched)
+	}
+
+	if len(matched) == 0 {
+		fmt.Println("No history recorded")
+		return nil
+	}
+	fmt.Printf("%-16s  %-8s  %-6s  %-7s  %-8s  %-36s  %-8s  %s\n",
+		"TIME", "ACTION", "TARGET", "RESULT", "COMMIT", "DEPLOYMENT", "DURATION", "USER")
+	for _, e := range matched {
+		fmt.Printf("%-16s  %-8s  %-6s  %-7s  %-8s  %-36s  %-8s  %s\n",
+			e.Time.Local().Format("2006-01-02 15:04"), e.Action, e.Target, e.Result,
+			shortSHA(e.Commit), e.DeploymentID, e.Duration().Round(time.Second), e.User)
+		if e.Preview != "" {
+			fmt.Printf("  preview %s\n", e.Preview)
+		}
+		if e.Error != "" {
+			fmt.Printf("  %s\n", e.Error)
+		}
+	}
+	return nil
+}
+
+func runLink(target, ref string) error {
+	cfg, err := config.Load(".")
+	if err != nil {
+		return err
+	}
+
+	target = targetFor(cfg, target)
+	if err := linkApp(cfg, target, ref); err != nil {
+		return err
+	}
+
+	if err := cfg.Save("."); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+	return nil
+}
+
+func runSpecExport(output string) error {
+	cfg, err := config.Load(".")
+	if err != nil {
+		return fmt.Errorf("config not found - run 'mvpbridge init' first: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid .mvpbridge/config.yaml: %w", err)
+	}
+
+	remote, err := getRepoRemote()
+	if err != nil {
+		return fmt.Errorf("getting git remote: %w", err)
+	}
+	repoURL := remote.HTTPSURL()
+
+	// Exporting needs no API access, so no token is resolved
+	deployer := deploy.NewDODeployerWithToken("", appNameFor(cfg, repoURL), repoURL, defaultDeployBranch)
+	if err := configureDODeployer(deployer, cfg); err != nil {
+		return err
+	}
+
+	envVars, err := extractEnvVars()
+	if err != nil {
+		return fmt.Errorf("extracting env vars: %w", err)
+	}
+
+	spec, err := deployer.ExportSpec(cfg.IsStatic(), envVars)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		_, err = os.Stdout.Write(spec)
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0750); err != nil {
+		return err
+	}
+	if err := os.WriteFile(output, spec, 0600); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Wrote %s\n", output)
+	return nil
+}
+
+func runDomainAdd(target string, domain config.Domain, wait time.Duration) error {
+	cfg, err := config.Load(".")
+	if err != nil {
+		return fmt.Errorf("config not found - run 'mvpbridge init' first: %w", err)
+	}
+
+	domain.Host = strings.ToLower(domain.Host)
+	domain.Type = strings.ToUpper(domain.Type)
+	cfg.SetDomain(domain)
+	if err := cfg.ValidateDomains(); err != nil {
+		return err
+	}
+	if err := cfg.Save("."); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+	fmt.Printf("✓ Added %s to .mvpbridge/config.yaml\n", domain.Host)
+
+	switch target = targetFor(cfg, target); target {
+	case "do":
+		return addDomainDigitalOcean(cfg, domain, wait)
+	case "aws":
+		return addDomainAWS(cfg, domain, wait)
+	default:
+		return fmt.Errorf("unknown target: %s (supported: do, aws)", target)
+	}
+}
+
+func addDomainDigitalOcean(cfg *config.Config, domain config.Domain, wait time.Duration) error {
+	deployer, err := newDODeployer(cfg)
+	if err != nil {
+		return err
+	}
+
+	result, err := deployer.FindApp()
+	if errors.Is(err, deploy.ErrAppNotFound) {
+		fmt.Println("App not deployed yet; the domain is attached on the next deploy.")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("looking up app: %w", err)
+	}
+	app := result.App
+
+	spec := doDomain(domain)
+	if _, err := deployer.AddDomain(&app, spec); err != nil {
+		return fmt.Errorf("attaching domain: %w", err)
+	}
+	fmt.Printf("✓ Attached %s to %s\n", domain.Host, deployer.AppName)
+	printDNSRecords(deploy.DODNSRecords(spec, nil, app.DefaultIngress))
+
+	if wait == 0 {
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Println("Waiting for the domain to be configured and its certificate issued...")
+	phase := ""
+	validations := false
+	_, err = deployer.WaitForDomain(app.ID, domain.Host, wait, func(d *deploy.DOAppDomain) {
+		if d.Phase != phase {
+			phase = d.Phase
+			fmt.Printf("  Domain status: %s\n", phase)
+		}
+		if !validations && len(d.Validations) > 0 {
+			validations = true
+			printDNSRecords(deploy.DODNSRecords(spec, d, app.DefaultIngress))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ https://%s is live\n", domain.Host)
+	return nil
+}
+
+func addDomainAWS(cfg *config.Config, domain config.Domain, wait time.Duration) error {
+	deployer, err := newAWSDeployer(cfg)
+	if err != nil {
+		return err
+	}
+
+	result, err := deployer.FindApp()
+	if errors.Is(err, deploy.ErrAppNotFound) {
+		fmt.Println("App not deployed yet; the domain is attached on the next deploy.")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("looking up app: %w", err)
+	}
+	appID := result.App.AppID
+
+	root, prefix := deploy.AmplifyDomainParts(domain.Host, domain.Zone)
+	branch := domainBranch(domain, deployer.Branch)
+	assoc, err := deployer.AddDomain(appID, root, prefix, branch)
+	if err != nil {
+		return fmt.Errorf("attaching domain: %w", err)
+	}
+	fmt.Printf("✓ Mapped %s to branch %s\n", domain.Host, branch)
+
+	records := assoc.DNSRecords()
+	printDNSRecords(records)
+
+	if wait == 0 {
+		if len(records) == 0 {
+			fmt.Println("Run 'mvpbridge domain list' to see the DNS records to create once Amplify has generated them.")
+		}
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Println("Waiting for the domain to be verified and its certificate issued...")
+	status := assoc.DomainStatus
+	_, err = deployer.WaitForDomain(appID, root, wait, func(a *deploy.AmplifyDomainAssociation) {
+		if a.DomainStatus != status {
+			status = a.DomainStatus
+			fmt.Printf("  Domain status: %s\n", status)
+		}
+		if len(records) == 0 {
+			records = a.DNSRecords()
+			printDNSRecords(records)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ https://%s is live\n", domain.Host)
+	return nil
+}
+
+func runDomainRemove(target, host string) error {
+	cfg, err := config.Load(".")
+	if err != nil {
+		return fmt.Errorf("config not found - run 'mvpbridge init' first: %w", err)
+	}
+
+	host = strings.ToLower(host)
+	domain := config.Domain{Host: host}
+	for _, d := range cfg.Domains {
+		if d.Host == host {
+			domain = d
+		}
+	}
+	listed := cfg.RemoveDomain(host)
+
+	switch target = targetFor(cfg, target); target {
+	case "do":
+		err = removeDomainDigitalOcean(cfg, host)
+	case "aws":
+		err = removeDomainAWS(cfg, domain)
+	default:
+		return fmt.Errorf("unknown target: %s (supported: do, aws)", target)
+	}
+	if errors.Is(err, deploy.ErrDomainNotFound) || errors.Is(err, deploy.ErrAppNotFound) {
+		if !listed {
+			return fmt.Errorf("%s is not configured or attached to the app", host)
+		}
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if listed {
+		if err := cfg.Save("."); err != nil {
+			return fmt.Errorf("saving config: %w", err)
+		}
+	}
+	fmt.Printf("✓ Removed %s\n", host)
+	fmt.Println("  Remove its DNS records at your DNS provider if you no longer use them.")
+	return nil
+}
+
+func removeDomainDigitalOcean(cfg *config.Config, host string) error {
+	deployer, err := newDODeployer(cfg)
+	if err != nil {
+		return err
+	}
+
+	result, err := deployer.FindApp()
+	if err != nil {
+		return err
+	}
+	_, err = deployer.RemoveDomain(&result.App, host)
+	return err
+}
+
+func removeDomainAWS(cfg *config.Config, domain config.Domain) error {
+	deployer, err := newAWSDeployer(cfg)
+	if err != nil {
+		return err
+	}
+
+	result, err := deployer.FindApp()
+	if err != nil {
+		return err
+	}
+	root, prefix := deploy.AmplifyDomainParts(domain.Host, domain.Zone)
+	return deployer.RemoveDomain(result.App.AppID, root, prefix)
+}
+
+func runDomainList(target string) error {
+	cfg, err := config.Load(".")
+	if err != nil {
+		return fmt.Errorf("config not found - run 'mvpbridge init' first: %w", err)
+	}
+
+	var attached map[string]bool
+	switch target = targetFor(cfg, target); target {
+	case "do":
+		attached, err = listDomainsDigitalOcean(cfg)
+	case "aws":
+		attached, err = listDomainsAWS(cfg)
+	default:
+		return fmt.Errorf("unknown target: %s (supported: do, aws)", target)
+	}
+	if err != nil && !errors.Is(err, deploy.ErrAppNotFound) {
+		return err
+	}
+
+	var pending []string
+	for _, d := range cfg.Domains {
+		if !attached[d.Host] {
+			pending = append(pending, d.Host)
+		}
+	}
+	if len(pending) > 0 {
+		fmt.Println("Configured but not attached (run 'mvpbridge deploy' to attach):")
+		for _, host := range pending {
+			fmt.Printf("  %s\n", host)
+		}
+	}
+	if len(attached) == 0 && len(pending) == 0 {
+		fmt.Println("No custom domains.")
+	}
+	return nil
+}
+
+func listDomainsDigitalOcean(cfg *config.Config) (map[string]bool, error) {
+	deployer, err := newDODeployer(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := deployer.FindApp()
+	if err != nil {
+		return nil, err
+	}
+	app := result.App
+
+	attached := make(map[string]bool)
+	for _, d := range app.Spec.Domains {
+		attached[d.Domain] = true
+
+		var status *deploy.DOAppDomain
+		phase := "UNKNOWN"
+		for i := range app.Domains {
+			if app.Domains[i].Spec.Domain == d.Domain {
+				status = &app.Domains[i]
+				phase = status.Phase
+			}
+		}
+		fmt.Printf("%-40s %-8s %s\n", d.Domain, doDomainType(d.Type), phase)
+		if phase != "ACTIVE" {
+			printDNSRecords(deploy.DODNSRecords(d, status, app.DefaultIngress))
+		}
+	}
+	return attached, nil
+}
+
+func listDomainsAWS(cfg *config.Config) (map[string]bool, error) {
+	deployer, err := newAWSDeployer(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := deployer.FindApp()
+	if err != nil {
+		return nil, err
+	}
+
+	associations, err := deployer.ListDomainAssociations(result.App.AppID)
+	if err != nil {
+		return nil, fmt.Errorf("listing domains: %w", err)
+	}
+
+	attached := make(map[string]bool)
+	for i := range associations {
+		assoc := &associations[i]
+		for _, sub := range assoc.SubDomains {
+			host := assoc.DomainName
+			if sub.SubDomainSetting.Prefix != "" {
+				host = sub.SubDomainSetting.Prefix + "." + host
+			}
+			attached[host] = true
+			fmt.Printf("%-40s %-12s %s\n", host, "-> "+sub.SubDomainSetting.BranchName, assoc.DomainStatus)
+		}
+		if assoc.DomainStatus != "AVAILABLE" {
+			printDNSRecords(assoc.DNSRecords())
+		}
+	}
+	return attached, nil
+}
+
+// doDomain converts a configured domain to its app spec entry
+func doDomain(d config.Domain) deploy.DODomain {
+	return deploy.DODomain{Domain: d.Host, Type: doDomainType(d.Type), Zone: d.Zone}
+}
+
+// doDomainType returns a domain's type, which App Platform treats as an
+// alias when unset
+func doDomainType(t string) string {
+	if t == "" {
+		return "ALIAS"
+	}
+	return t
+}
+
+// domainBranch returns the branch an Amplify domain serves
+func domainBranch(d config.Domain, deployed string) string {
+	if d.Branch != "" {
+		return d.Branch
+	}
+	return deployed
+}
+
+// printDNSRecords lists records the user must create at their DNS provider
+func printDNSRecords(records []deploy.DNSRecord) {
+	if len(records) == 0 {
+		return
+	}
+	fmt.Println("  Create these DNS records at your DNS provider:")
+	for _, r := range records {
+		fmt.Printf("    %-45s %-6s %s\n", r.Name, r.Type, r.Value)
+	}
+}
+
+// syncAmplifyDomains attaches configured domains missing from an app. Failures
+// are reported but do not fail the deploy.
+func syncAmplifyDomains(cfg *config.Config, deployer *deploy.AWSDeployer, appID string) {
+	for _, d := range cfg.Domains {
+		root, prefix := deploy.AmplifyDomainParts(d.Host, d.Zone)
+		if _, err := deployer.AddDomain(appID, root, prefix, domainBranch(d, deployer.Branch)); err != nil {
+			fmt.Printf("  Warning: attaching %s: %v\n", d.Host, err)
+		}
+	}
+}
+
+func runImport(target, ref string) error {
+	cfg, err := config.Load(".")
+	if errors.Is(err, fs.ErrNotExist) {
+		// Importing can stand in for init
+		d, detectErr := detect.DetectAll(".")
+		if detectErr != nil {
+			return fmt.Errorf("detection failed: %w", detectErr)
+		}
+		cfg = config.NewFromDetection(d, "")
+	} else if err != nil {
+		return err
+	}
+
+	target = targetFor(cfg, target)
+	if err := linkApp(cfg, target, ref); err != nil {
+		return err
+	}
+
+	local, err := extractEnvVars()
+	if err != nil {
+		return fmt.Errorf("extracting env vars: %w", err)
+	}
+
+	switch target {
+	case "do":
+		err = importDigitalOcean(cfg, local)
+	case "aws":
+		err = importAWS(cfg, local)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.Save("."); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+
+	fmt.Printf("\n✓ Wrote %s - review it, then run `mvpbridge deploy`\n", filepath.Join(config.ConfigDir, config.ConfigFile))
+	return nil
+}
+
+// linkApp resolves an app by ID or name, records it in state and points the
+// config at it
+func linkApp(cfg *config.Config, target, ref string) error {
+	var appID, appName, region string
+
+	switch target {
+	case "do":
+		deployer, err := newDODeployer(cfg)
+		if err != nil {
+			return err
+		}
+		app, err := findAppByRef(ref, func(id, name string) (string, error) {
+			deployer.AppID, deployer.AppName = id, name
+			app, err := deployer.FindApp()
+			if err != nil {
+				return "", err
+			}
+			return app.App.ID, nil
+		})
+		if err != nil {
+			return err
+		}
+		appID, appName = app, deployer.AppName
+	case "aws":
+		deployer, err := newAWSDeployer(cfg)
+		if err != nil {
+			return err
+		}
+		app, err := findAppByRef(ref, func(id, name string) (string, error) {
+			deployer.AppID, deployer.AppName = id, name
+			app, err := deployer.FindApp()
+			if err != nil {
+				return "", err
+			}
+			return app.App.AppID, nil
+		})
+		if err != nil {
+			return err
+		}
+		appID, appName, region = app, deployer.AppName, deployer.Region
+	default:
+		return fmt.Errorf("unknown target: %s (supported: do, aws)", target)
+	}
+
+	recordApp(target, appID, appName, region)
+	cfg.Target = target
+	cfg.Deploy.AppName = appName
+
+	fmt.Printf("✓ Linked to %s (%s)\n", appName, appID)
+	return nil
+}
+
+// findAppByRef looks an app up by ID first and, when no app has that ID, by
+// name. find looks up an app by ID, or by name when the ID is empty.
+func findAppByRef(ref string, find func(id, name string) (string, error)) (string, error) {
+	id, err := find(ref, ref)
+	if err == nil {
+		return id, nil
+	}
+
+	if !errors.Is(err, deploy.ErrAppDeleted) && !isAPIStatus(err, http.StatusBadRequest) {
+		return "", err
+	}
+
+	id, err = find("", ref)
+	if errors.Is(err, deploy.ErrAppNotFound) {
+		return "", fmt.Errorf("no app with ID or name %q", ref)
+	}
+	return id, err
+}
+
+// importDigitalOcean copies the settings of the linked app's spec into cfg
+// and, unless the project already has one, writes the spec to .do/app.yaml
+// so deploys keep the components and settings mvpbridge does not manage
+func importDigitalOcean(cfg *config.Config, local map[string]string) error {
+	deployer, err := newDODeployer(cfg)
+	if err != nil {
+		return err
+	}
+	app, err := deployer.FindApp()
+	if err != nil {
+		return fmt.Errorf("reading app: %w", err)
+	}
+	spec := app.App.Spec
+
+	cfg.Deploy.DORegion = spec.Region
+	fmt.Printf("  Region: %s\n", spec.Region)
+
+	mainName := ""
+	switch {
+	case len(spec.StaticSites) > 0:
+		site := spec.StaticSites[0]
+		mainName = site.Name
+		cfg.Detected.OutputType = string(detect.Static)
+		if site.BuildCommand != "" {
+			cfg.Detected.BuildCommand = site.BuildCommand
+		}
+		if site.OutputDir != "" {
+			cfg.Detected.OutputDir = site.OutputDir
+		}
+		fmt.Printf("  Static site: build %q, output %q\n", cfg.Detected.BuildCommand, cfg.Detected.OutputDir)
+	case len(spec.Services) > 0:
+		svc := spec.Services[0]
+		mainName = svc.Name
+		cfg.Detected.OutputType = string(detect.SSR)
+		cfg.Deploy.InstanceSize = svc.InstanceSizeSlug
+		cfg.Deploy.InstanceCount = svc.InstanceCount
+		cfg.Deploy.HTTPPort = svc.HTTPPort
+		fmt.Printf("  Service: %d x %s on port %d\n", svc.InstanceCount, svc.InstanceSizeSlug, svc.HTTPPort)
+	}
+	cfg.Deploy.Component = ""
+	if mainName != spec.Name {
+		cfg.Deploy.Component = mainName
+	}
+
+	switch _, err := os.Stat(deploy.DOAppSpecFile); {
+	case err == nil:
+		if n := len(spec.Components()); n > 1 {
+			fmt.Printf("  Warning: app has %d components; check %s lists them all to keep them\n", n, deploy.DOAppSpecFile)
+		}
+	case errors.Is(err, fs.ErrNotExist):
+		data, err := deployer.RemoteSpec(app.App.ID)
+		if err != nil {
+			return fmt.Errorf("reading app spec: %w", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(deploy.DOAppSpecFile), 0750); err != nil {
+			return err
+		}
+		if err := os.WriteFile(deploy.DOAppSpecFile, data, 0600); err != nil {
+			return err
+		}
+		fmt.Printf("  Wrote %s with the app's %d components; deploys keep everything in it\n", deploy.DOAppSpecFile, len(spec.Components()))
+	default:
+		return err
+	}
+
+	cfg.Deploy.KeepEnv = missingKeys(spec.EnvKeys(), local)
+	return nil
+}
+
+// importAWS copies the settings of the linked Amplify app into cfg
+func importAWS(cfg *config.Config, local map[string]string) error {
+	deployer, err := newAWSDeployer(cfg)
+	if err != nil {
+		return err
+	}
+	settings, err := deployer.GetAppSettings(deployer.AppID)
+	if err != nil {
+		return fmt.Errorf("reading app: %w", err)
+	}
+
+	cfg.Deploy.Region = deployer.Region
+	cfg.Deploy.BuildSpec = settings.BuildSpec
+	fmt.Printf("  Region: %s, platform: %s\n", deployer.Region, settings.Platform)
+	if settings.BuildSpec != "" {
+		fmt.Println("  Build spec: imported")
+	}
+
+	cfg.Deploy.CustomRules = nil
+	for _, r := range settings.CustomRules {
+		cfg.Deploy.CustomRules = append(cfg.Deploy.CustomRules, config.Rule{
+			Source: r.Source, Target: r.Target, Status: r.Status, Condition: r.Condition,
+		})
+	}
+	fmt.Printf("  Custom rules: %d\n", len(cfg.Deploy.CustomRules))
+
+	keys := make([]string, 0, len(settings.EnvironmentVariables))
+	for k := range settings.EnvironmentVariables {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	cfg.Deploy.KeepEnv = missingKeys(keys, local)
+
+	branch, err := deployer.GetBranch(deployer.AppID, deployer.Branch)
+	switch {
+	case err == nil:
+		fmt.Printf("  Branch %s: stage %s", branch.BranchName, branch.Stage)
+		if branch.Framework != "" {
+			fmt.Printf(", framework %s", branch.Framework)
+		}
+		fmt.Println()
+		if branch.BuildSpec != "" {
+			fmt.Println("  Note: the branch overrides the app build spec in the Amplify console")
+		}
+	case isAPIStatus(err, http.StatusNotFound):
+		fmt.Printf("  Branch %s not found; deploy will create it\n", deployer.Branch)
+	default:
+		return fmt.Errorf("reading branch %s: %w", deployer.Branch, err)
+	}
+
+	return nil
+}
+
+// isAPIStatus reports whether err is a platform API error with the given status
+func isAPIStatus(err error, status int) bool {
+	var apiErr *deploy.APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// missingKeys returns the remote env var names that are not set locally
+func missingKeys(remote []string, local map[string]string) []string {
+	var keep []string
+	for _, k := range remote {
+		if _, ok := local[k]; !ok {
+			keep = append(keep, k)
+		}
+	}
+	if len(keep) > 0 {
+		fmt.Printf("  Keeping %d remote env vars not in .env: %s\n", len(keep), strings.Join(keep, ", "))
+	}
+	return keep
+}
+
+func runAuthCheck(target string) error {
+	// Config is optional here; it only supplies the default target and AWS settings
+	cfg, err := config.Load(".")
+	if err != nil {
+		cfg = &config.Config{}
+	}
+
+	switch target = targetFor(cfg, target); target {
+	case "do":
+		token, source, err := resolveDOToken(cfg)
+		if err != nil {
+			return err
+		}
+		deployer := deploy.NewDODeployerWithToken(token, "", "", "")
+		deployer.SetContext(rootCtx)
+		fmt.Printf("DigitalOcean credentials (%s)... ", source)
+		return reportCredentialCheck(deployer.ValidateCredentials())
+	case "aws":
+		creds, err := resolveAWSCredentials(cfg)
+		if err != nil {
+			return err
+		}
+		deployer := deploy.NewAWSDeployerWithCredentials(creds, "", "", "", awsRegionFor(cfg))
+		deployer.SetContext(rootCtx)
+		fmt.Printf("AWS credentials (%s)... ", creds.Source)
+		return reportCredentialCheck(deployer.ValidateCredentials())
+	default:
+		return fmt.Errorf("unknown target: %s (supported: do, aws)", target)
+	}
+}
+
+func runAuthLogin(target, token string) error {
+	cfg, err := config.Load(".")
+	if err != nil {
+		cfg = &config.Config{}
+	}
+
+	var stored *config.TargetCredentials
+	switch target = targetFor(cfg, target); target {
+	case "do":
+		if token == "" {
+			if token, err = promptSecret("DigitalOcean API token: "); err != nil {
+				return err
+			}
+		}
+		deployer := deploy.NewDODeployerWithToken(token, "", "", "")
+		deployer.SetContext(rootCtx)
+		fmt.Print("Validating DigitalOcean token... ")
+		if err := reportCredentialCheck(deployer.ValidateCredentials()); err != nil {
+			return err
+		}
+		stored = &config.TargetCredentials{Token: token}
+	case "aws":
+		accessKey, err := promptValue("AWS access key ID: ")
+		if err != nil {
+			return err
+		}
+		secretKey, err := promptSecret("AWS secret access key: ")
+		if err != nil {
+			return err
+		}
+		creds := &deploy.AWSCredentials{AccessKeyID: accessKey, SecretAccessKey: secretKey}
+		deployer := deploy.NewAWSDeployerWithCredentials(creds, "", "", "", awsRegionFor(cfg))
+		deployer.SetContext(rootCtx)
+		fmt.Print("Validating AWS credentials... ")
+		if err := reportCredentialCheck(deployer.ValidateCredentials()); err != nil {
+			return err
+		}
+		stored = &config.TargetCredentials{AccessKeyID: accessKey, SecretAccessKey: secretKey}
+	default:
+		return fmt.Errorf("unknown target: %s (supported: do, aws)", target)
+	}
+
+	path, err := config.UserCredentialsPath()
+	if err != nil {
+		return err
+	}
+	creds, err := config.LoadCredentials(path)
+	if err != nil {
+		return err
+	}
+	creds.Set(target, stored)
+	if err := creds.Save(path); err != nil {
+		return fmt.Errorf("saving credentials: %w", err)
+	}
+
+	fmt.Printf("✓ Saved %s credentials to %s\n", target, path)
+	return nil
+}
+
+func runAuthLogout(target string) error {
+	cfg, err := config.Load(".")
+	if err != nil {
+		cfg = &config.Config{}
+	}
+	target = targetFor(cfg, target)
+
+	path, err := config.UserCredentialsPath()
+	if err != nil {
+		return err
+	}
+	creds, err := config.LoadCredentials(path)
+	if err != nil {
+		return err
+	}
+
+	if !creds.Remove(target) {
+		fmt.Printf("No stored credentials for %s\n", target)
+		return nil
+	}
+	if err := creds.Save(path); err != nil {
+		return fmt.Errorf("saving credentials: %w", err)
+	}
+
+	fmt.Printf("✓ Removed stored %s credentials\n", target)
+	return nil
+}
+
+func runAuthList() error {
+	path, err := config.UserCredentialsPath()
+	if err != nil {
+		return err
+	}
+	creds, err := config.LoadCredentials(path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Stored credentials (%s):\n", path)
+	if len(creds.Targets) == 0 {
+		fmt.Println("  (none)")
+	}
+	for _, target := range []string{"do", "aws"} {
+		c := creds.Get(target)
+		switch {
+		case c == nil:
+		case c.Token != "":
+			fmt.Printf("  %-4s %s\n", target, maskSecret(c.Token))
+		default:
+			fmt.Printf("  %-4s %s\n", target, maskSecret(c.AccessKeyID))
+		}
+	}
+
+	// doctl is optional, so a missing config is not an error
+	contexts, current, err := deploy.DoctlContexts(deploy.DoctlConfigPath())
+	if err != nil {
+		return nil //nolint:nilerr
+	}
+	fmt.Println("\ndoctl contexts:")
+	for _, name := range contexts {
+		marker := " "
+		if name == current {
+			marker = "*"
+		}
+		fmt.Printf("  %s %s\n", marker, name)
+	}
+	return nil
+}
+
+// promptValue reads a single line from stdin
+func promptValue(label string) (string, error) {
+	fmt.Print(label)
+	var value string
+	if _, err := fmt.Scanln(&value); err != nil || strings.TrimSpace(value) == "" {
+		return "", fmt.Errorf("no value entered")
+	}
+	return strings.TrimSpace(value), nil
+}
+
+// promptSecret reads a secret from stdin without echoing it, so it stays out
+// of the terminal's scrollback. Piped input is read as a plain line.
+func promptSecret(label string) (string, error) {
+	fd := int(os.Stdin.Fd()) // #nosec G115 - file descriptors fit in an int
+	if !term.IsTerminal(fd) {
+		return promptValue(label)
+	}
+
+	fmt.Print(label)
+	value, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil || strings.TrimSpace(string(value)) == "" {
+		return "", fmt.Errorf("no value entered")
+	}
+	return strings.TrimSpace(string(value)), nil
+}
+
+// maskSecret shows only the last four characters of a secret
+func maskSecret(s string) string {
+	if len(s) <= 4 {
+		return "****"
+	}
+	return "****" + s[len(s)-4:]
+}
+
+// reportCredentialCheck completes a "Validating credentials..." line and
+// turns missing permissions into an error
+func reportCredentialCheck(id *deploy.Identity, err error) error {
+	if err != nil {
+		fmt.Println("✗")
+		return fmt.Errorf("validating credentials: %w", err)
+	}
+
+	if len(id.Missing) > 0 {
+		fmt.Println("✗")
+		fmt.Printf("  Authenticated as %s, but missing permissions:\n", id.Name)
+		for _, m := range id.Missing {
+			fmt.Printf("    - %s\n", m)
+		}
+		return fmt.Errorf("credentials lack required permissions: %s", strings.Join(id.Missing, ", "))
+	}
+
+	fmt.Printf("✓ %s\n", id.Name)
+	return nil
+}
+
+func runPreviewDestroy(target, name string) error {
+	cfg, err := config.Load(".")
+	if err != nil {
+		return fmt.Errorf("config not found - run 'mvpbridge init' first: %w", err)
+	}
+
+	previews, err := listPreviews(cfg, target)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range previews.items {
+		if p.Name == previews.name(name) || p.Branch == name {
+			if err := previews.remove(p); err != nil {
+				return fmt.Errorf("destroying preview %s: %w", p.Name, err)
+			}
+			fmt.Printf("✓ Destroyed preview %s\n", p.Name)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("preview not found: %s", name)
+}
+
+func runPreviewGC(target, olderThan string) error {
+	age, err := parseAge(olderThan)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(".")
+	if err != nil {
+		return fmt.Errorf("config not found - run 'mvpbridge init' first: %w", err)
+	}
+
+	previews, err := listPreviews(cfg, target)
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-age)
+	removed := 0
+	for _, p := range previews.items {
+		if p.CreatedAt.After(cutoff) {
+			continue
+		}
+		if err := previews.remove(p); err != nil {
+			return fmt.Errorf("destroying preview %s: %w", p.Name, err)
+		}
+		fmt.Printf("  Destroyed %s (created %s)\n", p.Name, p.CreatedAt.Format(time.RFC3339))
+		removed++
+	}
+
+	fmt.Printf("✓ Removed %d of %d previews older than %s\n", removed, len(previews.items), olderThan)
+	return nil
+}
+
+// previewSet is the list of previews on one target, how a preview name is
+// listed there and how to destroy them
+type previewSet struct {
+	target  string
+	items   []deploy.Preview
+	name    func(string) string
+	destroy func(deploy.Preview) error
+}
+
+// remove destroys a preview, recording it in the history
+func (s *previewSet) remove(p deploy.Preview) error {
+	entry := &config.HistoryEntry{Action: "destroy", Target: s.target, Preview: p.Name, AppID: p.AppID}
+	started := time.Now()
+	err := s.destroy(p)
+	recordHistory(entry, started, err)
+	return err
+}
+
+func listPreviews(cfg *config.Config, target string) (*previewSet, error) {
+	target = targetFor(cfg, target)
+	switch target {
+	case "do":
+		deployer, err := newDODeployer(cfg)
+		if err != nil {
+			return nil, err
+		}
+		items, err := deployer.ListPreviews()
+		if err != nil {
+			return nil, fmt.Errorf("listing previews: %w", err)
+		}
+		return &previewSet{target: target, items: items, name: deployer.PreviewName, destroy: func(p deploy.Preview) error {
+			return deployer.DeleteApp(p.AppID)
+		}}, nil
+	case "aws":
+		deployer, err := newAWSDeployer(cfg)
+		if err != nil {
+			return nil, err
+		}
+		app, err := deployer.FindApp()
+		if err != nil {
+			return nil, fmt.Errorf("looking up app: %w", err)
+		}
+		items, err := deployer.ListPreviews(app.App.AppID)
+		if err != nil {
+			return nil, fmt.Errorf("listing previews: %w", err)
+		}
+		return &previewSet{target: target, items: items, name: deployer.PreviewName, destroy: func(p deploy.Preview) error {
+			return deployer.DeleteBranch(p.AppID, p.Branch)
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unknown target: %s (supported: do, aws)", target)
+	}
+}
+
+// Helper functions
+
+func checkGit() error {
+	cmd := exec.CommandContext(rootCtx, "git", "--version")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git not installed")
+	}
+	return nil
+}
+
+func checkGitRepo() error {
+	if _, err := os.Stat(".git"); os.IsNotExist(err) {
+		return fmt.Errorf("not a git repository")
+	}
+	return nil
+}
+
+func checkNode() error {
+	cmd := exec.CommandContext(rootCtx, "node", "--version")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("node not installed")
+	}
+	return nil
+}
+
+func formatFramework(fw detect.Framework) string {
+	switch fw {
+	case detect.Vite:
+		return "Vite"
+	case detect.NextJS:
+		return "Next.js"
+	default:
+		return "Unknown"
+	}
+}
+
+func formatNodeVersion(version string) string {
+	if version == "" {
+		return "Not pinned"
+	}
+	return version + " (pinned)"
+}
+
+// getRepoRemote parses the URL of the selected Git remote
+func getRepoRemote() (*git.Remote, error) {
+	raw, err := git.Open(rootCtx, ".").RemoteURL(gitRemote)
+	if err != nil {
+		return nil, err
+	}
+	return git.ParseRemote(raw)
+}
+
+func getGitBranch() (string, error) {
+	cmd := exec.CommandContext(rootCtx, "git", "rev-parse", "--abbrev-ref", "HEAD")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("reading current git branch: %w", err)
+	}
+
+	branch := strings.TrimSpace(string(output))
+	if branch == "HEAD" {
+		return "", fmt.Errorf("detached HEAD - check out a branch first")
+	}
+
+	return branch, nil
+}
+
+// preflight runs the inspect checks and, unless the build is local, checks
+// that the working tree is clean. Errors stop the deploy unless force is set.
+// It then returns the commit to deploy: HEAD, which must be on the deployed
+// branch of the remote whatever force says, since the platform builds from
+// there. Local builds have no commit.
+func preflight(target, preview string, local, force bool) (string, error) {
+	fmt.Println("Pre-flight checks...")
+
+	d, err := detect.DetectAll(".")
+	if err != nil {
+		return "", fmt.Errorf("detection failed: %w", err)
+	}
+	issues := detect.DeployIssues(d, target)
+
+	repo := git.Open(rootCtx, ".")
+	if !local {
+		issues = append(issues, gitIssues(repo)...)
+	}
+
+	errorCount := 0
+	for _, issue := range issues {
+		mark := "!"
+		if issue.Severity == detect.SeverityError {
+			mark = "✗"
+			errorCount++
+		}
+		fmt.Printf("  %s %s\n", mark, issue.Description)
+	}
+
+	switch {
+	case errorCount == 0:
+		fmt.Println("  ✓ Ready to deploy")
+	case force:
+		fmt.Printf("  Deploying despite %d error(s) (--force)\n", errorCount)
+	default:
+		return "", fmt.Errorf("pre-flight checks found %d error(s); fix them or deploy with --force", errorCount)
+	}
+
+	if local {
+		fmt.Println()
+		return "", nil
+	}
+
+	// Amplify builds a given commit; App Platform builds the branch tip
+	branch := defaultDeployBranch
+	if preview != "" {
+		if branch, err = getGitBranch(); err != nil {
+			return "", err
+		}
+	}
+	commit, err := pushedCommit(repo, gitRemote, branch, target == "do")
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("  ✓ Commit %s on %s/%s\n", shortSHA(commit), gitRemote, branch)
+	fmt.Println()
+	return commit, nil
+}
+
+// gitIssues reports uncommitted changes, which the platform would not build
+func gitIssues(repo *git.Repo) []detect.Issue {
+	changes, err := repo.Changes()
+	switch {
+	case err != nil:
+		return []detect.Issue{{Code: "GIT_STATUS", Description: fmt.Sprintf("Could not read git status: %v", err), Severity: detect.SeverityError}}
+	case len(changes) > 0:
+		return []detect.Issue{{
+			Code:        "DIRTY_TREE",
+			Description: fmt.Sprintf("Uncommitted changes in %d file(s), e.g. %s", len(changes), changes[0]),
+			Severity:    detect.SeverityError,
+		}}
+	}
+	return nil
+}
+
+// pushedCommit returns the SHA of HEAD, refusing when the remote branch does
+// not contain it. With exact, HEAD must be the branch tip, for platforms that
+// always build the tip rather than a given commit.
+func pushedCommit(repo *git.Repo, remote, branch string, exact bool) (string, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+	tip, err := repo.RemoteBranch(remote, branch)
+	if err != nil {
+		return "", fmt.Errorf("reading %s/%s: %w", remote, branch, err)
+	}
+	if tip == "" {
+		return "", fmt.Errorf("branch %s does not exist on %s; push it first", branch, remote)
+	}
+	if head == tip {
+		return head, nil
+	}
+	if exact {
+		return "", fmt.Errorf("HEAD %s is not the tip of %s/%s (%s); push or pull so they match", shortSHA(head), remote, branch, shortSHA(tip))
+	}
+
+	pushed, err := repo.IsAncestor(head, tip)
+	if err != nil {
+		return "", fmt.Errorf("%s/%s has commits not fetched here; run git fetch %s", remote, branch, remote)
+	}
+	if !pushed {
+		return "", fmt.Errorf("HEAD %s is not pushed to %s/%s; push it first", shortSHA(head), remote, branch)
+	}
+	return head, nil
+}
+
+// parseAge parses a duration that may also use a day suffix, such as "7d"
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age: %s", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age: %s", s)
+	}
+	return d, nil
+}
+
+// targetFor returns the given target, falling back to the configured one
+func targetFor(cfg *config.Config, target string) string {
+	if target != "" {
+		return target
+	}
+	if cfg.Target != "" {
+		return cfg.Target
+	}
+	return "do"
+}
+
+// recordApp stores the deployed app in project state so later commands can
+// find and clean it up
+func recordApp(target, appID, appName, region string) {
+	state, err := config.LoadState(".")
+	if err == nil {
+		ts := state.Target(target)
+		ts.AppID, ts.AppName, ts.Region = appID, appName, region
+		err = state.Save(".")
+	}
+	if err != nil {
+		fmt.Printf("  Warning: could not save state: %v\n", err)
+	}
+}
+
+// recordDeployment stores the deployment a deploy started and the commit it
+// builds, so status can show what mvpbridge last shipped
+func recordDeployment(target, deploymentID, commit string) {
+	state, err := config.LoadState(".")
+	if err == nil {
+		ts := state.Target(target)
+		ts.DeploymentID, ts.Commit = deploymentID, commit
+		err = state.Save(".")
+	}
+	if err != nil {
+		fmt.Printf("  Warning: could not save state: %v\n", err)
+	}
+}
+
+// recordHistory appends a finished action to the project's history. Errors
+// are redacted first, since API responses can echo secrets.
+func recordHistory(entry *config.HistoryEntry, started time.Time, err error) {
+	entry.Time = started.UTC()
+	entry.DurationMS = time.Since(started).Milliseconds()
+	entry.User = historyUser()
+	entry.Result = config.ResultSuccess
+	if err != nil {
+		entry.Result = config.ResultFailure
+		entry.Error = deploy.RedactError(err)
+	}
+
+	if err := config.AppendHistory(".", *entry); err != nil {
+		fmt.Printf("  Warning: could not save history: %v\n", err)
+	}
+}
+
+// historyUser names who ran a command: the Git author email, or else the
+// OS user
+func historyUser() string {
+	if email, err := git.Open(rootCtx, ".").Config("user.email"); err == nil && email != "" {
+		return email
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+// notifyEvent sends an event about the action in entry to every webhook
+// subscribed to it. Delivery failures are warnings; they never fail a deploy.
+func notifyEvent(cfg *config.Config, kind notify.EventType, entry *config.HistoryEntry, logs string) {
+	webhooks := cfg.Notifications.Webhooks
+	if len(webhooks) == 0 {
+		return
+	}
+
+	event := notify.Event{
+		Type:         kind,
+		App:          entry.AppName,
+		Target:       entry.Target,
+		Preview:      entry.Preview,
+		URL:          entry.URL,
+		Commit:       entry.Commit,
+		DeploymentID: entry.DeploymentID,
+		User:         entry.User,
+		DurationMS:   entry.DurationMS,
+		Result:       entry.Result,
+		Error:        entry.Error,
+		Time:         time.Now().UTC(),
+	}
+	if event.App == "" {
+		event.App = cfg.Deploy.AppName
+	}
+	if event.App == "" {
+		if remote, err := getRepoRemote(); err == nil {
+			event.App = appNameFor(cfg, remote.HTTPSURL())
+		}
+	}
+	if event.User == "" {
+		event.User = historyUser()
+	}
+	if logs != "" {
+		event.Logs = notify.LogExcerpt(logs)
+	}
+
+	// Not rootCtx: a deploy cancelled with Ctrl-C is still announced
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	notifier := notify.New(cfg.Notifications.Retries)
+	for _, w := range webhooks {
+		if !w.Wants(kind) {
+			continue
+		}
+		if err := notifier.Send(ctx, w, event); err != nil {
+			fmt.Printf("  Warning: %s notification to %s failed: %v\n", kind, webhookHost(w.URL), err)
+		}
+	}
+}
+
+// failureLogs fetches the build log of a failed deployment, or the log of the
+// failed step of an Amplify job, for the failure notification
+func failureLogs(cfg *config.Config, entry *config.HistoryEntry) string {
+	if len(cfg.Notifications.Webhooks) == 0 || entry.AppID == "" || entry.DeploymentID == "" {
+		return ""
+	}
+
+	var logs string
+	var err error
+	switch entry.Target {
+	case "do":
+		deployer, deployerErr := newDODeployer(cfg)
+		if deployerErr != nil {
+			return ""
+		}
+		logs, err = deployer.GetLogs(entry.AppID, entry.DeploymentID)
+	case "aws":
+		deployer, deployerErr := newAWSDeployer(cfg)
+		if deployerErr != nil {
+			return ""
+		}
+		logs, err = deployer.GetLogs(entry.AppID, deployer.Branch, entry.DeploymentID)
+	default:
+		return ""
+	}
+	if err != nil {
+		fmt.Printf("  Warning: could not fetch build logs: %v\n", err)
+		return ""
+	}
+	return logs
+}
+
+// webhookHost names a webhook by its host, since the rest of the URL is
+// usually a secret
+func webhookHost(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "webhook"
+}
+
+func runNotifyTest(kind notify.EventType) error {
+	cfg, err := config.Load(".")
+	if err != nil {
+		return fmt.Errorf("config not found - run 'mvpbridge init' first: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	webhooks := cfg.Notifications.Webhooks
+	if len(webhooks) == 0 {
+		return fmt.Errorf("no webhooks configured - add them under notifications: in %s",
+			filepath.Join(config.ConfigDir, config.ConfigFile))
+	}
+
+	valid := false
+	for _, t := range notify.EventTypes {
+		valid = valid || t == kind
+	}
+	if !valid {
+		return fmt.Errorf("unknown event: %s (supported: start, success, failure, rollback)", kind)
+	}
+
+	event := notify.Event{
+		Type:         kind,
+		App:          cfg.Deploy.AppName,
+		Target:       targetFor(cfg, ""),
+		Commit:       "0000000000000000000000000000000000000000",
+		DeploymentID: "test",
+		User:         historyUser(),
+		DurationMS:   90 * 1000,
+		Time:         time.Now().UTC(),
+	}
+	if event.App == "" {
+		event.App = "mvpbridge-app"
+	}
+	if kind == notify.Start {
+		event.DurationMS = 0
+	}
+	if kind != notify.Start {
+		event.Result = notify.ResultSuccess
+	}
+	if kind == notify.Failure {
+		event.Result = notify.ResultFailure
+		event.Error = "test failure from mvpbridge notify test"
+		event.Logs = "npm run build\nError: this is a test notification"
+	}
+
+	ctx, cancel := context.WithTimeout(rootCtx, time.Minute)
+	defer cancel()
+	notifier := notify.New(cfg.Notifications.Retries)
+	failed := 0
+	for _, w := range webhooks {
+		format := w.Format
+		if format == "" {
+			format = notify.Generic
+		}
+		if err := notifier.Send(ctx, w, event); err != nil {
+			fmt.Printf("  ✗ %s (%s): %v\n", webhookHost(w.URL), format, err)
+			failed++
+			continue
+		}
+		fmt.Printf("  ✓ %s (%s)\n", webhookHost(w.URL), format)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d webhooks failed", failed, len(webhooks))
+	}
+	return nil
+}
+
+// recordedAppID returns the app ID saved in state by an earlier deploy, so the
+// app is addressed directly rather than matched by name. An ID recorded for a
+// different region is ignored.
+func recordedAppID(target, region string) string {
+	state, err := config.LoadState(".")
+	if err != nil {
+		return ""
+	}
+	ts, ok := state.Targets[target]
+	if !ok || (region != "" && ts.Region != "" && ts.Region != region) {
+		return ""
+	}
+	return ts.AppID
+}
+
+// forgetDeletedApp clears state for an app that was deleted outside
+// mvpbridge, so the next deploy creates a fresh one
+func forgetDeletedApp(target string, err error) error {
+	if !errors.Is(err, deploy.ErrAppDeleted) {
+		return err
+	}
+
+	state, loadErr := config.LoadState(".")
+	if loadErr == nil {
+		state.Clear(target)
+		loadErr = state.Save(".")
+	}
+	if loadErr != nil {
+		return fmt.Errorf("%w (could not clear state: %v)", err, loadErr)
+	}
+	return fmt.Errorf("%w; removed it from %s - run deploy again to create a new app", err, filepath.Join(config.ConfigDir, config.StateFile))
+}
+
+// noteRename reports an app that was renamed outside mvpbridge
+func noteRename(configured, actual string) {
+	if configured != actual {
+		fmt.Printf("  Note: app was renamed to %q outside mvpbridge; deploying to it by ID\n", actual)
+	}
+}
+
+// appNameFor returns the configured app name or derives one from the repo URL
+func appNameFor(cfg *config.Config, repoURL string) string {
+	if cfg.Deploy.AppName != "" {
+		return cfg.Deploy.AppName
+	}
+
+	parts := strings.Split(repoURL, "/")
+	if len(parts) > 0 && parts[len(parts)-1] != "" {
+		return parts[len(parts)-1]
+	}
+	return "mvpbridge-app"
+}
+
+// awsRegionFor returns the configured AWS region or the default
+func awsRegionFor(cfg *config.Config) string {
+	if cfg.Deploy.Region != "" {
+		return cfg.Deploy.Region
+	}
+	return "us-east-1"
+}
+
+// resolveAWSCredentials resolves AWS credentials for --profile, or else the
+// environment or the configured profile
+func resolveAWSCredentials(cfg *config.Config) (*deploy.AWSCredentials, error) {
+	creds, err := deploy.ResolveAWSCredentialsContext(rootCtx, awsProfile, cfg.Deploy.AWSProfile)
+	if errors.Is(err, deploy.ErrNoAWSCredentials) {
+		if stored := storedCredentials("aws"); stored != nil && stored.AccessKeyID != "" {
+			return &deploy.AWSCredentials{
+				AccessKeyID:     stored.AccessKeyID,
+				SecretAccessKey: stored.SecretAccessKey,
+				Source:          "mvpbridge auth login",
+			}, nil
+		}
+	}
+	return creds, err
+}
+
+// resolveDOToken finds a DigitalOcean token. An explicit doctl context (via
+// --context or deploy.do_context) wins, then DIGITALOCEAN_TOKEN, then a token
+// stored with 'mvpbridge auth login', then doctl's current context.
+func resolveDOToken(cfg *config.Config) (token, source string, err error) {
+	ctxName := doContext
+	if ctxName == "" {
+		ctxName = cfg.Deploy.DOContext
+	}
+	if ctxName != "" {
+		token, err := deploy.DoctlToken(deploy.DoctlConfigPath(), ctxName)
+		return token, "doctl context " + ctxName, err
+	}
+
+	if token := os.Getenv("DIGITALOCEAN_TOKEN"); token != "" {
+		return token, "environment", nil
+	}
+
+	if stored := storedCredentials("do"); stored != nil && stored.Token != "" {
+		return stored.Token, "mvpbridge auth login", nil
+	}
+
+	if token, err := deploy.DoctlToken(deploy.DoctlConfigPath(), ""); err == nil {
+		return token, "doctl current context", nil
+	}
+
+	return "", "", fmt.Errorf("no DigitalOcean token found: set DIGITALOCEAN_TOKEN, run 'mvpbridge auth login do', or run 'doctl auth init'")
+}
+
+// storedCredentials returns credentials saved with 'mvpbridge auth login', or nil
+func storedCredentials(target string) *config.TargetCredentials {
+	path, err := config.UserCredentialsPath()
+	if err != nil {
+		return nil
+	}
+	creds, err := config.LoadCredentials(path)
+	if err != nil {
+		return nil
+	}
+	return creds.Get(target)
+}
+
+func newDODeployer(cfg *config.Config) (*deploy.DODeployer, error) {
+	remote, err := getRepoRemote()
+	if err != nil {
+		return nil, fmt.Errorf("getting git remote: %w", err)
+	}
+	repoURL := remote.HTTPSURL()
+
+	token, _, err := resolveDOToken(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	deployer := deploy.NewDODeployerWithToken(token, appNameFor(cfg, repoURL), repoURL, defaultDeployBranch)
+	deployer.SetContext(rootCtx)
+	deployer.AppID = recordedAppID("do", "")
+	if err := configureDODeployer(deployer, cfg); err != nil {
+		return nil, err
+	}
+	return deployer, nil
+}
+
+// configureDODeployer applies the config and the project's .do/app.yaml to
+// a deployer
+func configureDODeployer(deployer *deploy.DODeployer, cfg *config.Config) error {
+	deployer.Region = cfg.Deploy.DORegion
+	deployer.BuildCommand = cfg.Detected.BuildCommand
+	deployer.OutputDir = cfg.Detected.OutputDir
+	deployer.InstanceSize = cfg.Deploy.InstanceSize
+	deployer.InstanceCount = cfg.Deploy.InstanceCount
+	deployer.HTTPPort = cfg.Deploy.HTTPPort
+	deployer.KeepEnv = cfg.Deploy.KeepEnv
+	deployer.Component = cfg.Deploy.Component
+	deployer.SetStaticRouting(cfg.GetFramework())
+	for _, d := range cfg.Domains {
+		deployer.Domains = append(deployer.Domains, doDomain(d))
+	}
+	for _, c := range cfg.Components {
+		deployer.Components = append(deployer.Components, deploy.DOComponent{
+			Name:         c.Name,
+			Kind:         c.Kind,
+			SourceDir:    c.SourceDir,
+			BuildCommand: c.BuildCommand,
+			RunCommand:   c.RunCommand,
+			HTTPPort:     c.HTTPPort,
+			Route:        c.Route,
+			JobKind:      c.JobKind,
+			InstanceSize: c.InstanceSize,
+		})
+	}
+	if db := cfg.Database; db != nil {
+		deployer.Database = &deploy.DODatabase{
+			Name:        db.Name,
+			Engine:      db.Engine,
+			Version:     db.Version,
+			Production:  db.Cluster != "",
+			ClusterName: db.Cluster,
+			DBName:      db.DBName,
+			DBUser:      db.DBUser,
+		}
+		deployer.DatabaseEnv = db.EnvVar
+	}
+
+	spec, err := deploy.LoadDOAppSpec(deploy.DOAppSpecFile)
+	if err != nil {
+		return err
+	}
+	deployer.BaseSpec = spec
+	return nil
+}
+
+func newAWSDeployer(cfg *config.Config) (*deploy.AWSDeployer, error) {
+	remote, err := getRepoRemote()
+	if err != nil {
+		return nil, fmt.Errorf("getting git remote: %w", err)
+	}
+	repoURL, err := deploy.AmplifyRepository(remote)
+	if err != nil {
+		return nil, err
+	}
+
+	return awsDeployerFor(cfg, repoURL)
+}
+
+// newLocalAWSDeployer creates a deployer for manual deployments, which need
+// no Git remote; without one the app is named after the project directory
+func newLocalAWSDeployer(cfg *config.Config) (*deploy.AWSDeployer, error) {
+	remote, err := getRepoRemote()
+	if err != nil {
+		dir, absErr := filepath.Abs(".")
+		if absErr != nil {
+			return nil, absErr
+		}
+		return awsDeployerFor(cfg, filepath.ToSlash(dir))
+	}
+	return awsDeployerFor(cfg, remote.HTTPSURL())
+}
+
+// awsDeployerFor creates a deployer for the app built from repoURL
+func awsDeployerFor(cfg *config.Config, repoURL string) (*deploy.AWSDeployer, error) {
+	creds, err := resolveAWSCredentials(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	deployer := deploy.NewAWSDeployerWithCredentials(creds, appNameFor(cfg, repoURL), repoURL, defaultDeployBranch, awsRegionFor(cfg))
+	deployer.SetContext(rootCtx)
+	deployer.AppID = recordedAppID("aws", deployer.Region)
+	deployer.BuildSpec = cfg.Deploy.BuildSpec
+	deployer.PackageManager = detect.PackageManager(cfg.Detected.PackageManager)
+	deployer.KeepEnv = cfg.Deploy.KeepEnv
+	for _, r := range cfg.Deploy.CustomRules {
+		deployer.CustomRules = append(deployer.CustomRules, deploy.AmplifyRule{
+			Source: r.Source, Target: r.Target, Status: r.Status, Condition: r.Condition,
+		})
+	}
+	return deployer, nil
+}
+
+func extractEnvVars() (map[string]string, error) {
+	envVars := make(map[string]string)
+
+	data, err := os.ReadFile(".env")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return envVars, nil
+		}
+		return nil, err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if idx := strings.Index(line, "="); idx > 0 {
+			key := strings.TrimSpace(line[:idx])
 			value := strings.TrimSpace(line[idx+1:])
 			// Remove quotes if present
 			value = strings.Trim(value, `"'`)
@@ -384,36 +3085,40 @@ func extractEnvVars() (map[string]string, error) {
 
 // Deploy functions
 
-func deployDigitalOcean(cfg *config.Config) error {
+func deployDigitalOcean(cfg *config.Config, preview, commit string, entry *config.HistoryEntry) error {
 	fmt.Println("Deploying to DigitalOcean...")
 	fmt.Println()
 
-	// Get GitHub repo URL
-	repoURL, err := getGitHubRepo()
+	// Create deployer
+	deployer, err := newDODeployer(cfg)
 	if err != nil {
-		return fmt.Errorf("getting GitHub repo: %w", err)
+		return err
 	}
 
-	// Determine app name
-	appName := cfg.Deploy.AppName
-	if appName == "" {
-		// Extract from repo URL
-		parts := strings.Split(repoURL, "/")
-		if len(parts) > 0 {
-			appName = parts[len(parts)-1]
-		} else {
-			appName = "mvpbridge-app"
+	// Previews get their own app that tracks the current branch
+	if preview != "" {
+		branch, err := getGitBranch()
+		if err != nil {
+			return err
 		}
+		if err := deployer.UsePreview(preview, branch); err != nil {
+			return err
+		}
+		if db := cfg.Database; db != nil && db.Cluster != "" {
+			if deployer.Database != nil {
+				fmt.Printf("Preview gets a dev database instead of cluster %s\n", db.Cluster)
+			} else {
+				fmt.Printf("Preview gets no database or pre-deploy jobs: cluster %s is production\n", db.Cluster)
+			}
+		}
+		fmt.Printf("Preview %s from branch %s\n\n", deployer.AppName, branch)
 	}
 
-	// Create deployer
-	deployer, err := deploy.NewDODeployer(appName, repoURL, "main")
-	if err != nil {
+	fmt.Print("[1/4] Validating credentials... ")
+	if err := reportCredentialCheck(deployer.ValidateCredentials()); err != nil {
 		return err
 	}
 
-	fmt.Println("[1/4] Validating credentials... ✓")
-
 	// Extract env vars
 	envVars, err := extractEnvVars()
 	if err != nil {
@@ -427,63 +3132,146 @@ func deployDigitalOcean(cfg *config.Config) error {
 
 	fmt.Printf("[3/4] Configuring secrets (%d vars)... ✓\n", len(envVars))
 
+	// Remember what is live now so failed smoke tests can roll back to it
+	configuredName := deployer.AppName
+	runSmoke := preview == "" && len(cfg.Smoke.Checks) > 0
+	previousID := ""
+	if runSmoke {
+		if existing, err := deployer.FindApp(); err == nil {
+			previousID = existing.App.ActiveDeployment.ID
+		}
+	}
+
 	// Deploy
 	result, err := deployer.Deploy(isStatic, envVars)
 	if err != nil {
-		return fmt.Errorf("deployment failed: %w", err)
+		return fmt.Errorf("deployment failed: %w", forgetDeletedApp("do", err))
 	}
+	noteRename(configuredName, deployer.AppName)
 
 	fmt.Println("[4/4] Triggering deployment... ✓")
 	fmt.Println()
 	fmt.Println("Deployment started!")
 
+	if preview == "" && result.App.ID != "" {
+		recordApp("do", result.App.ID, deployer.AppName, "")
+	}
+
+	// App Platform builds the branch tip, which the pre-flight checks
+	// matched to HEAD; record it against the deployment building it
+	deploymentID := ""
+	if result.App.ID != "" {
+		dep, err := deployer.StartedDeployment(&result.App)
+		switch {
+		case err != nil:
+			fmt.Printf("  Warning: could not start a deployment: %v\n", err)
+		case dep.CommitHash() != "" && commit != "" && dep.CommitHash() != commit:
+			fmt.Printf("  Warning: deployment %s builds %s, not %s\n", dep.ID, shortSHA(dep.CommitHash()), shortSHA(commit))
+			deploymentID, commit = dep.ID, dep.CommitHash()
+			entry.Commit = commit
+		default:
+			deploymentID = dep.ID
+		}
+	}
+	if preview == "" {
+		recordDeployment("do", deploymentID, commit)
+	}
+	entry.AppID, entry.DeploymentID = result.App.ID, deploymentID
+
+	// A new app has no URL until its first deployment is live
+	if preview != "" && result.App.LiveURL == "" && result.App.ID != "" {
+		if err := deployer.WaitForDeployment(result.App.ID, 15*time.Minute); err != nil {
+			return fmt.Errorf("waiting for preview: %w", err)
+		}
+		if result, err = deployer.GetAppByID(result.App.ID); err != nil {
+			return fmt.Errorf("fetching preview app: %w", err)
+		}
+	}
+
 	// Display URLs
-	if result.App.LiveURL != "" {
-		fmt.Printf("  App URL: %s\n", result.App.LiveURL)
-	} else if result.App.DefaultIngress != "" {
-		fmt.Printf("  App URL: https://%s\n", result.App.DefaultIngress)
+	appURL := result.App.LiveURL
+	if appURL == "" && result.App.DefaultIngress != "" {
+		appURL = "https://" + result.App.DefaultIngress
+	}
+	if appURL != "" {
+		fmt.Printf("  App URL: %s\n", appURL)
 	}
 	if result.App.ID != "" {
 		fmt.Printf("  Dashboard: https://cloud.digitalocean.com/apps/%s\n", result.App.ID)
 	}
+	if deploymentID != "" {
+		fmt.Printf("  Deployment: %s\n", deploymentID)
+	}
+	if commit != "" {
+		fmt.Printf("  Commit: %s\n", commit)
+	}
+
+	if preview != "" {
+		fmt.Printf("preview_url=%s\n", appURL)
+	}
+	entry.AppName, entry.URL = deployer.AppName, appURL
+
+	if runSmoke {
+		return smokeDigitalOcean(cfg, deployer, result.App.ID, deploymentID, previousID)
+	}
 
 	return nil
 }
 
-func deployAWS(cfg *config.Config) error {
-	fmt.Println("Deploying to AWS Amplify...")
+// smokeDigitalOcean waits for the deployment the deploy started to go live,
+// runs the smoke tests and optionally rolls back to previousID when they fail
+func smokeDigitalOcean(cfg *config.Config, deployer *deploy.DODeployer, appID, deploymentID, previousID string) error {
+	if deploymentID == "" {
+		return fmt.Errorf("smoke tests not run: no deployment was started to wait for")
+	}
+
 	fmt.Println()
+	fmt.Println("Waiting for deployment to go live...")
+	if err := deployer.WaitForDeploymentID(appID, deploymentID, 20*time.Minute); err != nil {
+		return fmt.Errorf("deployment failed: %w", err)
+	}
 
-	// Get GitHub repo URL
-	repoURL, err := getGitHubRepo()
+	app, err := deployer.GetAppByID(appID)
 	if err != nil {
-		return fmt.Errorf("getting GitHub repo: %w", err)
+		return fmt.Errorf("fetching app: %w", err)
 	}
 
-	// Determine app name
-	appName := cfg.Deploy.AppName
-	if appName == "" {
-		parts := strings.Split(repoURL, "/")
-		if len(parts) > 0 {
-			appName = parts[len(parts)-1]
-		} else {
-			appName = "mvpbridge-app"
-		}
+	smokeErr := runSmokeTests(cfg, app.App.LiveURL)
+	if smokeErr == nil || !cfg.Smoke.Rollback {
+		return smokeErr
+	}
+	if previousID == "" {
+		return fmt.Errorf("%w; no previous deployment to roll back to", smokeErr)
 	}
 
-	// Determine region
-	region := cfg.Deploy.Region
-	if region == "" {
-		region = "us-east-1"
+	fmt.Println()
+	entry := &config.HistoryEntry{Action: "rollback", Target: "do", AppID: appID,
+		AppName: deployer.AppName, URL: app.App.LiveURL, DeploymentID: previousID}
+	if err := autoRollback(cfg, entry, func() error {
+		return doRollback(deployer, appID, previousID)
+	}); err != nil {
+		return fmt.Errorf("%w; %w", smokeErr, err)
 	}
+	return fmt.Errorf("%w; rolled back to %s", smokeErr, previousID)
+}
+
+func deployAWS(cfg *config.Config, preview, commit string, entry *config.HistoryEntry) error {
+	fmt.Println("Deploying to AWS Amplify...")
+	fmt.Println()
+
+	region := awsRegionFor(cfg)
 
 	// Create deployer
-	deployer, err := deploy.NewAWSDeployer(appName, repoURL, "main", region)
+	deployer, err := newAWSDeployer(cfg)
 	if err != nil {
 		return err
 	}
+	deployer.CommitID = commit
 
-	fmt.Println("[1/4] Validating credentials... ✓")
+	fmt.Print("[1/4] Validating credentials... ")
+	if err := reportCredentialCheck(deployer.ValidateCredentials()); err != nil {
+		return err
+	}
 
 	// Extract env vars
 	envVars, err := extractEnvVars()
@@ -491,6 +3279,17 @@ func deployAWS(cfg *config.Config) error {
 		return fmt.Errorf("extracting env vars: %w", err)
 	}
 
+	if preview != "" {
+		return deployAWSPreview(deployer, preview, envVars, entry)
+	}
+
+	if len(cfg.Components) > 0 {
+		fmt.Printf("  Warning: components are deployed on DigitalOcean only; %d ignored on Amplify\n", len(cfg.Components))
+	}
+	if cfg.Database != nil {
+		fmt.Println("  Warning: database is attached on DigitalOcean only; set its connection string in .env for Amplify")
+	}
+
 	fmt.Println("[2/4] Creating app spec... ✓")
 
 	// Get build config from detection
@@ -510,18 +3309,35 @@ func deployAWS(cfg *config.Config) error {
 	// Determine if static
 	isStatic := cfg.IsStatic()
 
+	if deployer.RepoBuildSpec, err = checkRepoBuildSpec(d, isStatic); err != nil {
+		return err
+	}
+	if deployer.RepoBuildSpec && cfg.Deploy.BuildSpec != "" {
+		fmt.Printf("  Note: deploy.build_spec is not used; Amplify builds with %s\n", buildspec.AmplifyFile)
+	}
+
 	fmt.Printf("[3/4] Configuring secrets (%d vars)... ✓\n", len(envVars))
 
 	// Deploy
+	configuredName := deployer.AppName
 	result, err := deployer.Deploy(isStatic, envVars, buildCommand, outputDir)
 	if err != nil {
-		return fmt.Errorf("deployment failed: %w", err)
+		return fmt.Errorf("deployment failed: %w", forgetDeletedApp("aws", err))
 	}
+	noteRename(configuredName, deployer.AppName)
 
 	fmt.Println("[4/4] Triggering deployment... ✓")
 	fmt.Println()
 	fmt.Println("Deployment started!")
 
+	if result.App.AppID != "" {
+		recordApp("aws", result.App.AppID, deployer.AppName, region)
+		recordDeployment("aws", result.JobID, commit)
+		syncAmplifyDomains(cfg, deployer, result.App.AppID)
+	}
+	entry.AppID, entry.DeploymentID = result.App.AppID, result.JobID
+	entry.AppName, entry.URL = deployer.AppName, amplifyBranchURL(deployer.Branch, result.App.DefaultDomain)
+
 	// Display URLs
 	if result.App.DefaultDomain != "" {
 		fmt.Printf("  App URL: https://%s\n", result.App.DefaultDomain)
@@ -530,6 +3346,250 @@ func deployAWS(cfg *config.Config) error {
 		fmt.Printf("  Console: https://%s.console.aws.amazon.com/amplify/home?region=%s#/%s\n",
 			region, region, result.App.AppID)
 	}
+	if result.JobID != "" {
+		fmt.Printf("  Job:     %s (branch %s)\n", result.JobID, deployer.Branch)
+	}
+	if commit != "" {
+		fmt.Printf("  Commit:  %s\n", commit)
+	}
+
+	if len(cfg.Smoke.Checks) > 0 {
+		return smokeAWS(cfg, deployer, result.App.AppID, result.App.DefaultDomain, false)
+	}
+
+	return nil
+}
+
+// deployAWSLocal builds the site locally and uploads the output to an
+// Amplify app without a repository
+func deployAWSLocal(cfg *config.Config, entry *config.HistoryEntry) error {
+	if !cfg.IsStatic() {
+		return fmt.Errorf("--local supports static sites only; SSR apps must be built by Amplify from Git")
+	}
+
+	fmt.Println("Deploying to AWS Amplify from a local build...")
+	fmt.Println()
+
+	region := awsRegionFor(cfg)
+
+	deployer, err := newLocalAWSDeployer(cfg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print("[1/4] Validating credentials... ")
+	if err := reportCredentialCheck(deployer.ValidateCredentials()); err != nil {
+		return err
+	}
+
+	d, err := detect.DetectAll(".")
+	if err != nil {
+		return fmt.Errorf("detecting project: %w", err)
+	}
+	buildCommand := d.BuildCommand
+	if buildCommand == "" {
+		buildCommand = "npm run build"
+	}
+	outputDir := d.OutputDir
+	if outputDir == "" {
+		outputDir = "dist"
+	}
+
+	fmt.Printf("[2/4] Building with %q...\n", buildCommand)
+	if err := runBuild(buildCommand); err != nil {
+		return fmt.Errorf("build failed: %w", err)
+	}
+
+	fmt.Printf("[3/4] Uploading %s... ", outputDir)
+	configuredName := deployer.AppName
+	result, err := deployer.DeployLocal(outputDir)
+	if err != nil {
+		fmt.Println("✗")
+		return fmt.Errorf("deployment failed: %w", forgetDeletedApp("aws", err))
+	}
+	fmt.Println("✓")
+	noteRename(configuredName, deployer.AppName)
+
+	fmt.Println("[4/4] Starting deployment... ✓")
+	fmt.Println()
+	fmt.Println("Deployment started!")
+
+	recordApp("aws", result.App.AppID, deployer.AppName, region)
+	recordDeployment("aws", result.JobID, "")
+	entry.AppID, entry.DeploymentID = result.App.AppID, result.JobID
+	entry.AppName, entry.URL = deployer.AppName, amplifyBranchURL(deployer.Branch, result.App.DefaultDomain)
+	syncAmplifyDomains(cfg, deployer, result.App.AppID)
+	if result.App.DefaultDomain != "" {
+		fmt.Printf("  App URL: %s\n", amplifyBranchURL(deployer.Branch, result.App.DefaultDomain))
+	}
+	fmt.Printf("  Console: https://%s.console.aws.amazon.com/amplify/home?region=%s#/%s\n",
+		region, region, result.App.AppID)
+	fmt.Printf("  Job:     %s (branch %s)\n", result.JobID, deployer.Branch)
+
+	if len(cfg.Smoke.Checks) > 0 {
+		return smokeAWS(cfg, deployer, result.App.AppID, result.App.DefaultDomain, true)
+	}
+
+	return nil
+}
+
+// runBuild runs the project's build command through the shell, streaming its
+// output
+func runBuild(command string) error {
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+
+	// #nosec G204 - the build command comes from the project's own package.json
+	cmd := exec.CommandContext(rootCtx, shell, flag, command)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// checkRepoBuildSpec reports whether the project has an amplify.yml. Amplify
+// builds with that file instead of the app's build spec, so settings missing
+// from it are only warned about; normalize adds them.
+func checkRepoBuildSpec(d *detect.Detection, isStatic bool) (bool, error) {
+	data, err := os.ReadFile(buildspec.AmplifyFile)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	complete, conflicts, err := buildspec.Complete(data, buildspec.SettingsFor(d, isStatic))
+	if err != nil {
+		return false, err
+	}
+	for _, c := range conflicts {
+		fmt.Printf("  Warning: %s: %s; Amplify builds with the file's value\n", buildspec.AmplifyFile, c)
+	}
+	if !complete {
+		fmt.Printf("  Warning: %s lacks settings mvpbridge needs and Amplify builds with it; run `mvpbridge normalize` with target aws and commit it\n", buildspec.AmplifyFile)
+	}
+	return true, nil
+}
+
+// smokeAWS waits for the branch build to finish, runs the smoke tests and
+// optionally re-runs the previous successful job when they fail. Manual
+// deployments have no repository to rebuild from, so they are not rolled back.
+func smokeAWS(cfg *config.Config, deployer *deploy.AWSDeployer, appID, defaultDomain string, manual bool) error {
+	fmt.Println()
+	fmt.Println("Waiting for build to finish...")
+	if _, err := deployer.WaitForLatestJob(appID, deployer.Branch, 30*time.Minute); err != nil {
+		return fmt.Errorf("deployment failed: %w", err)
+	}
+
+	branchURL := amplifyBranchURL(deployer.Branch, defaultDomain)
+	smokeErr := runSmokeTests(cfg, branchURL)
+	if smokeErr == nil || !cfg.Smoke.Rollback {
+		return smokeErr
+	}
+	if manual {
+		return fmt.Errorf("%w; not rolled back: local deploys keep no earlier build to restore, so deploy a good build again with --local", smokeErr)
+	}
+
+	jobs, err := deployer.ListJobs(appID, deployer.Branch, 10)
+	if err != nil {
+		return fmt.Errorf("%w; listing jobs for rollback: %w", smokeErr, err)
+	}
+	prev := deploy.PreviousJob(jobs)
+	if prev == nil {
+		return fmt.Errorf("%w; no previous job to roll back to", smokeErr)
+	}
+
+	fmt.Println()
+	entry := &config.HistoryEntry{Action: "rollback", Target: "aws", AppID: appID,
+		AppName: deployer.AppName, URL: branchURL, DeploymentID: prev.JobID, Commit: prev.CommitID}
+	if err := autoRollback(cfg, entry, func() error {
+		return awsRollback(deployer, appID, prev.JobID)
+	}); err != nil {
+		return fmt.Errorf("%w; %w", smokeErr, err)
+	}
+	return fmt.Errorf("%w; rolling back to job %s", smokeErr, prev.JobID)
+}
+
+// autoRollback runs a rollback made after failed smoke tests, recording and
+// announcing it like one run by hand
+func autoRollback(cfg *config.Config, entry *config.HistoryEntry, rollback func() error) error {
+	started := time.Now()
+	err := rollback()
+	recordHistory(entry, started, err)
+	notifyEvent(cfg, notify.Rollback, entry, "")
+	return err
+}
+
+// runSmokeTests runs the configured smoke checks against baseURL
+func runSmokeTests(cfg *config.Config, baseURL string) error {
+	if baseURL == "" {
+		return fmt.Errorf("smoke tests failed: app has no live URL")
+	}
+
+	fmt.Printf("Running %d smoke checks against %s...\n", len(cfg.Smoke.Checks), baseURL)
+	retries := smoke.DefaultRetries
+	if cfg.Smoke.Retries != nil {
+		retries = *cfg.Smoke.Retries
+	}
+	runner := smoke.NewRunner(retries, cfg.Smoke.Interval)
+	results := runner.RunContext(rootCtx, baseURL, cfg.Smoke.Checks)
+
+	for _, res := range results {
+		if res.Err != nil {
+			fmt.Printf("  ✗ %s: %v (after %d attempts)\n", res.Check.Path, res.Err, res.Attempts)
+		} else {
+			fmt.Printf("  ✓ %s\n", res.Check.Path)
+		}
+	}
+
+	if failed := smoke.Failed(results); len(failed) > 0 {
+		return fmt.Errorf("smoke tests failed: %d of %d checks", len(failed), len(results))
+	}
+	return nil
+}
+
+// amplifyBranchURL returns the default URL Amplify serves a branch on
+func amplifyBranchURL(branch, defaultDomain string) string {
+	if defaultDomain == "" {
+		return ""
+	}
+	return fmt.Sprintf("https://%s.%s", strings.ReplaceAll(branch, "/", "-"), defaultDomain)
+}
+
+// deployAWSPreview builds the current branch as a DEVELOPMENT branch of the
+// existing Amplify app
+func deployAWSPreview(deployer *deploy.AWSDeployer, preview string, envVars map[string]string, entry *config.HistoryEntry) error {
+	branch, err := getGitBranch()
+	if err != nil {
+		return err
+	}
+	if branch == deployer.Branch {
+		return fmt.Errorf("previews must be deployed from a branch other than %s", deployer.Branch)
+	}
+
+	app, err := deployer.FindApp()
+	if err != nil {
+		return fmt.Errorf("looking up app (deploy production first): %w", err)
+	}
+	entry.AppID = app.App.AppID
+
+	fmt.Printf("[2/4] Preview %s from branch %s... ✓\n", preview, branch)
+	fmt.Printf("[3/4] Configuring secrets (%d vars)... ✓\n", len(envVars))
+
+	deployer.Branch = branch
+	previewURL, err := deployer.DeployPreview(app.App.AppID, app.App.DefaultDomain, preview, envVars)
+	if err != nil {
+		return fmt.Errorf("deployment failed: %w", err)
+	}
+
+	fmt.Println("[4/4] Triggering deployment... ✓")
+	fmt.Println()
+	fmt.Println("Preview deployment started!")
+	fmt.Printf("  App URL: %s\n", previewURL)
+	fmt.Printf("preview_url=%s\n", previewURL)
+	entry.AppName, entry.URL = deployer.AppName, previewURL
 
 	return nil
 }